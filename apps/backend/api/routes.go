@@ -5,16 +5,50 @@ import (
 	"net/http"
 	"runtime/debug"
 	"time"
+
+	"github.com/monarch-dev/monarch/internal/httpjson"
 )
 
 func (s *Server) routes() {
-	s.mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /ready", s.handleReady)
 
 	if s.projSvc != nil {
 		s.mux.HandleFunc("POST /projects", s.projSvc.RegisterHandler)
+		s.mux.HandleFunc("GET /projects", s.projSvc.ListHandler)
+		s.mux.HandleFunc("GET /projects/{id}", s.projSvc.GetHandler)
+		s.mux.HandleFunc("POST /projects/{id}/rescan", s.projSvc.RescanHandler)
+		s.mux.HandleFunc("DELETE /projects/{id}", s.projSvc.DeleteHandler)
+	}
+
+	if s.taskSvc != nil {
+		s.mux.HandleFunc("POST /projects/{id}/tasks", s.taskSvc.CreateHandler)
+		s.mux.HandleFunc("GET /projects/{id}/tasks", s.taskSvc.ListHandler)
+		s.mux.HandleFunc("GET /tasks/{id}", s.taskSvc.GetHandler)
+		s.mux.HandleFunc("PATCH /tasks/{id}", s.taskSvc.UpdateHandler)
+		s.mux.HandleFunc("DELETE /tasks/{id}", s.taskSvc.DeleteHandler)
+		s.mux.HandleFunc("POST /tasks/{id}/status", s.taskSvc.TransitionHandler)
+	}
+
+	if s.attempts != nil {
+		s.mux.HandleFunc("GET /tasks/{id}/attempts", s.attempts.ListHandler)
+		s.mux.HandleFunc("GET /attempts/{id}", s.attempts.GetHandler)
+		s.mux.HandleFunc("GET /attempts/{id}/logs", s.attempts.LogsHandler)
+	}
+
+	if s.runners != nil {
+		s.mux.HandleFunc("GET /runners", s.runners.ListHandler)
+		s.mux.HandleFunc("POST /runners/{id}/stop", s.runners.StopHandler)
+		s.mux.HandleFunc("POST /runners/{id}/restart", s.runners.RestartHandler)
+	}
+
+	if s.reaper != nil {
+		s.mux.HandleFunc("GET /reaper", s.reaper.StatsHandler)
+		s.mux.HandleFunc("POST /reaper/run", s.reaper.RunHandler)
+	}
+
+	if s.secrets != nil {
+		s.mux.HandleFunc("PUT /secrets/{name}", s.secrets.SetSecretHandler)
 	}
 
 	if s.sse != nil {
@@ -22,6 +56,23 @@ func (s *Server) routes() {
 	}
 }
 
+// handleHealth is the liveness probe: it always answers 200 while the process
+// is up, but includes the component breakdown for humans and the dashboard.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	httpjson.Write(w, http.StatusOK, s.health.Run(r.Context()))
+}
+
+// handleReady answers 503 unless every component (Postgres, pgvector, Docker,
+// LLM) is usable, i.e. Monarch can actually run gates.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Run(r.Context())
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	httpjson.Write(w, status, report)
+}
+
 func (s *Server) logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -47,7 +98,7 @@ func (s *Server) recoverer(next http.Handler) http.Handler {
 					"error", err,
 					"stack", string(debug.Stack()),
 				)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				httpjson.Error(w, http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -63,3 +114,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streaming handlers can flush through the logger.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
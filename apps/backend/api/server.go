@@ -5,26 +5,43 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/monarch-dev/monarch/attempt"
 	"github.com/monarch-dev/monarch/config"
+	"github.com/monarch-dev/monarch/health"
 	"github.com/monarch-dev/monarch/project"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/settings"
+	"github.com/monarch-dev/monarch/task"
 )
 
 type Server struct {
-	mux     *http.ServeMux
-	handler http.Handler
-	db      *pgxpool.Pool
-	cfg     *config.Config
-	projSvc *project.Service
-	sse     *mcp.SSEHandler
+	mux      *http.ServeMux
+	handler  http.Handler
+	db       *pgxpool.Pool
+	cfg      *config.Config
+	projSvc  *project.Service
+	taskSvc  *task.Service
+	attempts *attempt.Service
+	runners  *runner.Manager
+	reaper   *runner.Reaper
+	secrets  *settings.Service
+	sse      *mcp.SSEHandler
+	health   *health.Checker
 }
 
-func NewServer(cfg *config.Config, db *pgxpool.Pool, projSvc *project.Service, sse *mcp.SSEHandler) *Server {
+func NewServer(cfg *config.Config, db *pgxpool.Pool, projSvc *project.Service, taskSvc *task.Service, attempts *attempt.Service, runners *runner.Manager, reaper *runner.Reaper, secrets *settings.Service, sse *mcp.SSEHandler, checker *health.Checker) *Server {
 	s := &Server{
-		mux:     http.NewServeMux(),
-		db:      db,
-		cfg:     cfg,
-		projSvc: projSvc,
-		sse:     sse,
+		mux:      http.NewServeMux(),
+		db:       db,
+		cfg:      cfg,
+		projSvc:  projSvc,
+		taskSvc:  taskSvc,
+		attempts: attempts,
+		runners:  runners,
+		reaper:   reaper,
+		secrets:  secrets,
+		sse:      sse,
+		health:   checker,
 	}
 	s.routes()
 	s.handler = s.recoverer(s.logger(s.mux))
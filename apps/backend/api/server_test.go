@@ -1,18 +1,23 @@
 package api_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/monarch-dev/monarch/api"
 	"github.com/monarch-dev/monarch/config"
+	"github.com/monarch-dev/monarch/health"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServer_Health(t *testing.T) {
 	cfg := &config.Config{Env: "test", Port: 8080}
-	srv := api.NewServer(cfg, nil, nil, nil)
+	srv := api.NewServer(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -20,5 +25,62 @@ func TestServer_Health(t *testing.T) {
 	srv.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Equal(t, "OK", w.Body.String())
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusOK, report.Status)
+}
+
+func TestServer_Ready(t *testing.T) {
+	failing := health.Component{Name: "docker", Check: func(ctx context.Context) error {
+		return errors.New("daemon unreachable")
+	}}
+	passing := health.Component{Name: "database", Check: func(ctx context.Context) error {
+		return nil
+	}}
+
+	tests := []struct {
+		name       string
+		components []health.Component
+		wantCode   int
+	}{
+		{"AllHealthy", []health.Component{passing}, http.StatusOK},
+		{"ComponentDown", []health.Component{passing, failing}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Env: "test", Port: 8080}
+			srv := api.NewServer(cfg, nil, nil, nil, nil, nil, nil, nil, nil, health.NewChecker(tt.components...))
+
+			req := httptest.NewRequest("GET", "/ready", nil)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			var report health.Report
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Len(t, report.Components, len(tt.components))
+		})
+	}
+}
+
+func TestServer_HealthStaysUpWhenDegraded(t *testing.T) {
+	failing := health.Component{Name: "docker", Check: func(ctx context.Context) error {
+		return errors.New("daemon unreachable")
+	}}
+	cfg := &config.Config{Env: "test", Port: 8080}
+	srv := api.NewServer(cfg, nil, nil, nil, nil, nil, nil, nil, nil, health.NewChecker(failing))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "daemon unreachable", report.Components["docker"].Error)
 }
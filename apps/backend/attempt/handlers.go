@@ -0,0 +1,131 @@
+package attempt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/internal/httpjson"
+)
+
+// ListHandler serves GET /tasks/{id}/attempts.
+func (s *Service) ListHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := pathUUID(w, r, "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	attempts, err := s.List(r.Context(), taskID)
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, attempts)
+}
+
+// GetHandler serves GET /attempts/{id}.
+func (s *Service) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid attempt ID")
+	if !ok {
+		return
+	}
+
+	a, err := s.Get(r.Context(), id)
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, a)
+}
+
+// LogsHandler serves GET /attempts/{id}/logs as a server-sent event stream:
+// the stored output first, then live lines while the attempt runs. Each line
+// is a "line" event carrying a LogLine; a "done" event ends the stream.
+func (s *Service) LogsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid attempt ID")
+	if !ok {
+		return
+	}
+
+	// Subscribe before reading history so no line falls between the two.
+	var live <-chan LogLine
+	if s.hub != nil {
+		ch, cancel := s.hub.Subscribe(id)
+		defer cancel()
+		live = ch
+	}
+
+	a, err := s.q.GetAttempt(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrNotFound
+		}
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+	stored, err := s.Logs(r.Context(), id)
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	replayed := make(map[string]int64)
+	for _, line := range stored {
+		writeEvent(w, "line", line)
+		replayed[line.Gate] = max(replayed[line.Gate], line.Seq)
+	}
+	if live == nil || Status(a.Status) != StatusRunning {
+		writeEvent(w, "done", struct{}{})
+		return
+	}
+	_ = rc.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-live:
+			if !ok {
+				writeEvent(w, "done", struct{}{})
+				_ = rc.Flush()
+				return
+			}
+			if line.Seq <= replayed[line.Gate] {
+				continue
+			}
+			writeEvent(w, "line", line)
+			_ = rc.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+func statusCode(err error) int {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrTaskNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func pathUUID(w http.ResponseWriter, r *http.Request, msg string) (pgtype.UUID, bool) {
+	var id pgtype.UUID
+	if err := id.Scan(r.PathValue("id")); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, msg)
+		return id, false
+	}
+	return id, true
+}
@@ -0,0 +1,53 @@
+package attempt_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/monarch-dev/monarch/attempt"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	q := dbtest.Backends(t)[0].Querier
+	svc := attempt.NewService(q)
+	tk := newTask(t, q)
+	run, err := svc.Start(context.Background(), tk.ID, 1)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks/{id}/attempts", svc.ListHandler)
+	mux.HandleFunc("GET /attempts/{id}", svc.GetHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{"List", "/tasks/" + tk.ID.String() + "/attempts", http.StatusOK},
+		{"ListUnknownTask", "/tasks/00000000-0000-0000-0000-00000000dead/attempts", http.StatusNotFound},
+		{"Get", "/attempts/" + run.ID.String(), http.StatusOK},
+		{"GetBadID", "/attempts/nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/tasks/"+tk.ID.String()+"/attempts", nil))
+	var body []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body, 1)
+	assert.Equal(t, "RUNNING", body[0]["status"])
+	assert.Equal(t, []any{}, body[0]["gates"])
+}
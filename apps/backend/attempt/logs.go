@@ -0,0 +1,181 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/runner"
+)
+
+// MaxLogBytes caps the output stored per gate while it runs. Live
+// subscribers still see every line; the gate result keeps the final tail.
+const MaxLogBytes = 1 << 20
+
+// logBuffer is how many lines a gate may get ahead of the database before
+// the command's output copy waits for it.
+const logBuffer = 1024
+
+// subscriberBuffer is how many lines a subscriber may lag before it starts
+// missing them.
+const subscriberBuffer = 256
+
+// LogLine is one line of a gate's output. Seq numbers a gate's lines from 1.
+type LogLine struct {
+	Gate   string    `json:"gate"`
+	Seq    int64     `json:"seq"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// Hub fans live log lines out to the subscribers of each attempt.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[pgtype.UUID]map[chan LogLine]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[pgtype.UUID]map[chan LogLine]struct{})}
+}
+
+// Subscribe returns the attempt's live lines. The channel is closed when the
+// attempt finishes; cancel stops the subscription early. A subscriber that
+// falls behind misses lines rather than stalling the gate.
+func (h *Hub) Subscribe(attemptID pgtype.UUID) (<-chan LogLine, func()) {
+	ch := make(chan LogLine, subscriberBuffer)
+	h.mu.Lock()
+	if h.subs[attemptID] == nil {
+		h.subs[attemptID] = make(map[chan LogLine]struct{})
+	}
+	h.subs[attemptID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[attemptID][ch]; ok {
+			delete(h.subs[attemptID], ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *Hub) publish(attemptID pgtype.UUID, line LogLine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[attemptID] {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// end closes the attempt's subscriptions.
+func (h *Hub) end(attemptID pgtype.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[attemptID] {
+		close(ch)
+	}
+	delete(h.subs, attemptID)
+}
+
+// GateLog records one gate's output as it streams: every line goes to the
+// hub, and lines are stored in the background until MaxLogBytes.
+type GateLog struct {
+	attemptID pgtype.UUID
+	gate      string
+	hub       *Hub
+	seq       int64
+	stored    int
+	truncated bool
+	lines     chan database.CreateGateLogLineParams
+	done      chan struct{}
+	err       error
+}
+
+// OpenLog starts the log for gate. Lines are stored under ctx, so pass one
+// that outlives a cancelled request; Close must be called when the gate ends.
+func (s *Service) OpenLog(ctx context.Context, attemptID pgtype.UUID, gate string) *GateLog {
+	l := &GateLog{
+		attemptID: attemptID,
+		gate:      gate,
+		hub:       s.hub,
+		lines:     make(chan database.CreateGateLogLineParams, logBuffer),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		for p := range l.lines {
+			if l.err != nil {
+				continue
+			}
+			if err := s.q.CreateGateLogLine(ctx, p); err != nil {
+				l.err = fmt.Errorf("failed to store log for gate %s: %w", gate, err)
+			}
+		}
+	}()
+	return l
+}
+
+// Write records line. It is a runner.LineSink and, like one, must not be
+// called concurrently.
+func (l *GateLog) Write(line runner.Line) {
+	l.seq++
+	if l.hub != nil {
+		l.hub.publish(l.attemptID, LogLine{Gate: l.gate, Seq: l.seq, Stream: line.Stream, Text: line.Text, Time: line.Time})
+	}
+
+	if l.truncated {
+		return
+	}
+	text := sanitize(line.Text)
+	if l.stored+len(text) > MaxLogBytes {
+		l.truncated = true
+		text = fmt.Sprintf("[log truncated: only the first %d bytes are stored]", MaxLogBytes)
+	}
+	l.stored += len(text)
+	l.lines <- database.CreateGateLogLineParams{
+		AttemptID: l.attemptID,
+		GateName:  l.gate,
+		Seq:       l.seq,
+		Stream:    line.Stream,
+		Line:      text,
+		LoggedAt:  pgtype.Timestamptz{Time: line.Time, Valid: true},
+	}
+}
+
+// Close waits for the stored lines to be written and returns the first
+// error writing them.
+func (l *GateLog) Close() error {
+	close(l.lines)
+	<-l.done
+	return l.err
+}
+
+// Logs returns the attempt's stored output lines in order.
+func (s *Service) Logs(ctx context.Context, attemptID pgtype.UUID) ([]LogLine, error) {
+	if _, err := s.q.GetAttempt(ctx, attemptID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.q.ListGateLogLines(ctx, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]LogLine, len(rows))
+	for i, r := range rows {
+		lines[i] = LogLine{Gate: r.GateName, Seq: r.Seq, Stream: r.Stream, Text: r.Line, Time: r.LoggedAt.Time}
+	}
+	return lines, nil
+}
@@ -0,0 +1,126 @@
+package attempt_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monarch-dev/monarch/attempt"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateLog_StoresAndPublishes(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			hub := attempt.NewHub()
+			svc := attempt.NewService(b.Querier).WithHub(hub)
+			tk := newTask(t, b.Querier)
+			run, err := svc.Start(ctx, tk.ID, 1)
+			require.NoError(t, err)
+
+			live, cancel := hub.Subscribe(run.ID)
+			defer cancel()
+
+			log := svc.OpenLog(ctx, run.ID, "test")
+			log.Write(runner.Line{Time: time.Now(), Stream: runner.StreamStdout, Text: "=== RUN TestA"})
+			log.Write(runner.Line{Time: time.Now(), Stream: runner.StreamStderr, Text: "bad\x00byte"})
+			require.NoError(t, log.Close())
+
+			first := <-live
+			assert.Equal(t, attempt.LogLine{Gate: "test", Seq: 1, Stream: "stdout", Text: "=== RUN TestA", Time: first.Time}, first)
+			assert.Equal(t, int64(2), (<-live).Seq)
+
+			lines, err := svc.Logs(ctx, run.ID)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			assert.Equal(t, "=== RUN TestA", lines[0].Text)
+			assert.Equal(t, "badbyte", lines[1].Text)
+			assert.Equal(t, "stderr", lines[1].Stream)
+
+			require.NoError(t, svc.Finish(ctx, run.ID, attempt.StatusPassed))
+			_, open := <-live
+			assert.False(t, open, "finishing the attempt ends its subscriptions")
+		})
+	}
+}
+
+func TestGateLog_CapsStoredOutput(t *testing.T) {
+	ctx := context.Background()
+	q := dbtest.Backends(t)[0].Querier
+	svc := attempt.NewService(q)
+	tk := newTask(t, q)
+	run, err := svc.Start(ctx, tk.ID, 1)
+	require.NoError(t, err)
+
+	log := svc.OpenLog(ctx, run.ID, "test")
+	chunk := strings.Repeat("x", 64<<10)
+	for range 20 {
+		log.Write(runner.Line{Time: time.Now(), Stream: runner.StreamStdout, Text: chunk})
+	}
+	require.NoError(t, log.Close())
+
+	lines, err := svc.Logs(ctx, run.ID)
+	require.NoError(t, err)
+	require.Len(t, lines, 17)
+	assert.Contains(t, lines[16].Text, "log truncated")
+	assert.Equal(t, int64(17), lines[16].Seq)
+}
+
+func TestLogsHandler(t *testing.T) {
+	ctx := context.Background()
+	q := dbtest.Backends(t)[0].Querier
+	hub := attempt.NewHub()
+	svc := attempt.NewService(q).WithHub(hub)
+	tk := newTask(t, q)
+	run, err := svc.Start(ctx, tk.ID, 1)
+	require.NoError(t, err)
+
+	log := svc.OpenLog(ctx, run.ID, "build")
+	log.Write(runner.Line{Time: time.Now(), Stream: runner.StreamStdout, Text: "stored"})
+	require.NoError(t, log.Close())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /attempts/{id}/logs", svc.LogsHandler)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/attempts/" + run.ID.String() + "/logs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+				events <- data
+			}
+		}
+		close(events)
+	}()
+
+	assert.Contains(t, <-events, `"text":"stored"`)
+
+	live := svc.OpenLog(ctx, run.ID, "test")
+	live.Write(runner.Line{Time: time.Now(), Stream: runner.StreamStderr, Text: "live"})
+	require.NoError(t, live.Close())
+	assert.Contains(t, <-events, `"text":"live"`)
+
+	require.NoError(t, svc.Finish(ctx, run.ID, attempt.StatusPassed))
+	assert.Equal(t, "{}", <-events)
+	_, open := <-events
+	assert.False(t, open)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/attempts/00000000-0000-0000-0000-00000000dead/logs", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,216 @@
+// Package attempt records the history of submitted attempts: which gates ran,
+// how they ended, and why they failed.
+package attempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/runner/parser"
+)
+
+// MaxOutputBytes caps each stored stdout/stderr stream. The tail is kept
+// since that's where test runners and linters summarise failures.
+const MaxOutputBytes = runner.MaxOutputBytes
+
+type Status string
+
+const (
+	StatusRunning     Status = "RUNNING"
+	StatusPassed      Status = "PASSED"
+	StatusFailed      Status = "FAILED"
+	StatusInterrupted Status = "INTERRUPTED"
+)
+
+var (
+	ErrNotFound     = errors.New("attempt not found")
+	ErrTaskNotFound = errors.New("task not found")
+)
+
+// Attempt is an attempt with its gate results, as served to clients.
+type Attempt struct {
+	database.Attempt
+	Gates []Gate `json:"gates"`
+}
+
+type Gate struct {
+	database.GateResult
+	Findings []parser.LogEntry `json:"findings"`
+}
+
+type Service struct {
+	q   database.Querier
+	hub *Hub
+}
+
+func NewService(q database.Querier) *Service {
+	return &Service{q: q}
+}
+
+// WithHub publishes live gate output to hub, for LogsHandler to serve.
+func (s *Service) WithHub(hub *Hub) *Service {
+	s.hub = hub
+	return s
+}
+
+// Start opens a RUNNING attempt; number is the task's attempt count.
+func (s *Service) Start(ctx context.Context, taskID pgtype.UUID, number int32) (database.Attempt, error) {
+	return s.q.CreateAttempt(ctx, database.CreateAttemptParams{TaskID: taskID, Number: number})
+}
+
+// SetSnapshot records the workspace snapshot the attempt's gates validate:
+// the commit it was taken at and the hash of the changes on top of it.
+func (s *Service) SetSnapshot(ctx context.Context, id pgtype.UUID, commit, diffHash string) error {
+	_, err := s.q.SetAttemptSnapshot(ctx, database.SetAttemptSnapshotParams{
+		ID:               id,
+		SnapshotCommit:   commit,
+		SnapshotDiffHash: diffHash,
+	})
+	return err
+}
+
+func (s *Service) RecordGate(ctx context.Context, attemptID pgtype.UUID, res runner.GateResult) error {
+	params := database.CreateGateResultParams{
+		AttemptID:  attemptID,
+		GateName:   res.Gate,
+		Status:     string(res.Outcome),
+		DurationMs: res.Duration.Milliseconds(),
+		Stdout:     capOutput(res.Stdout),
+		Stderr:     capOutput(res.Stderr),
+	}
+	if res.ExitCode != nil {
+		params.ExitCode = pgtype.Int4{Int32: int32(*res.ExitCode), Valid: true}
+	}
+	if res.Cause != nil {
+		params.Cause = sanitize(res.Cause.Error())
+	}
+
+	result, err := s.q.CreateGateResult(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to record gate %s: %w", res.Gate, err)
+	}
+
+	for _, f := range res.Findings {
+		err := s.q.CreateGateFinding(ctx, database.CreateGateFindingParams{
+			GateResultID: result.ID,
+			Severity:     string(f.Severity),
+			File:         sanitize(f.File),
+			Line:         int32(f.Line),
+			Message:      sanitize(f.Message),
+			Tool:         f.Tool,
+			RuleID:       f.RuleID,
+			Hint:         f.Hint,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record finding for gate %s: %w", res.Gate, err)
+		}
+	}
+	return nil
+}
+
+// Finish closes the attempt with status and ends its live log.
+func (s *Service) Finish(ctx context.Context, id pgtype.UUID, status Status) error {
+	_, err := s.q.FinishAttempt(ctx, database.FinishAttemptParams{ID: id, Status: string(status)})
+	if s.hub != nil {
+		s.hub.end(id)
+	}
+	return err
+}
+
+func (s *Service) Get(ctx context.Context, id pgtype.UUID) (*Attempt, error) {
+	a, err := s.q.GetAttempt(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.load(ctx, a)
+}
+
+// List returns the task's attempts, oldest first.
+func (s *Service) List(ctx context.Context, taskID pgtype.UUID) ([]Attempt, error) {
+	if _, err := s.q.GetTask(ctx, taskID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.q.ListAttempts(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]Attempt, 0, len(rows))
+	for _, row := range rows {
+		a, err := s.load(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, *a)
+	}
+	return attempts, nil
+}
+
+func (s *Service) load(ctx context.Context, a database.Attempt) (*Attempt, error) {
+	results, err := s.q.ListGateResults(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := s.q.ListAttemptFindings(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byResult := make(map[pgtype.UUID][]parser.LogEntry)
+	for _, f := range findings {
+		byResult[f.GateResultID] = append(byResult[f.GateResultID], parser.LogEntry{
+			Severity: parser.Severity(f.Severity),
+			File:     f.File,
+			Line:     int(f.Line),
+			Message:  f.Message,
+			Tool:     f.Tool,
+			RuleID:   f.RuleID,
+			Hint:     f.Hint,
+		})
+	}
+
+	gates := make([]Gate, len(results))
+	for i, r := range results {
+		gates[i] = Gate{GateResult: r, Findings: byResult[r.ID]}
+		if gates[i].Findings == nil {
+			gates[i].Findings = []parser.LogEntry{}
+		}
+	}
+	return &Attempt{Attempt: a, Gates: gates}, nil
+}
+
+// capOutput keeps the last MaxOutputBytes of s.
+func capOutput(s string) string {
+	s = sanitize(s)
+	if len(s) <= MaxOutputBytes {
+		return s
+	}
+	dropped := len(s) - MaxOutputBytes
+	tail := s[dropped:]
+	// Don't start mid-rune.
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+		dropped++
+	}
+	return fmt.Sprintf("[truncated %d bytes]\n%s", dropped, tail)
+}
+
+// sanitize makes tool output storable as TEXT: Postgres rejects NUL bytes
+// and invalid UTF-8.
+func sanitize(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\x00", "")
+}
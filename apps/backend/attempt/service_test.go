@@ -0,0 +1,120 @@
+package attempt_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/attempt"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/runner/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTask(t *testing.T, q database.Querier) database.Task {
+	ctx := context.Background()
+	proj, err := q.CreateProject(ctx, t.TempDir())
+	require.NoError(t, err)
+	tk, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: "BACKLOG"})
+	require.NoError(t, err)
+	return tk
+}
+
+func TestService_RecordAndList(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := attempt.NewService(b.Querier)
+			tk := newTask(t, b.Querier)
+
+			run, err := svc.Start(ctx, tk.ID, 1)
+			require.NoError(t, err)
+			require.NoError(t, svc.SetSnapshot(ctx, run.ID, "abc123", "d1ff"))
+
+			exit := 1
+			require.NoError(t, svc.RecordGate(ctx, run.ID, runner.GateResult{
+				Gate:     "build",
+				Outcome:  runner.OutcomePass,
+				Duration: 1500 * time.Millisecond,
+			}))
+			require.NoError(t, svc.RecordGate(ctx, run.ID, runner.GateResult{
+				Gate:     "lint",
+				Outcome:  runner.OutcomeValidationFailure,
+				Duration: time.Second,
+				ExitCode: &exit,
+				Stdout:   "bad\x00bytes\xff",
+				Findings: []parser.LogEntry{{Severity: parser.SeverityError, File: "app.ts", Line: 3, Message: "nope", Tool: "eslint"}},
+			}))
+			require.NoError(t, svc.RecordGate(ctx, run.ID, runner.GateResult{
+				Gate:    "test",
+				Outcome: runner.OutcomeSystemError,
+				Cause:   errors.New("docker unreachable"),
+			}))
+			require.NoError(t, svc.Finish(ctx, run.ID, attempt.StatusFailed))
+
+			attempts, err := svc.List(ctx, tk.ID)
+			require.NoError(t, err)
+			require.Len(t, attempts, 1)
+
+			got := attempts[0]
+			assert.Equal(t, "FAILED", got.Status)
+			assert.Equal(t, "abc123", got.SnapshotCommit)
+			assert.Equal(t, "d1ff", got.SnapshotDiffHash)
+			require.Len(t, got.Gates, 3)
+			assert.Equal(t, "build", got.Gates[0].GateName)
+			assert.Equal(t, int64(1500), got.Gates[0].DurationMs)
+			assert.Empty(t, got.Gates[0].Findings)
+
+			lint := got.Gates[1]
+			assert.Equal(t, "VALIDATION_FAILURE", lint.Status)
+			assert.Equal(t, int32(1), lint.ExitCode.Int32)
+			assert.Equal(t, "badbytes�", lint.Stdout)
+			require.Len(t, lint.Findings, 1)
+			assert.Equal(t, "app.ts", lint.Findings[0].File)
+
+			assert.Equal(t, "SYSTEM_ERROR", got.Gates[2].Status)
+			assert.Equal(t, "docker unreachable", got.Gates[2].Cause)
+			assert.False(t, got.Gates[2].ExitCode.Valid)
+
+			single, err := svc.Get(ctx, run.ID)
+			require.NoError(t, err)
+			assert.Equal(t, got.ID, single.ID)
+		})
+	}
+}
+
+func TestService_NotFound(t *testing.T) {
+	svc := attempt.NewService(dbtest.Backends(t)[0].Querier)
+	missing := pgtype.UUID{Bytes: [16]byte{0xde, 0xad}, Valid: true}
+
+	_, err := svc.Get(context.Background(), missing)
+	assert.ErrorIs(t, err, attempt.ErrNotFound)
+
+	_, err = svc.List(context.Background(), missing)
+	assert.ErrorIs(t, err, attempt.ErrTaskNotFound)
+}
+
+func TestService_CapsOutput(t *testing.T) {
+	q := dbtest.Backends(t)[0].Querier
+	svc := attempt.NewService(q)
+	tk := newTask(t, q)
+	ctx := context.Background()
+
+	run, err := svc.Start(ctx, tk.ID, 1)
+	require.NoError(t, err)
+
+	huge := strings.Repeat("x", attempt.MaxOutputBytes) + "the end"
+	require.NoError(t, svc.RecordGate(ctx, run.ID, runner.GateResult{Gate: "test", Outcome: runner.OutcomePass, Stderr: huge}))
+
+	got, err := svc.Get(ctx, run.ID)
+	require.NoError(t, err)
+	stderr := got.Gates[0].Stderr
+	assert.True(t, strings.HasPrefix(stderr, "[truncated 7 bytes]\n"), stderr[:40])
+	assert.True(t, strings.HasSuffix(stderr, "the end"))
+}
@@ -2,22 +2,35 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
-
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/docker/docker/client"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/monarch-dev/monarch/api"
+	"github.com/monarch-dev/monarch/attempt"
 	"github.com/monarch-dev/monarch/config"
 	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/health"
+	"github.com/monarch-dev/monarch/internal/lifecycle"
+	"github.com/monarch-dev/monarch/internal/llm"
+	"github.com/monarch-dev/monarch/internal/llm/gemini"
 	monarchmcp "github.com/monarch-dev/monarch/mcp"
 	"github.com/monarch-dev/monarch/mcp/tools"
 	"github.com/monarch-dev/monarch/project"
 	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/runner/eval"
+	"github.com/monarch-dev/monarch/settings"
+	"github.com/monarch-dev/monarch/snapshot"
+	"github.com/monarch-dev/monarch/task"
 )
 
 func main() {
@@ -28,73 +41,238 @@ func main() {
 }
 
 func run() error {
-	cfg, err := config.Load()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	ctx := context.Background()
+	// ctx is cancelled on SIGINT/SIGTERM, which starts the shutdown sequence.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize DB
-	if cfg.DB == "" {
-		return fmt.Errorf("database URL is required")
+	store, err := openStorage(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+
+	if interrupted, err := store.querier.ListInterruptedTasks(ctx); err != nil {
+		fmt.Printf("Warning: failed to list interrupted tasks: %v\n", err)
+	} else {
+		for _, t := range interrupted {
+			fmt.Printf("Task %s was interrupted by the last shutdown; resubmit to retry\n", t.ID)
+		}
 	}
 
-	pool, err := database.Connect(ctx, cfg.DB)
+	// Initialize the container runtime
+	rt, err := openRuntime(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
 	}
-	defer pool.Close()
-	fmt.Println("Connected to database")
+	defer rt.close()
 
-	// Initialize Docker Client
-	dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	instance, err := instanceID(ctx, store.querier)
 	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
+		return fmt.Errorf("failed to load instance ID: %w", err)
 	}
-	defer dockerCli.Close()
+	images := runner.NewImageRegistry(rt.images, cfg.Images).WithPullProgress(logPull)
 
-	// 1. Reaper: Clean up zombies
-	cleaned, err := runner.ReapZombies(ctx, dockerCli)
+	// 1. Runner Manager: adopt warm runners from the last run, reap the rest
+	runMgr := runner.NewManager(rt.runtime).WithInstance(instance).WithRetention(cfg.StoppedRetention).
+		WithRemoveOnShutdown(cfg.RemoveRunnersOnShutdown)
+	adopted, reaped, err := runMgr.Adopt(ctx, currentImage(store.projects, images))
 	if err != nil {
 		// Log but don't fail, as it might be permission issue or transient
-		fmt.Printf("Warning: failed to reap zombies: %v\n", err)
-	} else if cleaned > 0 {
-		fmt.Printf("Reaped %d zombie containers\n", cleaned)
+		fmt.Printf("Warning: failed to reap some runners: %v\n", err)
+	}
+	if adopted > 0 || reaped > 0 {
+		fmt.Printf("Adopted %d warm runners, reaped %d zombie containers\n", adopted, reaped)
 	}
 
-	// 2. Runner Manager: Start monitoring
-	runMgr := runner.NewManager(dockerCli)
-	// Check idle every minute, timeout after 5 minutes
-	runMgr.StartMonitor(ctx, 1*time.Minute, 5*time.Minute)
+	// Snapshots left by the last run belong to attempts that are over.
+	snapshots := snapshot.NewSnapshotter(cfg.SnapshotDir)
+	if err := snapshots.Clean(); err != nil {
+		fmt.Printf("Warning: failed to remove old workspace snapshots: %v\n", err)
+	}
+
+	// 2. Start monitoring, and reaping what runners leave behind
+	runMgr.StartMonitor(ctx, cfg.MonitorInterval, cfg.IdleTimeout)
+	reaper := runner.NewReaper(rt.runtime, runMgr).WithResources(rt.resources).WithDryRun(cfg.ReapDryRun)
+	reaper.Start(ctx, cfg.ReapInterval)
+
+	// 3. LLM: optional, only LLM eval gates need it
+	var evalEngine *eval.Engine
+	if cfg.LLM.APIKey != "" {
+		llmClient, err := newLLMClient(cfg.LLM)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		defer llmClient.Close()
+		evalEngine = eval.NewEngine(llmClient, cfg.FileSizeLimit)
+	} else {
+		fmt.Println("Warning: no LLM API key configured, LLM eval gates are disabled")
+	}
+	scheduler := runner.NewScheduler(cfg.GateWorkers).WithParallelism(cfg.Parallelism)
+	runSvc := runner.NewService(runMgr, images, runner.NewExecutor(rt.runtime), evalEngine).
+		WithLimits(cfg.Resources).
+		WithGateTimeout(cfg.GateTimeout).
+		WithScheduler(scheduler).
+		WithBuilder(rt.builder)
+	var secrets *settings.Service
+	if len(cfg.EncryptionKey) > 0 {
+		secrets = settings.NewService(store.querier, cfg.EncryptionKey)
+		runSvc.WithSecrets(secrets)
+	} else {
+		fmt.Println("Warning: no encryption key configured, gates can't use secrets")
+	}
 
 	// Initialize Services
-	projStore := project.NewPostgresStore(pool)
-	projSvc := project.NewService(projStore)
-	
+	projSvc := project.NewService(store.projects).WithRunners(runMgr)
+
 	// Initialize MCP
 	mcpServer := monarchmcp.NewServer()
-	
+
 	// Planner Tools
 	planner := tools.NewPlanner(projSvc)
 	planner.Register(mcpServer)
-	
+
 	// Builder Tools
-	// For now, runner service is nil or needs implementation.
-	// We pass nil as placeholder or simple executor wrapper if available.
-	// But main.go shouldn't be too complex.
-	builderStore := database.New(pool)
-	builder := tools.NewBuilder(builderStore, nil) // runner service is nil for now
+	tracker := lifecycle.NewTracker()
+	logHub := attempt.NewHub()
+	builder := tools.NewBuilder(store.querier, runSvc, tracker).WithHub(logHub).WithSnapshots(snapshots)
 	builder.Register(mcpServer)
 
 	sseServer := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
 		return mcpServer
 	}, nil)
 
+	checks := append(store.checks, rt.checks...)
+	checks = append(checks, health.LLM(cfg.LLM.Provider, cfg.LLM.APIKey))
+	checker := health.NewChecker(checks...)
+
 	// Initialize Server
-	srv := api.NewServer(cfg, pool, projSvc, sseServer)
+	srv := api.NewServer(cfg, store.pool, projSvc, task.NewService(store.querier).WithQueue(scheduler), attempt.NewService(store.querier).WithHub(logHub), runMgr, reaper, secrets, sseServer, checker)
+
+	httpSrv := &http.Server{Addr: cfg.Addr(), Handler: srv}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpSrv.ListenAndServe()
+	}()
+
+	fmt.Printf("Monarch Supervisor listening on %s [%s]\n", cfg.Addr(), cfg.Env)
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	stop()
+
+	fmt.Printf("Shutting down (waiting up to %s for in-flight work)\n", cfg.ShutdownTimeout)
+	return shutdown(cfg, tracker, httpSrv, runMgr)
+}
+
+// shutdown stops accepting new attempts, drains in-flight MCP calls and gate
+// runs until the deadline, then stops the HTTP server and releases runners,
+// leaving running ones warm for the next start unless configured to remove
+// them.
+// Deferred closers in run release the LLM client, runtime client and DB pool.
+func shutdown(cfg *config.Config, tracker *lifecycle.Tracker, httpSrv *http.Server, runMgr *runner.Manager) error {
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
 
-	fmt.Printf("Monarch Supervisor starting on port %d [%s]\n", cfg.Port, cfg.Env)
+	interrupted := tracker.Drain(drainCtx)
+	for _, op := range interrupted {
+		fmt.Printf("Interrupted: %s\n", op)
+	}
+
+	// Long-lived SSE streams never go idle, so Shutdown can only be given a
+	// short grace period before the remaining connections are closed.
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer httpCancel()
+	if err := httpSrv.Shutdown(httpCtx); err != nil {
+		_ = httpSrv.Close()
+	}
+
+	cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cleanupCancel()
+	kept, removed, err := runMgr.Shutdown(cleanupCtx)
+	if err != nil {
+		fmt.Printf("Warning: failed to remove some runners: %v\n", err)
+	}
 
-	return http.ListenAndServe(":"+strconv.Itoa(cfg.Port), srv)
+	fmt.Printf("Shutdown complete: %d operations interrupted, %d runners kept warm, %d runners removed\n", len(interrupted), kept, removed)
+	return nil
+}
+
+// instanceSetting holds the ID that tells this instance's runners apart from
+// those of other Monarch instances sharing the Docker daemon or namespace.
+const instanceSetting = "runner.instance_id"
+
+func instanceID(ctx context.Context, q database.Querier) (string, error) {
+	row, err := q.GetSetting(ctx, instanceSetting)
+	if err == nil {
+		return string(row.Value), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+
+	id := uuid.NewString()
+	err = q.UpsertSetting(ctx, database.UpsertSettingParams{
+		Key:         instanceSetting,
+		Value:       []byte(id),
+		IsEncrypted: pgtype.Bool{Bool: false, Valid: true},
+	})
+	return id, err
+}
+
+// currentImage checks a surviving runner against the project's current gate
+// config and image. A built image's tag hashes the snapshot it was built
+// from, so only how it is built is checked; like a warm runner's, its image
+// is rebuilt if need be when the runner is next replaced.
+func currentImage(projects project.Store, images *runner.ImageRegistry) runner.ImageCheck {
+	return func(ctx context.Context, projectID, stack, image, build string) bool {
+		var id pgtype.UUID
+		if err := id.Scan(projectID); err != nil {
+			return false
+		}
+		proj, err := projects.Get(ctx, id)
+		if err != nil {
+			return false
+		}
+		cfg, err := gates.DetectStack(proj.Path)
+		if err != nil || cfg.Stack != stack {
+			return false
+		}
+		if cfg.Runner.Build != nil {
+			return build == runner.BuildKey(*cfg.Runner.Build)
+		}
+		want, err := images.Resolve(ctx, stack, cfg.Image)
+		return build == "" && err == nil && want == image
+	}
+}
+
+// logPull prints image-level pull status and finished layers; per-byte
+// progress would flood the log.
+func logPull(p runner.PullProgress) {
+	switch {
+	case p.Layer == "":
+		fmt.Printf("Pulling %s: %s\n", p.Image, p.Status)
+	case p.Status == "Pull complete":
+		fmt.Printf("Pulling %s: layer %s done\n", p.Image, p.Layer)
+	}
+}
+
+func newLLMClient(cfg config.LLMConfig) (llm.Client, error) {
+	switch cfg.Provider {
+	case "gemini":
+		return gemini.NewClientWithModel(cfg.APIKey, cfg.Model)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider %q", cfg.Provider)
+	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/client"
+	"github.com/monarch-dev/monarch/config"
+	"github.com/monarch-dev/monarch/health"
+	"github.com/monarch-dev/monarch/runner"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// containers bundles everything that depends on the configured runtime.
+type containers struct {
+	runtime   runner.Runtime
+	images    runner.ImageClient   // nil on Kubernetes, where nodes pull images
+	builder   *runner.ImageBuilder // nil on Kubernetes, which can't build
+	resources runner.Resources     // nil on Kubernetes, where runners are only pods
+	checks    []health.Component
+	close     func()
+}
+
+// openRuntime connects to the runtime runners run on.
+func openRuntime(cfg *config.Config) (*containers, error) {
+	switch cfg.Runtime {
+	case config.RuntimeKubernetes:
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = cfg.Kubernetes.Kubeconfig
+		kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+		restConfig, err := kubeconfig.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		namespace := cfg.Kubernetes.Namespace
+		if namespace == "" {
+			if namespace, _, err = kubeconfig.Namespace(); err != nil {
+				return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+			}
+		}
+		cs, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+		}
+		fmt.Printf("Running runners as pods in namespace %s\n", namespace)
+
+		k := runner.NewKubernetes(cs, restConfig, namespace)
+		return &containers{
+			runtime: k,
+			checks:  []health.Component{{Name: "kubernetes", Check: k.Ping}},
+			close:   func() {},
+		}, nil
+
+	default:
+		dockerOpts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+		if cfg.DockerHost != "" {
+			dockerOpts = append(dockerOpts, client.WithHost(cfg.DockerHost))
+		}
+		dockerCli, err := client.NewClientWithOpts(dockerOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create docker client: %w", err)
+		}
+
+		docker := runner.NewDocker(dockerCli)
+		return &containers{
+			runtime:   docker,
+			images:    dockerCli,
+			builder:   runner.NewImageBuilder(dockerCli),
+			resources: docker,
+			checks:    []health.Component{health.Docker(dockerCli)},
+			close:     func() { dockerCli.Close() },
+		}, nil
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/monarch-dev/monarch/config"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/sqlite"
+	"github.com/monarch-dev/monarch/health"
+	"github.com/monarch-dev/monarch/project"
+)
+
+// storage bundles everything that depends on the configured backend.
+type storage struct {
+	querier  database.Querier
+	projects project.Store
+	pool     *pgxpool.Pool // nil in SQLite mode
+	checks   []health.Component
+	close    func()
+}
+
+// openStorage connects to the configured backend and applies migrations.
+func openStorage(ctx context.Context, cfg *config.Config) (*storage, error) {
+	switch cfg.Storage {
+	case config.StorageSQLite:
+		db, err := sqlite.Open(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+		}
+		fmt.Printf("Using SQLite database at %s\n", cfg.SQLitePath)
+
+		applied, err := sqlite.Migrate(ctx, db)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		reportMigrations(applied)
+
+		return &storage{
+			querier:  sqlite.New(db),
+			projects: project.NewSQLiteStore(db),
+			checks:   []health.Component{{Name: "database", Check: db.PingContext}},
+			close:    func() { db.Close() },
+		}, nil
+
+	default:
+		if cfg.DB == "" {
+			return nil, fmt.Errorf("database URL is required")
+		}
+
+		pool, err := database.Connect(ctx, cfg.DB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		fmt.Println("Connected to database")
+
+		applied, err := database.Migrate(ctx, pool)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		reportMigrations(applied)
+
+		return &storage{
+			querier:  database.New(pool),
+			projects: project.NewPostgresStore(pool),
+			pool:     pool,
+			checks:   []health.Component{health.Database(pool), health.PGVector(pool)},
+			close:    pool.Close,
+		}, nil
+	}
+}
+
+func reportMigrations(applied int) {
+	if applied > 0 {
+		fmt.Printf("Applied %d database migrations\n", applied)
+	}
+}
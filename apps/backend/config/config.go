@@ -1,37 +1,492 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/monarch-dev/monarch/gates"
+	"gopkg.in/yaml.v3"
 )
 
+// Config is resolved in layers: defaults < config file < env < CLI flags.
 type Config struct {
-	Port int
-	Env  string
-	DB   string
+	Port       int
+	Bind       string
+	Env        string
+	Storage    string
+	DB         string
+	SQLitePath string
+	// SnapshotDir holds the per-attempt workspace snapshots gates run
+	// against. Runners mount it, so it must be on the Docker host or, for
+	// Kubernetes, on every node runners may be scheduled on.
+	SnapshotDir string
+	// Runtime is where runners run: RuntimeDocker or RuntimeKubernetes.
+	Runtime         string
+	DockerHost      string
+	Kubernetes      KubernetesConfig
+	IdleTimeout     time.Duration
+	MonitorInterval time.Duration
+	// StoppedRetention is how long idle-stopped runners are kept for
+	// resuming before they are removed.
+	StoppedRetention time.Duration
+	// ReapInterval is how often runner containers, volumes and networks
+	// this instance no longer uses are removed.
+	ReapInterval time.Duration
+	// ReapDryRun makes the reaper only log what it would remove.
+	ReapDryRun bool
+	// RemoveRunnersOnShutdown removes runners on a graceful shutdown
+	// instead of leaving them running for the next start to adopt.
+	RemoveRunnersOnShutdown bool
+	ShutdownTimeout         time.Duration
+	GateTimeout             time.Duration
+	// GateWorkers is how many gates may run at once across all projects.
+	GateWorkers   int
+	FileSizeLimit int64
+	LLM           LLMConfig
+	// EncryptionKey encrypts the secrets gates use; without it, gates
+	// can't use secrets. It is set base64-encoded and must be 32 bytes.
+	EncryptionKey []byte
+	// Images overrides the default runner image per stack, set as
+	// images.<stack> in config.yaml.
+	Images map[string]string
+	// Resources sets runner limits per stack, as resources.<stack>.memory,
+	// .cpus, .pids and .tmpfs in config.yaml.
+	Resources map[string]gates.Resources
+	// Parallelism lets a project's gates run concurrently, per stack, as
+	// parallelism.<stack> in config.yaml. Unset stacks run one at a time.
+	Parallelism map[string]int
+}
+
+// KubernetesConfig locates the cluster runner pods are created in.
+type KubernetesConfig struct {
+	// Kubeconfig is the kubeconfig file to use; empty means the usual
+	// lookup ($KUBECONFIG, ~/.kube/config), then the in-cluster config.
+	Kubeconfig string
+	// Namespace defaults to the kubeconfig context's namespace.
+	Namespace string
+}
+
+type LLMConfig struct {
+	Provider string
+	Model    string
+	APIKey   string
+}
+
+// Addr returns the listen address for the HTTP server.
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Bind, strconv.Itoa(c.Port))
+}
+
+// ValidationError reports a config value that could not be applied.
+type ValidationError struct {
+	Key string
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config %q: %v", e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
 }
 
-func Load() (*Config, error) {
-	portStr := os.Getenv("MONARCH_PORT")
-	port := 9090
-	if portStr != "" {
-		var err error
-		port, err = strconv.Atoi(portStr)
-		if err != nil {
-			return nil, err
+// option describes one config key and where it can be set from.
+// Keys use dotted names matching the nesting in config.yaml.
+type option struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+}
+
+var options = []option{
+	{"port", "MONARCH_PORT", "port", "HTTP port"},
+	{"bind", "MONARCH_BIND", "bind", "bind address (use 0.0.0.0 to allow LAN access)"},
+	{"env", "MONARCH_ENV", "env", "environment name"},
+	{"storage", "MONARCH_STORAGE", "storage", "storage backend: postgres or sqlite"},
+	{"database_url", "DATABASE_URL", "database-url", "Postgres connection string"},
+	{"sqlite_path", "MONARCH_SQLITE_PATH", "sqlite-path", "SQLite database file for local mode"},
+	{"snapshot_dir", "MONARCH_SNAPSHOT_DIR", "snapshot-dir", "where workspace snapshots for gate runs are kept"},
+	{"runtime", "MONARCH_RUNTIME", "runtime", "where runners run: docker or kubernetes"},
+	{"docker_host", "DOCKER_HOST", "docker-host", "Docker daemon address"},
+	{"kubernetes.kubeconfig", "MONARCH_KUBECONFIG", "kubeconfig", "kubeconfig file for the kubernetes runtime"},
+	{"kubernetes.namespace", "MONARCH_KUBERNETES_NAMESPACE", "kubernetes-namespace", "namespace runner pods are created in"},
+	{"idle_timeout", "MONARCH_IDLE_TIMEOUT", "idle-timeout", "stop warm runners idle for this long"},
+	{"monitor_interval", "MONARCH_MONITOR_INTERVAL", "monitor-interval", "how often to check for idle runners"},
+	{"stopped_retention", "MONARCH_STOPPED_RETENTION", "stopped-retention", "remove stopped runners after this long instead of resuming them"},
+	{"reap_interval", "MONARCH_REAP_INTERVAL", "reap-interval", "how often to remove orphaned runner containers, volumes and networks"},
+	{"reap_dry_run", "MONARCH_REAP_DRY_RUN", "reap-dry-run", "only log what the reaper would remove"},
+	{"remove_runners_on_shutdown", "MONARCH_REMOVE_RUNNERS_ON_SHUTDOWN", "remove-runners-on-shutdown", "remove runners on shutdown instead of keeping them warm for the next start"},
+	{"shutdown_timeout", "MONARCH_SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long to wait for in-flight gate runs on shutdown"},
+	{"gate_timeout", "MONARCH_GATE_TIMEOUT", "gate-timeout", "kill gate commands running longer than this (gates.yaml can override per gate)"},
+	{"gate_workers", "MONARCH_GATE_WORKERS", "gate-workers", "max gates running at once across all projects"},
+	{"file_size_limit", "MONARCH_FILE_SIZE_LIMIT", "file-size-limit", "max bytes sent to LLM eval gates"},
+	{"llm.provider", "MONARCH_LLM_PROVIDER", "llm-provider", "LLM provider"},
+	{"llm.model", "MONARCH_LLM_MODEL", "llm-model", "LLM model (empty for provider default)"},
+	{"llm.api_key", "GEMINI_API_KEY", "", ""}, // Never accepted as a flag to keep it out of shell history.
+	{"encryption_key", "MONARCH_ENCRYPTION_KEY", "", ""},
+}
+
+// ConfigPathEnv overrides the default config file location.
+const ConfigPathEnv = "MONARCH_CONFIG"
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+const (
+	RuntimeDocker     = "docker"
+	RuntimeKubernetes = "kubernetes"
+)
+
+func defaults() *Config {
+	return &Config{
+		Port:             9090,
+		Bind:             "127.0.0.1",
+		Env:              "development",
+		Storage:          StoragePostgres,
+		SQLitePath:       dataPath("monarch.db"),
+		SnapshotDir:      dataPath("snapshots"),
+		Runtime:          RuntimeDocker,
+		IdleTimeout:      5 * time.Minute,
+		MonitorInterval:  1 * time.Minute,
+		StoppedRetention: 1 * time.Hour,
+		ReapInterval:     5 * time.Minute,
+		ShutdownTimeout:  30 * time.Second,
+		GateTimeout:      10 * time.Minute,
+		GateWorkers:      2,
+		FileSizeLimit:    100 * 1024,
+		LLM: LLMConfig{
+			Provider: "gemini",
+		},
+		Images:      make(map[string]string),
+		Resources:   make(map[string]gates.Resources),
+		Parallelism: make(map[string]int),
+	}
+}
+
+// Load resolves the configuration from all layers. args are the CLI
+// arguments without the program name.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("monarch", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config.yaml (default ~/.config/monarch/config.yaml)")
+	flagValues := make(map[string]*string)
+	for _, opt := range options {
+		if opt.flag != "" {
+			flagValues[opt.key] = fs.String(opt.flag, "", opt.usage)
 		}
 	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
 
-	return &Config{
-		Port: port,
-		Env:  getEnv("MONARCH_ENV", "development"),
-		DB:   os.Getenv("DATABASE_URL"),
-	}, nil
+	cfg := defaults()
+
+	// 1. Config file
+	path, explicit := resolvePath(*configPath)
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		for key, value := range values {
+			if err := cfg.set(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 2. Environment
+	for _, opt := range options {
+		if v := os.Getenv(opt.env); v != "" {
+			if err := cfg.set(opt.key, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 3. Flags (only those explicitly passed)
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.flag == f.Name && flagErr == nil {
+				flagErr = cfg.set(opt.key, *flagValues[opt.key])
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// dataPath returns the default location of name in Monarch's data dir.
+func dataPath(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".monarch", name)
+	}
+	return filepath.Join(home, ".monarch", name)
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+func resolvePath(flagPath string) (string, bool) {
+	if flagPath != "" {
+		return flagPath, true
+	}
+	if v := os.Getenv(ConfigPathEnv); v != "" {
+		return v, true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".config", "monarch", "config.yaml"), false
+}
+
+// readFile flattens config.yaml into dotted keys so every layer goes
+// through the same parsing and error reporting.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", raw, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]any, out map[string]string) error {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			if err := flatten(key, val, out); err != nil {
+				return err
+			}
+		case nil:
+			// An empty value leaves the lower layer in place.
+		case []any:
+			return &ValidationError{Key: key, Err: errors.New("lists are not supported")}
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+	return nil
+}
+
+func (c *Config) set(key, value string) error {
+	var err error
+	switch key {
+	case "port":
+		c.Port, err = strconv.Atoi(value)
+	case "bind":
+		c.Bind = value
+	case "env":
+		c.Env = value
+	case "storage":
+		c.Storage = value
+	case "database_url":
+		c.DB = value
+	case "sqlite_path":
+		c.SQLitePath = value
+	case "snapshot_dir":
+		c.SnapshotDir = value
+	case "runtime":
+		c.Runtime = value
+	case "docker_host":
+		c.DockerHost = value
+	case "kubernetes.kubeconfig":
+		c.Kubernetes.Kubeconfig = value
+	case "kubernetes.namespace":
+		c.Kubernetes.Namespace = value
+	case "idle_timeout":
+		c.IdleTimeout, err = time.ParseDuration(value)
+	case "monitor_interval":
+		c.MonitorInterval, err = time.ParseDuration(value)
+	case "stopped_retention":
+		c.StoppedRetention, err = time.ParseDuration(value)
+	case "reap_interval":
+		c.ReapInterval, err = time.ParseDuration(value)
+	case "reap_dry_run":
+		c.ReapDryRun, err = strconv.ParseBool(value)
+	case "remove_runners_on_shutdown":
+		c.RemoveRunnersOnShutdown, err = strconv.ParseBool(value)
+	case "shutdown_timeout":
+		c.ShutdownTimeout, err = time.ParseDuration(value)
+	case "gate_timeout":
+		c.GateTimeout, err = time.ParseDuration(value)
+	case "gate_workers":
+		c.GateWorkers, err = strconv.Atoi(value)
+	case "file_size_limit":
+		c.FileSizeLimit, err = strconv.ParseInt(value, 10, 64)
+	case "llm.provider":
+		c.LLM.Provider = value
+	case "llm.model":
+		c.LLM.Model = value
+	case "llm.api_key":
+		c.LLM.APIKey = value
+	case "encryption_key":
+		c.EncryptionKey, err = base64.StdEncoding.DecodeString(value)
+		if err == nil && value != "" && len(c.EncryptionKey) != 32 {
+			err = fmt.Errorf("must decode to 32 bytes, got %d", len(c.EncryptionKey))
+		}
+	default:
+		if stack, ok := strings.CutPrefix(key, "images."); ok {
+			err = c.setImage(stack, value)
+		} else if rest, ok := strings.CutPrefix(key, "resources."); ok {
+			err = c.setResource(rest, value)
+		} else if stack, ok := strings.CutPrefix(key, "parallelism."); ok {
+			err = c.setParallelism(stack, value)
+		} else {
+			err = errors.New("unknown key")
+		}
+	}
+	if err != nil {
+		return &ValidationError{Key: key, Err: err}
+	}
+	return nil
+}
+
+func (c *Config) setImage(stack, value string) error {
+	switch {
+	case stack == "" || strings.Contains(stack, "."):
+		return errors.New("expected images.<stack>")
+	case value == "":
+		return errors.New("must not be empty")
+	}
+	c.Images[stack] = value
+	return nil
+}
+
+func (c *Config) setResource(key, value string) error {
+	stack, field, ok := strings.Cut(key, ".")
+	if !ok || stack == "" {
+		return errors.New("expected resources.<stack>.<field>")
+	}
+
+	r := c.Resources[stack]
+	var err error
+	switch field {
+	case "memory":
+		_, err = units.RAMInBytes(value)
+		r.Memory = value
+	case "tmpfs":
+		_, err = units.RAMInBytes(value)
+		r.Tmpfs = value
+	case "cpus":
+		r.CPUs, err = strconv.ParseFloat(value, 64)
+		if err == nil && r.CPUs <= 0 {
+			err = errors.New("must be positive")
+		}
+	case "pids":
+		r.Pids, err = strconv.ParseInt(value, 10, 64)
+		if err == nil && r.Pids <= 0 {
+			err = errors.New("must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown resource %q", field)
+	}
+	if err != nil {
+		return err
+	}
+	c.Resources[stack] = r
+	return nil
+}
+
+func (c *Config) setParallelism(stack, value string) error {
+	if stack == "" || strings.Contains(stack, ".") {
+		return errors.New("expected parallelism.<stack>")
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return errors.New("must be positive")
+	}
+	c.Parallelism[stack] = n
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return &ValidationError{Key: "port", Err: fmt.Errorf("%d is out of range 1-65535", c.Port)}
+	}
+	if c.Bind != "localhost" && net.ParseIP(c.Bind) == nil {
+		return &ValidationError{Key: "bind", Err: fmt.Errorf("%q is not an IP address", c.Bind)}
+	}
+	if c.Env == "" {
+		return &ValidationError{Key: "env", Err: errors.New("must not be empty")}
+	}
+	switch c.Storage {
+	case StoragePostgres:
+	case StorageSQLite:
+		if c.SQLitePath == "" {
+			return &ValidationError{Key: "sqlite_path", Err: errors.New("must not be empty")}
+		}
+	default:
+		return &ValidationError{Key: "storage", Err: fmt.Errorf("unsupported backend %q", c.Storage)}
+	}
+	if c.SnapshotDir == "" {
+		return &ValidationError{Key: "snapshot_dir", Err: errors.New("must not be empty")}
+	}
+	switch c.Runtime {
+	case RuntimeDocker, RuntimeKubernetes:
+	default:
+		return &ValidationError{Key: "runtime", Err: fmt.Errorf("unsupported runtime %q", c.Runtime)}
+	}
+	if c.IdleTimeout <= 0 {
+		return &ValidationError{Key: "idle_timeout", Err: errors.New("must be positive")}
+	}
+	if c.MonitorInterval <= 0 {
+		return &ValidationError{Key: "monitor_interval", Err: errors.New("must be positive")}
+	}
+	if c.StoppedRetention <= 0 {
+		return &ValidationError{Key: "stopped_retention", Err: errors.New("must be positive")}
+	}
+	if c.ReapInterval <= 0 {
+		return &ValidationError{Key: "reap_interval", Err: errors.New("must be positive")}
+	}
+	if c.ShutdownTimeout <= 0 {
+		return &ValidationError{Key: "shutdown_timeout", Err: errors.New("must be positive")}
+	}
+	if c.GateTimeout <= 0 {
+		return &ValidationError{Key: "gate_timeout", Err: errors.New("must be positive")}
+	}
+	if c.GateWorkers <= 0 {
+		return &ValidationError{Key: "gate_workers", Err: errors.New("must be positive")}
+	}
+	if c.FileSizeLimit <= 0 {
+		return &ValidationError{Key: "file_size_limit", Err: errors.New("must be positive")}
+	}
+	if c.LLM.Provider != "gemini" {
+		return &ValidationError{Key: "llm.provider", Err: fmt.Errorf("unsupported provider %q", c.LLM.Provider)}
 	}
-	return fallback
+	return nil
 }
@@ -0,0 +1,64 @@
+// Package dbtest provides storage backends for tests that must pass on both
+// Postgres and SQLite.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+type Backend struct {
+	Name    string
+	Querier database.Querier
+}
+
+// Backends returns a migrated SQLite backend, plus Postgres when
+// TEST_DB_URL is set.
+func Backends(t *testing.T) []Backend {
+	backends := []Backend{{Name: "sqlite", Querier: sqlite.New(SQLite(t))}}
+	if pool := PostgresIfConfigured(t); pool != nil {
+		backends = append(backends, Backend{Name: "postgres", Querier: database.New(pool)})
+	}
+	return backends
+}
+
+// SQLite opens a fresh, migrated SQLite database in a temp dir.
+func SQLite(t *testing.T) *sql.DB {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "monarch.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	_, err = sqlite.Migrate(ctx, db)
+	require.NoError(t, err)
+	return db
+}
+
+// PostgresIfConfigured opens the migrated database at TEST_DB_URL, or
+// returns nil when it is not set.
+func PostgresIfConfigured(t *testing.T) *pgxpool.Pool {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		return nil
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		pool.Close()
+	})
+
+	_, err = database.Migrate(ctx, pool)
+	require.NoError(t, err)
+	return pool
+}
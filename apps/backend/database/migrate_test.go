@@ -0,0 +1,136 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_tasks.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"000002_tasks.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000001_init.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"000001_init.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+
+	migrations, err := database.LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "init", migrations[0].Name)
+	assert.Equal(t, "CREATE TABLE a ();", migrations[0].Up)
+	assert.Equal(t, "DROP TABLE a;", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"NoDirection", fstest.MapFS{"000001_init.sql": {}}, "missing .up.sql or .down.sql"},
+		{"NoName", fstest.MapFS{"000001.up.sql": {}}, "expected <version>_<name>"},
+		{"BadVersion", fstest.MapFS{"abc_init.up.sql": {}}, "invalid version"},
+		{"MissingUp", fstest.MapFS{"000001_init.down.sql": {Data: []byte("x")}}, "missing up file"},
+		{"DuplicateVersion", fstest.MapFS{
+			"000001_a.up.sql": {Data: []byte("x")},
+			"000001_b.up.sql": {Data: []byte("y")},
+		}, "used by both"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := database.LoadMigrations(tt.fsys)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestMigrator_UpDown_Integration(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	m, err := database.NewMigrator(pool)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	// Re-running is a no-op.
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, version)
+
+	var hasVector bool
+	err = pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')").Scan(&hasVector)
+	require.NoError(t, err)
+	assert.True(t, hasVector)
+
+	rolledBack, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+}
+
+func TestMigrator_UpgradesPreMigrationSchema_Integration(t *testing.T) {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbURL)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	m, err := database.NewMigrator(pool)
+	require.NoError(t, err)
+	migrations, err := database.LoadMigrations(os.DirFS("migrations"))
+	require.NoError(t, err)
+
+	// Recreate a dev database set up from schema.sql, before migrations.
+	_, err = m.Down(ctx, len(migrations))
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "DROP TABLE schema_migrations")
+	require.NoError(t, err)
+	legacy, err := os.ReadFile("testdata/schema_pre_migrations.sql")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, string(legacy))
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "INSERT INTO projects (path) VALUES ('/src/legacy')")
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, version)
+
+	var path string
+	err = pool.QueryRow(ctx, "SELECT path FROM projects").Scan(&path)
+	require.NoError(t, err)
+	assert.Equal(t, "/src/legacy", path, "existing rows survive the upgrade")
+}
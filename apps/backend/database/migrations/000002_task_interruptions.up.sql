@@ -0,0 +1,3 @@
+-- Set when a validation run was cut short by a Monarch shutdown so the
+-- attempt can be retried after restart without counting against the task.
+ALTER TABLE tasks ADD COLUMN interrupted_at TIMESTAMPTZ;
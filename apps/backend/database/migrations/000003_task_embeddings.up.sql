@@ -0,0 +1,9 @@
+-- Embeddings live beside tasks rather than on them so task rows scan without
+-- a pgvector type mapping.
+CREATE TABLE task_embeddings (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    embedding vector(1536) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX task_embeddings_embedding_idx ON task_embeddings USING hnsw (embedding vector_cosine_ops);
@@ -0,0 +1,8 @@
+-- Task status follows the state machine in the task package.
+UPDATE tasks SET status = 'BACKLOG'
+WHERE status NOT IN ('BACKLOG', 'IN_PROGRESS', 'VALIDATING', 'DONE', 'BLOCKED');
+
+ALTER TABLE tasks ALTER COLUMN status SET DEFAULT 'BACKLOG';
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_status_check
+    CHECK (status IN ('BACKLOG', 'IN_PROGRESS', 'VALIDATING', 'DONE', 'BLOCKED'));
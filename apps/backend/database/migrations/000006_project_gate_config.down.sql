@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE projects DROP COLUMN IF EXISTS gate_config;
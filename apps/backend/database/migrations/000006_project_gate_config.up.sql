@@ -0,0 +1,3 @@
+-- The detected gates.Config, refreshed by a rescan.
+ALTER TABLE projects ADD COLUMN gate_config JSONB;
+ALTER TABLE projects ADD COLUMN scanned_at TIMESTAMPTZ;
@@ -0,0 +1,42 @@
+-- Every submit_attempt that reaches the gates gets an attempt row, with one
+-- gate_results row per gate that ran and the parsed findings beneath it.
+CREATE TABLE attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    number INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'RUNNING'
+        CONSTRAINT attempts_status_check CHECK (status IN ('RUNNING', 'PASSED', 'FAILED', 'INTERRUPTED')),
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX attempts_task_id_idx ON attempts (task_id, started_at);
+
+CREATE TABLE gate_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    attempt_id UUID NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+    gate_name TEXT NOT NULL,
+    status TEXT NOT NULL
+        CONSTRAINT gate_results_status_check CHECK (status IN ('PASS', 'VALIDATION_FAILURE', 'SYSTEM_ERROR')),
+    duration_ms BIGINT NOT NULL,
+    exit_code INT,
+    stdout TEXT NOT NULL DEFAULT '',
+    stderr TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX gate_results_attempt_id_idx ON gate_results (attempt_id, created_at);
+
+CREATE TABLE gate_findings (
+    id BIGSERIAL PRIMARY KEY,
+    gate_result_id UUID NOT NULL REFERENCES gate_results(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    file TEXT NOT NULL DEFAULT '',
+    line INT NOT NULL DEFAULT 0,
+    message TEXT NOT NULL,
+    tool TEXT NOT NULL DEFAULT '',
+    rule_id TEXT NOT NULL DEFAULT '',
+    hint TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX gate_findings_gate_result_id_idx ON gate_findings (gate_result_id);
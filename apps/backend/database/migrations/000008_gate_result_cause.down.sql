@@ -0,0 +1 @@
+ALTER TABLE gate_results DROP COLUMN IF EXISTS cause;
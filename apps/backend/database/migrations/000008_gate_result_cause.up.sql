@@ -0,0 +1,2 @@
+-- The infrastructure error behind a SYSTEM_ERROR gate.
+ALTER TABLE gate_results ADD COLUMN cause TEXT NOT NULL DEFAULT '';
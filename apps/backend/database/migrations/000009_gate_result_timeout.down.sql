@@ -0,0 +1,4 @@
+UPDATE gate_results SET status = 'VALIDATION_FAILURE' WHERE status = 'TIMEOUT';
+ALTER TABLE gate_results DROP CONSTRAINT gate_results_status_check;
+ALTER TABLE gate_results ADD CONSTRAINT gate_results_status_check
+    CHECK (status IN ('PASS', 'VALIDATION_FAILURE', 'SYSTEM_ERROR'));
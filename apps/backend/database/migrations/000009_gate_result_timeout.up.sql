@@ -0,0 +1,5 @@
+-- A gate killed by its timeout is recorded as TIMEOUT, with the output it
+-- produced before it was killed.
+ALTER TABLE gate_results DROP CONSTRAINT gate_results_status_check;
+ALTER TABLE gate_results ADD CONSTRAINT gate_results_status_check
+    CHECK (status IN ('PASS', 'VALIDATION_FAILURE', 'SYSTEM_ERROR', 'TIMEOUT'));
@@ -0,0 +1,14 @@
+-- Gate output is stored line by line while the gate runs, so the dashboard
+-- can replay a live log. Each gate's log is capped by the writer; seq orders
+-- lines within a gate and lets live subscribers skip lines they replayed.
+CREATE TABLE gate_log_lines (
+    id BIGSERIAL PRIMARY KEY,
+    attempt_id UUID NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+    gate_name TEXT NOT NULL,
+    seq BIGINT NOT NULL,
+    stream TEXT NOT NULL CHECK (stream IN ('stdout', 'stderr')),
+    line TEXT NOT NULL,
+    logged_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX gate_log_lines_attempt_id_idx ON gate_log_lines (attempt_id, id);
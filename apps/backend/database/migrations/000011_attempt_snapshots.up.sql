@@ -0,0 +1,5 @@
+-- The workspace snapshot an attempt's gates ran against: the HEAD commit of
+-- a git project, and a hash of the uncommitted changes on top of it (or of
+-- the whole copied tree for projects outside git).
+ALTER TABLE attempts ADD COLUMN snapshot_commit TEXT NOT NULL DEFAULT '';
+ALTER TABLE attempts ADD COLUMN snapshot_diff_hash TEXT NOT NULL DEFAULT '';
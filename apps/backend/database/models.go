@@ -8,10 +8,58 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Attempt struct {
+	ID               pgtype.UUID        `json:"id"`
+	TaskID           pgtype.UUID        `json:"task_id"`
+	Number           int32              `json:"number"`
+	Status           string             `json:"status"`
+	StartedAt        pgtype.Timestamptz `json:"started_at"`
+	FinishedAt       pgtype.Timestamptz `json:"finished_at"`
+	SnapshotCommit   string             `json:"snapshot_commit"`
+	SnapshotDiffHash string             `json:"snapshot_diff_hash"`
+}
+
+type GateFinding struct {
+	ID           int64       `json:"id"`
+	GateResultID pgtype.UUID `json:"gate_result_id"`
+	Severity     string      `json:"severity"`
+	File         string      `json:"file"`
+	Line         int32       `json:"line"`
+	Message      string      `json:"message"`
+	Tool         string      `json:"tool"`
+	RuleID       string      `json:"rule_id"`
+	Hint         string      `json:"hint"`
+}
+
+type GateLogLine struct {
+	ID        int64              `json:"id"`
+	AttemptID pgtype.UUID        `json:"attempt_id"`
+	GateName  string             `json:"gate_name"`
+	Seq       int64              `json:"seq"`
+	Stream    string             `json:"stream"`
+	Line      string             `json:"line"`
+	LoggedAt  pgtype.Timestamptz `json:"logged_at"`
+}
+
+type GateResult struct {
+	ID         pgtype.UUID        `json:"id"`
+	AttemptID  pgtype.UUID        `json:"attempt_id"`
+	GateName   string             `json:"gate_name"`
+	Status     string             `json:"status"`
+	DurationMs int64              `json:"duration_ms"`
+	ExitCode   pgtype.Int4        `json:"exit_code"`
+	Stdout     string             `json:"stdout"`
+	Stderr     string             `json:"stderr"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	Cause      string             `json:"cause"`
+}
+
 type Project struct {
-	ID        pgtype.UUID        `json:"id"`
-	Path      string             `json:"path"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ID         pgtype.UUID        `json:"id"`
+	Path       string             `json:"path"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	GateConfig []byte             `json:"-"`
+	ScannedAt  pgtype.Timestamptz `json:"scanned_at"`
 }
 
 type Setting struct {
@@ -22,10 +70,18 @@ type Setting struct {
 }
 
 type Task struct {
-	ID           pgtype.UUID        `json:"id"`
-	ProjectID    pgtype.UUID        `json:"project_id"`
-	Title        string             `json:"title"`
-	Status       string             `json:"status"`
-	AttemptCount int32              `json:"attempt_count"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	ID            pgtype.UUID        `json:"id"`
+	ProjectID     pgtype.UUID        `json:"project_id"`
+	Title         string             `json:"title"`
+	Status        string             `json:"status"`
+	AttemptCount  int32              `json:"attempt_count"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	InterruptedAt pgtype.Timestamptz `json:"interrupted_at"`
+	Description   string             `json:"description"`
+}
+
+type TaskEmbedding struct {
+	TaskID    pgtype.UUID        `json:"task_id"`
+	Embedding string             `json:"embedding"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
@@ -11,16 +11,41 @@ import (
 )
 
 type Querier interface {
+	CreateAttempt(ctx context.Context, arg CreateAttemptParams) (Attempt, error)
+	CreateGateFinding(ctx context.Context, arg CreateGateFindingParams) error
+	CreateGateLogLine(ctx context.Context, arg CreateGateLogLineParams) error
+	CreateGateResult(ctx context.Context, arg CreateGateResultParams) (GateResult, error)
 	CreateProject(ctx context.Context, path string) (Project, error)
 	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
+	DeleteProject(ctx context.Context, id pgtype.UUID) (int64, error)
+	DeleteTask(ctx context.Context, id pgtype.UUID) (int64, error)
+	FinishAttempt(ctx context.Context, arg FinishAttemptParams) (Attempt, error)
+	GetAttempt(ctx context.Context, id pgtype.UUID) (Attempt, error)
 	GetProject(ctx context.Context, path string) (Project, error)
+	GetProjectByID(ctx context.Context, id pgtype.UUID) (Project, error)
 	GetSetting(ctx context.Context, key string) (Setting, error)
 	GetTask(ctx context.Context, id pgtype.UUID) (Task, error)
 	IncrementTaskAttempt(ctx context.Context, id pgtype.UUID) (int32, error)
+	ListAttemptFindings(ctx context.Context, attemptID pgtype.UUID) ([]GateFinding, error)
+	ListAttempts(ctx context.Context, taskID pgtype.UUID) ([]Attempt, error)
+	ListGateLogLines(ctx context.Context, attemptID pgtype.UUID) ([]GateLogLine, error)
+	ListGateResults(ctx context.Context, attemptID pgtype.UUID) ([]GateResult, error)
+	ListInterruptedTasks(ctx context.Context) ([]Task, error)
 	ListProjects(ctx context.Context) ([]Project, error)
 	ListTasks(ctx context.Context, projectID pgtype.UUID) ([]Task, error)
-	UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) error
+	ListTasksByStatus(ctx context.Context, arg ListTasksByStatusParams) ([]Task, error)
+	MarkTaskInterrupted(ctx context.Context, id pgtype.UUID) error
+	// Gives back an attempt that failed for reasons outside the agent's control.
+	RefundTaskAttempt(ctx context.Context, id pgtype.UUID) error
+	SearchTasksByEmbedding(ctx context.Context, arg SearchTasksByEmbeddingParams) ([]SearchTasksByEmbeddingRow, error)
+	SetAttemptSnapshot(ctx context.Context, arg SetAttemptSnapshotParams) (Attempt, error)
+	// Compare-and-set: only updates if the task is still in from_status.
+	TransitionTaskStatus(ctx context.Context, arg TransitionTaskStatusParams) (Task, error)
+	UpdateProjectGateConfig(ctx context.Context, arg UpdateProjectGateConfigParams) (Project, error)
+	// NULL arguments leave the column unchanged.
+	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
 	UpsertSetting(ctx context.Context, arg UpsertSettingParams) error
+	UpsertTaskEmbedding(ctx context.Context, arg UpsertTaskEmbeddingParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,402 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The Querier tests run against every backend so SQLite stays a drop-in
+// replacement for Postgres.
+
+func TestQuerier_ProjectsAndTasks(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+			path := t.TempDir()
+
+			proj, err := q.CreateProject(ctx, path)
+			require.NoError(t, err)
+			assert.True(t, proj.ID.Valid)
+			assert.True(t, proj.CreatedAt.Valid)
+
+			byPath, err := q.GetProject(ctx, path)
+			require.NoError(t, err)
+			assert.Equal(t, proj.ID, byPath.ID)
+
+			byID, err := q.GetProjectByID(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Equal(t, path, byID.Path)
+
+			projects, err := q.ListProjects(ctx)
+			require.NoError(t, err)
+			assert.NotEmpty(t, projects)
+
+			task, err := q.CreateTask(ctx, database.CreateTaskParams{
+				ProjectID: proj.ID,
+				Title:     "Add login",
+				Status:    "BACKLOG",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, proj.ID, task.ProjectID)
+
+			claimed, err := q.TransitionTaskStatus(ctx, database.TransitionTaskStatusParams{
+				ID: task.ID, FromStatus: "BACKLOG", ToStatus: "IN_PROGRESS",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "IN_PROGRESS", claimed.Status)
+
+			// The compare-and-set fails once the task has left BACKLOG.
+			_, err = q.TransitionTaskStatus(ctx, database.TransitionTaskStatusParams{
+				ID: task.ID, FromStatus: "BACKLOG", ToStatus: "IN_PROGRESS",
+			})
+			assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+			count, err := q.IncrementTaskAttempt(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int32(1), count)
+
+			require.NoError(t, q.MarkTaskInterrupted(ctx, task.ID))
+
+			got, err := q.GetTask(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "IN_PROGRESS", got.Status)
+			assert.Equal(t, int32(0), got.AttemptCount, "interruption refunds the attempt")
+			assert.True(t, got.InterruptedAt.Valid)
+
+			interrupted, err := q.ListInterruptedTasks(ctx)
+			require.NoError(t, err)
+			assert.Contains(t, taskIDs(interrupted), task.ID)
+
+			count, err = q.IncrementTaskAttempt(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int32(1), count)
+			require.NoError(t, q.RefundTaskAttempt(ctx, task.ID))
+			require.NoError(t, q.RefundTaskAttempt(ctx, task.ID))
+			got, err = q.GetTask(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int32(0), got.AttemptCount, "refunds never go negative")
+
+			tasks, err := q.ListTasks(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Len(t, tasks, 1)
+		})
+	}
+}
+
+func TestQuerier_ProjectGateConfigAndDelete(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+			assert.Nil(t, proj.GateConfig)
+			assert.False(t, proj.ScannedAt.Valid)
+
+			scanned, err := q.UpdateProjectGateConfig(ctx, database.UpdateProjectGateConfigParams{
+				ID: proj.ID, GateConfig: []byte(`{"stack":"go"}`),
+			})
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"stack":"go"}`, string(scanned.GateConfig))
+			assert.True(t, scanned.ScannedAt.Valid)
+
+			task, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: "BACKLOG"})
+			require.NoError(t, err)
+
+			n, err := q.DeleteProject(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			_, err = q.GetTask(ctx, task.ID)
+			assert.ErrorIs(t, err, pgx.ErrNoRows, "tasks are deleted with their project")
+
+			n, err = q.DeleteProject(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Zero(t, n)
+		})
+	}
+}
+
+func TestQuerier_TaskEditing(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+
+			backlog, err := q.CreateTask(ctx, database.CreateTaskParams{
+				ProjectID: proj.ID, Title: "one", Description: "first", Status: "BACKLOG",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "first", backlog.Description)
+			_, err = q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "two", Status: "DONE"})
+			require.NoError(t, err)
+
+			filtered, err := q.ListTasksByStatus(ctx, database.ListTasksByStatusParams{
+				ProjectID: proj.ID, Statuses: []string{"BACKLOG", "BLOCKED"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []pgtype.UUID{backlog.ID}, taskIDs(filtered))
+
+			updated, err := q.UpdateTask(ctx, database.UpdateTaskParams{
+				ID: backlog.ID, Title: pgtype.Text{String: "renamed", Valid: true},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "renamed", updated.Title)
+			assert.Equal(t, "first", updated.Description, "NULL leaves the column unchanged")
+
+			n, err := q.DeleteTask(ctx, backlog.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			n, err = q.DeleteTask(ctx, backlog.ID)
+			require.NoError(t, err)
+			assert.Zero(t, n)
+		})
+	}
+}
+
+func TestQuerier_AttemptHistory(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+			task, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: "BACKLOG"})
+			require.NoError(t, err)
+
+			attempt, err := q.CreateAttempt(ctx, database.CreateAttemptParams{TaskID: task.ID, Number: 1})
+			require.NoError(t, err)
+			assert.Equal(t, "RUNNING", attempt.Status)
+			assert.False(t, attempt.FinishedAt.Valid)
+			assert.Empty(t, attempt.SnapshotCommit)
+
+			snap, err := q.SetAttemptSnapshot(ctx, database.SetAttemptSnapshotParams{
+				ID: attempt.ID, SnapshotCommit: "abc123", SnapshotDiffHash: "d1ff",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "abc123", snap.SnapshotCommit)
+			assert.Equal(t, "d1ff", snap.SnapshotDiffHash)
+
+			lint, err := q.CreateGateResult(ctx, database.CreateGateResultParams{
+				AttemptID:  attempt.ID,
+				GateName:   "lint",
+				Status:     "VALIDATION_FAILURE",
+				DurationMs: 1200,
+				ExitCode:   pgtype.Int4{Int32: 1, Valid: true},
+				Stderr:     "1 problem",
+			})
+			require.NoError(t, err)
+			_, err = q.CreateGateResult(ctx, database.CreateGateResultParams{
+				AttemptID: attempt.ID, GateName: "docker", Status: "SYSTEM_ERROR", Cause: "daemon unreachable",
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, q.CreateGateFinding(ctx, database.CreateGateFindingParams{
+				GateResultID: lint.ID, Severity: "ERROR", File: "app.ts", Line: 10,
+				Message: "Unexpected console", Tool: "eslint", RuleID: "no-console",
+			}))
+
+			finished, err := q.FinishAttempt(ctx, database.FinishAttemptParams{ID: attempt.ID, Status: "FAILED"})
+			require.NoError(t, err)
+			assert.True(t, finished.FinishedAt.Valid)
+
+			attempts, err := q.ListAttempts(ctx, task.ID)
+			require.NoError(t, err)
+			require.Len(t, attempts, 1)
+			assert.Equal(t, "FAILED", attempts[0].Status)
+			assert.Equal(t, "d1ff", attempts[0].SnapshotDiffHash)
+
+			results, err := q.ListGateResults(ctx, attempt.ID)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			assert.Equal(t, "lint", results[0].GateName)
+			assert.Equal(t, int32(1), results[0].ExitCode.Int32)
+			assert.False(t, results[1].ExitCode.Valid)
+			assert.Equal(t, "daemon unreachable", results[1].Cause)
+
+			findings, err := q.ListAttemptFindings(ctx, attempt.ID)
+			require.NoError(t, err)
+			require.Len(t, findings, 1)
+			assert.Equal(t, lint.ID, findings[0].GateResultID)
+			assert.Equal(t, "no-console", findings[0].RuleID)
+
+			_, err = q.CreateGateResult(ctx, database.CreateGateResultParams{
+				AttemptID: attempt.ID, GateName: "slow", Status: "TIMEOUT", Stdout: "partial",
+			})
+			require.NoError(t, err)
+
+			_, err = q.CreateGateResult(ctx, database.CreateGateResultParams{
+				AttemptID: attempt.ID, GateName: "bad", Status: "FLAKY",
+			})
+			assert.ErrorContains(t, err, "gate_results_status_check")
+		})
+	}
+}
+
+func TestQuerier_GateLogLines(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+			task, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: "BACKLOG"})
+			require.NoError(t, err)
+			attempt, err := q.CreateAttempt(ctx, database.CreateAttemptParams{TaskID: task.ID, Number: 1})
+			require.NoError(t, err)
+
+			at := time.Date(2025, 1, 2, 3, 4, 5, 678000000, time.UTC)
+			for i, line := range []struct{ stream, text string }{{"stdout", "=== RUN TestA"}, {"stderr", "panic: boom"}} {
+				require.NoError(t, q.CreateGateLogLine(ctx, database.CreateGateLogLineParams{
+					AttemptID: attempt.ID,
+					GateName:  "test",
+					Seq:       int64(i + 1),
+					Stream:    line.stream,
+					Line:      line.text,
+					LoggedAt:  pgtype.Timestamptz{Time: at, Valid: true},
+				}))
+			}
+
+			lines, err := q.ListGateLogLines(ctx, attempt.ID)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			assert.Equal(t, "test", lines[0].GateName)
+			assert.Equal(t, int64(1), lines[0].Seq)
+			assert.Equal(t, "=== RUN TestA", lines[0].Line)
+			assert.Equal(t, "stderr", lines[1].Stream)
+			assert.True(t, lines[1].LoggedAt.Time.Equal(at))
+			assert.Equal(t, attempt.ID, lines[1].AttemptID)
+
+			err = q.CreateGateLogLine(ctx, database.CreateGateLogLineParams{
+				AttemptID: attempt.ID, GateName: "test", Seq: 3, Stream: "stdin", Line: "x",
+				LoggedAt: pgtype.Timestamptz{Time: at, Valid: true},
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQuerier_NotFoundIsPgxErrNoRows(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			_, err := b.Querier.GetTask(context.Background(), pgtype.UUID{Bytes: [16]byte{0xde, 0xad}, Valid: true})
+			assert.ErrorIs(t, err, pgx.ErrNoRows)
+		})
+	}
+}
+
+func TestQuerier_TaskStatusConstraint(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+
+			_, err = q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "bad", Status: "WHATEVER"})
+			assert.ErrorContains(t, err, "tasks_status_check")
+
+			task, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "ok", Status: "BACKLOG"})
+			require.NoError(t, err)
+
+			_, err = q.TransitionTaskStatus(ctx, database.TransitionTaskStatusParams{
+				ID: task.ID, FromStatus: "BACKLOG", ToStatus: "WHATEVER",
+			})
+			assert.ErrorContains(t, err, "tasks_status_check")
+		})
+	}
+}
+
+func TestQuerier_Settings(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			for _, value := range []string{"first", "second"} {
+				err := q.UpsertSetting(ctx, database.UpsertSettingParams{
+					Key:         "querier-test",
+					Value:       []byte(value),
+					IsEncrypted: pgtype.Bool{Bool: true, Valid: true},
+				})
+				require.NoError(t, err)
+			}
+
+			s, err := q.GetSetting(ctx, "querier-test")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("second"), s.Value)
+			assert.True(t, s.IsEncrypted.Bool)
+			assert.True(t, s.UpdatedAt.Valid)
+		})
+	}
+}
+
+func TestQuerier_SearchTasksByEmbedding(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			q := b.Querier
+
+			proj, err := q.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+
+			near, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "near", Status: "DONE"})
+			require.NoError(t, err)
+			far, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "far", Status: "DONE"})
+			require.NoError(t, err)
+
+			require.NoError(t, q.UpsertTaskEmbedding(ctx, database.UpsertTaskEmbeddingParams{TaskID: near.ID, Embedding: embedding(1, 0.1)}))
+			require.NoError(t, q.UpsertTaskEmbedding(ctx, database.UpsertTaskEmbeddingParams{TaskID: far.ID, Embedding: embedding(0, 1)}))
+
+			rows, err := q.SearchTasksByEmbedding(ctx, database.SearchTasksByEmbeddingParams{
+				Query:      embedding(1, 0),
+				MaxResults: 100,
+			})
+			require.NoError(t, err)
+
+			var ours []database.SearchTasksByEmbeddingRow
+			for _, r := range rows {
+				if r.Task.ProjectID == proj.ID {
+					ours = append(ours, r)
+				}
+			}
+			require.Len(t, ours, 2)
+			assert.Equal(t, "near", ours[0].Task.Title)
+			assert.Equal(t, "far", ours[1].Task.Title)
+			assert.InDelta(t, 1.0, ours[1].Distance, 1e-6)
+		})
+	}
+}
+
+// embedding builds a 1536-dim vector (the pgvector column size) with the
+// first two components set.
+func embedding(x, y float32) string {
+	v := make([]float32, 1536)
+	v[0], v[1] = x, y
+	return database.FormatVector(v)
+}
+
+func taskIDs(tasks []database.Task) []pgtype.UUID {
+	ids := make([]pgtype.UUID, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
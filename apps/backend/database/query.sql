@@ -4,11 +4,38 @@ INSERT INTO projects (path) VALUES ($1) RETURNING *;
 -- name: GetProject :one
 SELECT * FROM projects WHERE path = $1 LIMIT 1;
 
+-- name: GetProjectByID :one
+SELECT * FROM projects WHERE id = $1 LIMIT 1;
+
+-- name: UpdateProjectGateConfig :one
+UPDATE projects SET gate_config = sqlc.arg(gate_config), scanned_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteProject :execrows
+DELETE FROM projects WHERE id = $1;
+
 -- name: CreateTask :one
-INSERT INTO tasks (project_id, title, status) VALUES ($1, $2, $3) RETURNING *;
+INSERT INTO tasks (project_id, title, description, status) VALUES ($1, $2, $3, $4) RETURNING *;
 
 -- name: ListTasks :many
-SELECT * FROM tasks WHERE project_id = $1;
+SELECT * FROM tasks WHERE project_id = $1 ORDER BY created_at;
+
+-- name: ListTasksByStatus :many
+SELECT * FROM tasks
+WHERE project_id = sqlc.arg(project_id) AND status = ANY(sqlc.arg(statuses)::text[])
+ORDER BY created_at;
+
+-- name: UpdateTask :one
+-- NULL arguments leave the column unchanged.
+UPDATE tasks
+SET title = COALESCE(sqlc.narg(title), title),
+    description = COALESCE(sqlc.narg(description), description)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteTask :execrows
+DELETE FROM tasks WHERE id = $1;
 
 -- name: ListProjects :many
 SELECT * FROM projects ORDER BY created_at DESC;
@@ -16,11 +43,24 @@ SELECT * FROM projects ORDER BY created_at DESC;
 -- name: GetTask :one
 SELECT * FROM tasks WHERE id = $1 LIMIT 1;
 
--- name: UpdateTaskStatus :exec
-UPDATE tasks SET status = $2 WHERE id = $1;
+-- name: TransitionTaskStatus :one
+-- Compare-and-set: only updates if the task is still in from_status.
+UPDATE tasks SET status = sqlc.arg(to_status)
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status)
+RETURNING *;
 
 -- name: IncrementTaskAttempt :one
-UPDATE tasks SET attempt_count = attempt_count + 1 WHERE id = $1 RETURNING attempt_count;
+UPDATE tasks SET attempt_count = attempt_count + 1, interrupted_at = NULL WHERE id = $1 RETURNING attempt_count;
+
+-- name: MarkTaskInterrupted :exec
+UPDATE tasks SET interrupted_at = NOW(), attempt_count = GREATEST(attempt_count - 1, 0) WHERE id = $1;
+
+-- name: RefundTaskAttempt :exec
+-- Gives back an attempt that failed for reasons outside the agent's control.
+UPDATE tasks SET attempt_count = GREATEST(attempt_count - 1, 0) WHERE id = $1;
+
+-- name: ListInterruptedTasks :many
+SELECT * FROM tasks WHERE interrupted_at IS NOT NULL ORDER BY interrupted_at;
 
 -- name: UpsertSetting :exec
 INSERT INTO settings (key, value, is_encrypted, updated_at)
@@ -29,4 +69,56 @@ ON CONFLICT (key) DO UPDATE
 SET value = EXCLUDED.value, is_encrypted = EXCLUDED.is_encrypted, updated_at = NOW();
 
 -- name: GetSetting :one
-SELECT * FROM settings WHERE key = $1;
\ No newline at end of file
+SELECT * FROM settings WHERE key = $1;
+-- name: UpsertTaskEmbedding :exec
+INSERT INTO task_embeddings (task_id, embedding, updated_at)
+VALUES (sqlc.arg(task_id), CAST(sqlc.arg(embedding)::text AS vector), NOW())
+ON CONFLICT (task_id) DO UPDATE
+SET embedding = EXCLUDED.embedding, updated_at = NOW();
+
+-- name: SearchTasksByEmbedding :many
+SELECT sqlc.embed(tasks), (task_embeddings.embedding <=> CAST(sqlc.arg(query)::text AS vector))::float8 AS distance
+FROM task_embeddings
+JOIN tasks ON tasks.id = task_embeddings.task_id
+ORDER BY distance
+LIMIT sqlc.arg(max_results);
+
+-- name: CreateAttempt :one
+INSERT INTO attempts (task_id, number) VALUES ($1, $2) RETURNING *;
+
+-- name: FinishAttempt :one
+UPDATE attempts SET status = $2, finished_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: SetAttemptSnapshot :one
+UPDATE attempts SET snapshot_commit = $2, snapshot_diff_hash = $3 WHERE id = $1 RETURNING *;
+
+-- name: GetAttempt :one
+SELECT * FROM attempts WHERE id = $1 LIMIT 1;
+
+-- name: ListAttempts :many
+SELECT * FROM attempts WHERE task_id = $1 ORDER BY started_at, number;
+
+-- name: CreateGateResult :one
+INSERT INTO gate_results (attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, cause)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListGateResults :many
+SELECT * FROM gate_results WHERE attempt_id = $1 ORDER BY created_at;
+
+-- name: CreateGateFinding :exec
+INSERT INTO gate_findings (gate_result_id, severity, file, line, message, tool, rule_id, hint)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListAttemptFindings :many
+SELECT gate_findings.* FROM gate_findings
+JOIN gate_results ON gate_results.id = gate_findings.gate_result_id
+WHERE gate_results.attempt_id = $1
+ORDER BY gate_findings.id;
+
+-- name: CreateGateLogLine :exec
+INSERT INTO gate_log_lines (attempt_id, gate_name, seq, stream, line, logged_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListGateLogLines :many
+SELECT * FROM gate_log_lines WHERE attempt_id = $1 ORDER BY id;
@@ -11,29 +11,166 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createAttempt = `-- name: CreateAttempt :one
+INSERT INTO attempts (task_id, number) VALUES ($1, $2) RETURNING id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash
+`
+
+type CreateAttemptParams struct {
+	TaskID pgtype.UUID `json:"task_id"`
+	Number int32       `json:"number"`
+}
+
+func (q *Queries) CreateAttempt(ctx context.Context, arg CreateAttemptParams) (Attempt, error) {
+	row := q.db.QueryRow(ctx, createAttempt, arg.TaskID, arg.Number)
+	var i Attempt
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Number,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.SnapshotCommit,
+		&i.SnapshotDiffHash,
+	)
+	return i, err
+}
+
+const createGateFinding = `-- name: CreateGateFinding :exec
+INSERT INTO gate_findings (gate_result_id, severity, file, line, message, tool, rule_id, hint)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type CreateGateFindingParams struct {
+	GateResultID pgtype.UUID `json:"gate_result_id"`
+	Severity     string      `json:"severity"`
+	File         string      `json:"file"`
+	Line         int32       `json:"line"`
+	Message      string      `json:"message"`
+	Tool         string      `json:"tool"`
+	RuleID       string      `json:"rule_id"`
+	Hint         string      `json:"hint"`
+}
+
+func (q *Queries) CreateGateFinding(ctx context.Context, arg CreateGateFindingParams) error {
+	_, err := q.db.Exec(ctx, createGateFinding,
+		arg.GateResultID,
+		arg.Severity,
+		arg.File,
+		arg.Line,
+		arg.Message,
+		arg.Tool,
+		arg.RuleID,
+		arg.Hint,
+	)
+	return err
+}
+
+const createGateLogLine = `-- name: CreateGateLogLine :exec
+INSERT INTO gate_log_lines (attempt_id, gate_name, seq, stream, line, logged_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateGateLogLineParams struct {
+	AttemptID pgtype.UUID        `json:"attempt_id"`
+	GateName  string             `json:"gate_name"`
+	Seq       int64              `json:"seq"`
+	Stream    string             `json:"stream"`
+	Line      string             `json:"line"`
+	LoggedAt  pgtype.Timestamptz `json:"logged_at"`
+}
+
+func (q *Queries) CreateGateLogLine(ctx context.Context, arg CreateGateLogLineParams) error {
+	_, err := q.db.Exec(ctx, createGateLogLine,
+		arg.AttemptID,
+		arg.GateName,
+		arg.Seq,
+		arg.Stream,
+		arg.Line,
+		arg.LoggedAt,
+	)
+	return err
+}
+
+const createGateResult = `-- name: CreateGateResult :one
+INSERT INTO gate_results (attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, cause)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, created_at, cause
+`
+
+type CreateGateResultParams struct {
+	AttemptID  pgtype.UUID `json:"attempt_id"`
+	GateName   string      `json:"gate_name"`
+	Status     string      `json:"status"`
+	DurationMs int64       `json:"duration_ms"`
+	ExitCode   pgtype.Int4 `json:"exit_code"`
+	Stdout     string      `json:"stdout"`
+	Stderr     string      `json:"stderr"`
+	Cause      string      `json:"cause"`
+}
+
+func (q *Queries) CreateGateResult(ctx context.Context, arg CreateGateResultParams) (GateResult, error) {
+	row := q.db.QueryRow(ctx, createGateResult,
+		arg.AttemptID,
+		arg.GateName,
+		arg.Status,
+		arg.DurationMs,
+		arg.ExitCode,
+		arg.Stdout,
+		arg.Stderr,
+		arg.Cause,
+	)
+	var i GateResult
+	err := row.Scan(
+		&i.ID,
+		&i.AttemptID,
+		&i.GateName,
+		&i.Status,
+		&i.DurationMs,
+		&i.ExitCode,
+		&i.Stdout,
+		&i.Stderr,
+		&i.CreatedAt,
+		&i.Cause,
+	)
+	return i, err
+}
+
 const createProject = `-- name: CreateProject :one
-INSERT INTO projects (path) VALUES ($1) RETURNING id, path, created_at
+INSERT INTO projects (path) VALUES ($1) RETURNING id, path, created_at, gate_config, scanned_at
 `
 
 func (q *Queries) CreateProject(ctx context.Context, path string) (Project, error) {
 	row := q.db.QueryRow(ctx, createProject, path)
 	var i Project
-	err := row.Scan(&i.ID, &i.Path, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Path,
+		&i.CreatedAt,
+		&i.GateConfig,
+		&i.ScannedAt,
+	)
 	return i, err
 }
 
 const createTask = `-- name: CreateTask :one
-INSERT INTO tasks (project_id, title, status) VALUES ($1, $2, $3) RETURNING id, project_id, title, status, attempt_count, created_at
+INSERT INTO tasks (project_id, title, description, status) VALUES ($1, $2, $3, $4) RETURNING id, project_id, title, status, attempt_count, created_at, interrupted_at, description
 `
 
 type CreateTaskParams struct {
-	ProjectID pgtype.UUID `json:"project_id"`
-	Title     string      `json:"title"`
-	Status    string      `json:"status"`
+	ProjectID   pgtype.UUID `json:"project_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Status      string      `json:"status"`
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
-	row := q.db.QueryRow(ctx, createTask, arg.ProjectID, arg.Title, arg.Status)
+	row := q.db.QueryRow(ctx, createTask,
+		arg.ProjectID,
+		arg.Title,
+		arg.Description,
+		arg.Status,
+	)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -42,18 +179,112 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.Status,
 		&i.AttemptCount,
 		&i.CreatedAt,
+		&i.InterruptedAt,
+		&i.Description,
+	)
+	return i, err
+}
+
+const deleteProject = `-- name: DeleteProject :execrows
+DELETE FROM projects WHERE id = $1
+`
+
+func (q *Queries) DeleteProject(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProject, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTask = `-- name: DeleteTask :execrows
+DELETE FROM tasks WHERE id = $1
+`
+
+func (q *Queries) DeleteTask(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTask, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const finishAttempt = `-- name: FinishAttempt :one
+UPDATE attempts SET status = $2, finished_at = NOW() WHERE id = $1 RETURNING id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash
+`
+
+type FinishAttemptParams struct {
+	ID     pgtype.UUID `json:"id"`
+	Status string      `json:"status"`
+}
+
+func (q *Queries) FinishAttempt(ctx context.Context, arg FinishAttemptParams) (Attempt, error) {
+	row := q.db.QueryRow(ctx, finishAttempt, arg.ID, arg.Status)
+	var i Attempt
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Number,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.SnapshotCommit,
+		&i.SnapshotDiffHash,
+	)
+	return i, err
+}
+
+const getAttempt = `-- name: GetAttempt :one
+SELECT id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash FROM attempts WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAttempt(ctx context.Context, id pgtype.UUID) (Attempt, error) {
+	row := q.db.QueryRow(ctx, getAttempt, id)
+	var i Attempt
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Number,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.SnapshotCommit,
+		&i.SnapshotDiffHash,
 	)
 	return i, err
 }
 
 const getProject = `-- name: GetProject :one
-SELECT id, path, created_at FROM projects WHERE path = $1 LIMIT 1
+SELECT id, path, created_at, gate_config, scanned_at FROM projects WHERE path = $1 LIMIT 1
 `
 
 func (q *Queries) GetProject(ctx context.Context, path string) (Project, error) {
 	row := q.db.QueryRow(ctx, getProject, path)
 	var i Project
-	err := row.Scan(&i.ID, &i.Path, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Path,
+		&i.CreatedAt,
+		&i.GateConfig,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const getProjectByID = `-- name: GetProjectByID :one
+SELECT id, path, created_at, gate_config, scanned_at FROM projects WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetProjectByID(ctx context.Context, id pgtype.UUID) (Project, error) {
+	row := q.db.QueryRow(ctx, getProjectByID, id)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Path,
+		&i.CreatedAt,
+		&i.GateConfig,
+		&i.ScannedAt,
+	)
 	return i, err
 }
 
@@ -74,7 +305,7 @@ func (q *Queries) GetSetting(ctx context.Context, key string) (Setting, error) {
 }
 
 const getTask = `-- name: GetTask :one
-SELECT id, project_id, title, status, attempt_count, created_at FROM tasks WHERE id = $1 LIMIT 1
+SELECT id, project_id, title, status, attempt_count, created_at, interrupted_at, description FROM tasks WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetTask(ctx context.Context, id pgtype.UUID) (Task, error) {
@@ -87,12 +318,14 @@ func (q *Queries) GetTask(ctx context.Context, id pgtype.UUID) (Task, error) {
 		&i.Status,
 		&i.AttemptCount,
 		&i.CreatedAt,
+		&i.InterruptedAt,
+		&i.Description,
 	)
 	return i, err
 }
 
 const incrementTaskAttempt = `-- name: IncrementTaskAttempt :one
-UPDATE tasks SET attempt_count = attempt_count + 1 WHERE id = $1 RETURNING attempt_count
+UPDATE tasks SET attempt_count = attempt_count + 1, interrupted_at = NULL WHERE id = $1 RETURNING attempt_count
 `
 
 func (q *Queries) IncrementTaskAttempt(ctx context.Context, id pgtype.UUID) (int32, error) {
@@ -102,8 +335,178 @@ func (q *Queries) IncrementTaskAttempt(ctx context.Context, id pgtype.UUID) (int
 	return attempt_count, err
 }
 
+const listAttemptFindings = `-- name: ListAttemptFindings :many
+SELECT gate_findings.id, gate_findings.gate_result_id, gate_findings.severity, gate_findings.file, gate_findings.line, gate_findings.message, gate_findings.tool, gate_findings.rule_id, gate_findings.hint FROM gate_findings
+JOIN gate_results ON gate_results.id = gate_findings.gate_result_id
+WHERE gate_results.attempt_id = $1
+ORDER BY gate_findings.id
+`
+
+func (q *Queries) ListAttemptFindings(ctx context.Context, attemptID pgtype.UUID) ([]GateFinding, error) {
+	rows, err := q.db.Query(ctx, listAttemptFindings, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GateFinding
+	for rows.Next() {
+		var i GateFinding
+		if err := rows.Scan(
+			&i.ID,
+			&i.GateResultID,
+			&i.Severity,
+			&i.File,
+			&i.Line,
+			&i.Message,
+			&i.Tool,
+			&i.RuleID,
+			&i.Hint,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttempts = `-- name: ListAttempts :many
+SELECT id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash FROM attempts WHERE task_id = $1 ORDER BY started_at, number
+`
+
+func (q *Queries) ListAttempts(ctx context.Context, taskID pgtype.UUID) ([]Attempt, error) {
+	rows, err := q.db.Query(ctx, listAttempts, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attempt
+	for rows.Next() {
+		var i Attempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.Number,
+			&i.Status,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.SnapshotCommit,
+			&i.SnapshotDiffHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGateLogLines = `-- name: ListGateLogLines :many
+SELECT id, attempt_id, gate_name, seq, stream, line, logged_at FROM gate_log_lines WHERE attempt_id = $1 ORDER BY id
+`
+
+func (q *Queries) ListGateLogLines(ctx context.Context, attemptID pgtype.UUID) ([]GateLogLine, error) {
+	rows, err := q.db.Query(ctx, listGateLogLines, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GateLogLine
+	for rows.Next() {
+		var i GateLogLine
+		if err := rows.Scan(
+			&i.ID,
+			&i.AttemptID,
+			&i.GateName,
+			&i.Seq,
+			&i.Stream,
+			&i.Line,
+			&i.LoggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGateResults = `-- name: ListGateResults :many
+SELECT id, attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, created_at, cause FROM gate_results WHERE attempt_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) ListGateResults(ctx context.Context, attemptID pgtype.UUID) ([]GateResult, error) {
+	rows, err := q.db.Query(ctx, listGateResults, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GateResult
+	for rows.Next() {
+		var i GateResult
+		if err := rows.Scan(
+			&i.ID,
+			&i.AttemptID,
+			&i.GateName,
+			&i.Status,
+			&i.DurationMs,
+			&i.ExitCode,
+			&i.Stdout,
+			&i.Stderr,
+			&i.CreatedAt,
+			&i.Cause,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterruptedTasks = `-- name: ListInterruptedTasks :many
+SELECT id, project_id, title, status, attempt_count, created_at, interrupted_at, description FROM tasks WHERE interrupted_at IS NOT NULL ORDER BY interrupted_at
+`
+
+func (q *Queries) ListInterruptedTasks(ctx context.Context) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listInterruptedTasks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Status,
+			&i.AttemptCount,
+			&i.CreatedAt,
+			&i.InterruptedAt,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProjects = `-- name: ListProjects :many
-SELECT id, path, created_at FROM projects ORDER BY created_at DESC
+SELECT id, path, created_at, gate_config, scanned_at FROM projects ORDER BY created_at DESC
 `
 
 func (q *Queries) ListProjects(ctx context.Context) ([]Project, error) {
@@ -115,7 +518,13 @@ func (q *Queries) ListProjects(ctx context.Context) ([]Project, error) {
 	var items []Project
 	for rows.Next() {
 		var i Project
-		if err := rows.Scan(&i.ID, &i.Path, &i.CreatedAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Path,
+			&i.CreatedAt,
+			&i.GateConfig,
+			&i.ScannedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -127,7 +536,7 @@ func (q *Queries) ListProjects(ctx context.Context) ([]Project, error) {
 }
 
 const listTasks = `-- name: ListTasks :many
-SELECT id, project_id, title, status, attempt_count, created_at FROM tasks WHERE project_id = $1
+SELECT id, project_id, title, status, attempt_count, created_at, interrupted_at, description FROM tasks WHERE project_id = $1 ORDER BY created_at
 `
 
 func (q *Queries) ListTasks(ctx context.Context, projectID pgtype.UUID) ([]Task, error) {
@@ -146,6 +555,8 @@ func (q *Queries) ListTasks(ctx context.Context, projectID pgtype.UUID) ([]Task,
 			&i.Status,
 			&i.AttemptCount,
 			&i.CreatedAt,
+			&i.InterruptedAt,
+			&i.Description,
 		); err != nil {
 			return nil, err
 		}
@@ -157,20 +568,223 @@ func (q *Queries) ListTasks(ctx context.Context, projectID pgtype.UUID) ([]Task,
 	return items, nil
 }
 
-const updateTaskStatus = `-- name: UpdateTaskStatus :exec
-UPDATE tasks SET status = $2 WHERE id = $1
+const listTasksByStatus = `-- name: ListTasksByStatus :many
+SELECT id, project_id, title, status, attempt_count, created_at, interrupted_at, description FROM tasks
+WHERE project_id = $1 AND status = ANY($2::text[])
+ORDER BY created_at
 `
 
-type UpdateTaskStatusParams struct {
-	ID     pgtype.UUID `json:"id"`
-	Status string      `json:"status"`
+type ListTasksByStatusParams struct {
+	ProjectID pgtype.UUID `json:"project_id"`
+	Statuses  []string    `json:"statuses"`
 }
 
-func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) error {
-	_, err := q.db.Exec(ctx, updateTaskStatus, arg.ID, arg.Status)
+func (q *Queries) ListTasksByStatus(ctx context.Context, arg ListTasksByStatusParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listTasksByStatus, arg.ProjectID, arg.Statuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Status,
+			&i.AttemptCount,
+			&i.CreatedAt,
+			&i.InterruptedAt,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markTaskInterrupted = `-- name: MarkTaskInterrupted :exec
+UPDATE tasks SET interrupted_at = NOW(), attempt_count = GREATEST(attempt_count - 1, 0) WHERE id = $1
+`
+
+func (q *Queries) MarkTaskInterrupted(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markTaskInterrupted, id)
+	return err
+}
+
+const refundTaskAttempt = `-- name: RefundTaskAttempt :exec
+UPDATE tasks SET attempt_count = GREATEST(attempt_count - 1, 0) WHERE id = $1
+`
+
+// Gives back an attempt that failed for reasons outside the agent's control.
+func (q *Queries) RefundTaskAttempt(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, refundTaskAttempt, id)
 	return err
 }
 
+const searchTasksByEmbedding = `-- name: SearchTasksByEmbedding :many
+SELECT tasks.id, tasks.project_id, tasks.title, tasks.status, tasks.attempt_count, tasks.created_at, tasks.interrupted_at, tasks.description, (task_embeddings.embedding <=> CAST($1::text AS vector))::float8 AS distance
+FROM task_embeddings
+JOIN tasks ON tasks.id = task_embeddings.task_id
+ORDER BY distance
+LIMIT $2
+`
+
+type SearchTasksByEmbeddingParams struct {
+	Query      string `json:"query"`
+	MaxResults int32  `json:"max_results"`
+}
+
+type SearchTasksByEmbeddingRow struct {
+	Task     Task    `json:"task"`
+	Distance float64 `json:"distance"`
+}
+
+func (q *Queries) SearchTasksByEmbedding(ctx context.Context, arg SearchTasksByEmbeddingParams) ([]SearchTasksByEmbeddingRow, error) {
+	rows, err := q.db.Query(ctx, searchTasksByEmbedding, arg.Query, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchTasksByEmbeddingRow
+	for rows.Next() {
+		var i SearchTasksByEmbeddingRow
+		if err := rows.Scan(
+			&i.Task.ID,
+			&i.Task.ProjectID,
+			&i.Task.Title,
+			&i.Task.Status,
+			&i.Task.AttemptCount,
+			&i.Task.CreatedAt,
+			&i.Task.InterruptedAt,
+			&i.Task.Description,
+			&i.Distance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setAttemptSnapshot = `-- name: SetAttemptSnapshot :one
+UPDATE attempts SET snapshot_commit = $2, snapshot_diff_hash = $3 WHERE id = $1 RETURNING id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash
+`
+
+type SetAttemptSnapshotParams struct {
+	ID               pgtype.UUID `json:"id"`
+	SnapshotCommit   string      `json:"snapshot_commit"`
+	SnapshotDiffHash string      `json:"snapshot_diff_hash"`
+}
+
+func (q *Queries) SetAttemptSnapshot(ctx context.Context, arg SetAttemptSnapshotParams) (Attempt, error) {
+	row := q.db.QueryRow(ctx, setAttemptSnapshot, arg.ID, arg.SnapshotCommit, arg.SnapshotDiffHash)
+	var i Attempt
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Number,
+		&i.Status,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.SnapshotCommit,
+		&i.SnapshotDiffHash,
+	)
+	return i, err
+}
+
+const transitionTaskStatus = `-- name: TransitionTaskStatus :one
+UPDATE tasks SET status = $1
+WHERE id = $2 AND status = $3
+RETURNING id, project_id, title, status, attempt_count, created_at, interrupted_at, description
+`
+
+type TransitionTaskStatusParams struct {
+	ToStatus   string      `json:"to_status"`
+	ID         pgtype.UUID `json:"id"`
+	FromStatus string      `json:"from_status"`
+}
+
+// Compare-and-set: only updates if the task is still in from_status.
+func (q *Queries) TransitionTaskStatus(ctx context.Context, arg TransitionTaskStatusParams) (Task, error) {
+	row := q.db.QueryRow(ctx, transitionTaskStatus, arg.ToStatus, arg.ID, arg.FromStatus)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Status,
+		&i.AttemptCount,
+		&i.CreatedAt,
+		&i.InterruptedAt,
+		&i.Description,
+	)
+	return i, err
+}
+
+const updateProjectGateConfig = `-- name: UpdateProjectGateConfig :one
+UPDATE projects SET gate_config = $1, scanned_at = NOW()
+WHERE id = $2
+RETURNING id, path, created_at, gate_config, scanned_at
+`
+
+type UpdateProjectGateConfigParams struct {
+	GateConfig []byte      `json:"-"`
+	ID         pgtype.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateProjectGateConfig(ctx context.Context, arg UpdateProjectGateConfigParams) (Project, error) {
+	row := q.db.QueryRow(ctx, updateProjectGateConfig, arg.GateConfig, arg.ID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Path,
+		&i.CreatedAt,
+		&i.GateConfig,
+		&i.ScannedAt,
+	)
+	return i, err
+}
+
+const updateTask = `-- name: UpdateTask :one
+UPDATE tasks
+SET title = COALESCE($1, title),
+    description = COALESCE($2, description)
+WHERE id = $3
+RETURNING id, project_id, title, status, attempt_count, created_at, interrupted_at, description
+`
+
+type UpdateTaskParams struct {
+	Title       pgtype.Text `json:"title"`
+	Description pgtype.Text `json:"description"`
+	ID          pgtype.UUID `json:"id"`
+}
+
+// NULL arguments leave the column unchanged.
+func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, updateTask, arg.Title, arg.Description, arg.ID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Status,
+		&i.AttemptCount,
+		&i.CreatedAt,
+		&i.InterruptedAt,
+		&i.Description,
+	)
+	return i, err
+}
+
 const upsertSetting = `-- name: UpsertSetting :exec
 INSERT INTO settings (key, value, is_encrypted, updated_at)
 VALUES ($1, $2, $3, NOW())
@@ -188,3 +802,20 @@ func (q *Queries) UpsertSetting(ctx context.Context, arg UpsertSettingParams) er
 	_, err := q.db.Exec(ctx, upsertSetting, arg.Key, arg.Value, arg.IsEncrypted)
 	return err
 }
+
+const upsertTaskEmbedding = `-- name: UpsertTaskEmbedding :exec
+INSERT INTO task_embeddings (task_id, embedding, updated_at)
+VALUES ($1, CAST($2::text AS vector), NOW())
+ON CONFLICT (task_id) DO UPDATE
+SET embedding = EXCLUDED.embedding, updated_at = NOW()
+`
+
+type UpsertTaskEmbeddingParams struct {
+	TaskID    pgtype.UUID `json:"task_id"`
+	Embedding string      `json:"embedding"`
+}
+
+func (q *Queries) UpsertTaskEmbedding(ctx context.Context, arg UpsertTaskEmbeddingParams) error {
+	_, err := q.db.Exec(ctx, upsertTaskEmbedding, arg.TaskID, arg.Embedding)
+	return err
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+)
+
+const attemptColumns = "id, task_id, number, status, started_at, finished_at, snapshot_commit, snapshot_diff_hash"
+
+const gateResultColumns = "id, attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, created_at, cause"
+
+func (q *Queries) CreateAttempt(ctx context.Context, arg database.CreateAttemptParams) (database.Attempt, error) {
+	row := q.db.QueryRowContext(ctx,
+		"INSERT INTO attempts (id, task_id, number, started_at) VALUES (?, ?, ?, ?) RETURNING "+attemptColumns,
+		uuid.NewString(), arg.TaskID.String(), arg.Number, now())
+	return scanAttempt(row)
+}
+
+func (q *Queries) FinishAttempt(ctx context.Context, arg database.FinishAttemptParams) (database.Attempt, error) {
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE attempts SET status = ?, finished_at = ? WHERE id = ? RETURNING "+attemptColumns,
+		arg.Status, now(), arg.ID.String())
+	return scanAttempt(row)
+}
+
+func (q *Queries) SetAttemptSnapshot(ctx context.Context, arg database.SetAttemptSnapshotParams) (database.Attempt, error) {
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE attempts SET snapshot_commit = ?, snapshot_diff_hash = ? WHERE id = ? RETURNING "+attemptColumns,
+		arg.SnapshotCommit, arg.SnapshotDiffHash, arg.ID.String())
+	return scanAttempt(row)
+}
+
+func (q *Queries) GetAttempt(ctx context.Context, id pgtype.UUID) (database.Attempt, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT "+attemptColumns+" FROM attempts WHERE id = ? LIMIT 1", id.String())
+	return scanAttempt(row)
+}
+
+func (q *Queries) ListAttempts(ctx context.Context, taskID pgtype.UUID) ([]database.Attempt, error) {
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT "+attemptColumns+" FROM attempts WHERE task_id = ? ORDER BY started_at, number", taskID.String())
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanAttempt)
+}
+
+func (q *Queries) CreateGateResult(ctx context.Context, arg database.CreateGateResultParams) (database.GateResult, error) {
+	var exitCode any
+	if arg.ExitCode.Valid {
+		exitCode = arg.ExitCode.Int32
+	}
+	row := q.db.QueryRowContext(ctx,
+		"INSERT INTO gate_results (id, attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, cause, created_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING "+gateResultColumns,
+		uuid.NewString(), arg.AttemptID.String(), arg.GateName, arg.Status, arg.DurationMs, exitCode, arg.Stdout, arg.Stderr, arg.Cause, now())
+	return scanGateResult(row)
+}
+
+func (q *Queries) ListGateResults(ctx context.Context, attemptID pgtype.UUID) ([]database.GateResult, error) {
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT "+gateResultColumns+" FROM gate_results WHERE attempt_id = ? ORDER BY created_at, rowid", attemptID.String())
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanGateResult)
+}
+
+func (q *Queries) CreateGateFinding(ctx context.Context, arg database.CreateGateFindingParams) error {
+	_, err := q.db.ExecContext(ctx,
+		"INSERT INTO gate_findings (gate_result_id, severity, file, line, message, tool, rule_id, hint) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		arg.GateResultID.String(), arg.Severity, arg.File, arg.Line, arg.Message, arg.Tool, arg.RuleID, arg.Hint)
+	return err
+}
+
+func (q *Queries) ListAttemptFindings(ctx context.Context, attemptID pgtype.UUID) ([]database.GateFinding, error) {
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT gate_findings.id, gate_findings.gate_result_id, gate_findings.severity, gate_findings.file, gate_findings.line, "+
+			"gate_findings.message, gate_findings.tool, gate_findings.rule_id, gate_findings.hint "+
+			"FROM gate_findings JOIN gate_results ON gate_results.id = gate_findings.gate_result_id "+
+			"WHERE gate_results.attempt_id = ? ORDER BY gate_findings.id",
+		attemptID.String())
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanGateFinding)
+}
+
+func (q *Queries) CreateGateLogLine(ctx context.Context, arg database.CreateGateLogLineParams) error {
+	_, err := q.db.ExecContext(ctx,
+		"INSERT INTO gate_log_lines (attempt_id, gate_name, seq, stream, line, logged_at) VALUES (?, ?, ?, ?, ?, ?)",
+		arg.AttemptID.String(), arg.GateName, arg.Seq, arg.Stream, arg.Line, arg.LoggedAt.Time.UTC().Format(timeFormat))
+	return err
+}
+
+func (q *Queries) ListGateLogLines(ctx context.Context, attemptID pgtype.UUID) ([]database.GateLogLine, error) {
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT id, attempt_id, gate_name, seq, stream, line, logged_at FROM gate_log_lines WHERE attempt_id = ? ORDER BY id",
+		attemptID.String())
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanGateLogLine)
+}
+
+func scanAttempt(row scanner) (database.Attempt, error) {
+	var i database.Attempt
+	var id, taskID string
+	var startedAt, finishedAt sql.NullString
+	if err := row.Scan(&id, &taskID, &i.Number, &i.Status, &startedAt, &finishedAt, &i.SnapshotCommit, &i.SnapshotDiffHash); err != nil {
+		return i, translate(err)
+	}
+	if err := i.ID.Scan(id); err != nil {
+		return i, err
+	}
+	if err := i.TaskID.Scan(taskID); err != nil {
+		return i, err
+	}
+	var err error
+	if i.StartedAt, err = timestamptz(startedAt); err != nil {
+		return i, err
+	}
+	i.FinishedAt, err = timestamptz(finishedAt)
+	return i, err
+}
+
+func scanGateResult(row scanner) (database.GateResult, error) {
+	var i database.GateResult
+	var id, attemptID string
+	var exitCode sql.NullInt32
+	var createdAt sql.NullString
+	if err := row.Scan(&id, &attemptID, &i.GateName, &i.Status, &i.DurationMs, &exitCode, &i.Stdout, &i.Stderr, &createdAt, &i.Cause); err != nil {
+		return i, translate(err)
+	}
+	if err := i.ID.Scan(id); err != nil {
+		return i, err
+	}
+	if err := i.AttemptID.Scan(attemptID); err != nil {
+		return i, err
+	}
+	i.ExitCode = pgtype.Int4{Int32: exitCode.Int32, Valid: exitCode.Valid}
+	var err error
+	i.CreatedAt, err = timestamptz(createdAt)
+	return i, err
+}
+
+func scanGateFinding(row scanner) (database.GateFinding, error) {
+	var i database.GateFinding
+	var gateResultID string
+	if err := row.Scan(&i.ID, &gateResultID, &i.Severity, &i.File, &i.Line, &i.Message, &i.Tool, &i.RuleID, &i.Hint); err != nil {
+		return i, translate(err)
+	}
+	err := i.GateResultID.Scan(gateResultID)
+	return i, err
+}
+
+func scanGateLogLine(row scanner) (database.GateLogLine, error) {
+	var i database.GateLogLine
+	var attemptID string
+	var loggedAt sql.NullString
+	if err := row.Scan(&i.ID, &attemptID, &i.GateName, &i.Seq, &i.Stream, &i.Line, &loggedAt); err != nil {
+		return i, translate(err)
+	}
+	if err := i.AttemptID.Scan(attemptID); err != nil {
+		return i, err
+	}
+	var err error
+	i.LoggedAt, err = timestamptz(loggedAt)
+	return i, err
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,23 @@
+-- SQLite mirror of database/migrations. Versions must stay in lockstep with
+-- the Postgres migrations so both backends expose the same schema.
+CREATE TABLE projects (
+    id TEXT PRIMARY KEY,
+    path TEXT NOT NULL UNIQUE,
+    created_at TEXT
+);
+
+CREATE TABLE tasks (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT
+);
+
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value BLOB NOT NULL,
+    is_encrypted INTEGER DEFAULT 0,
+    updated_at TEXT NOT NULL
+);
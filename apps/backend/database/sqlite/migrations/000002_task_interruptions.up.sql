@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN interrupted_at TEXT;
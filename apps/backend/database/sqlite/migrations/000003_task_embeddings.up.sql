@@ -0,0 +1,7 @@
+-- Embeddings are stored in pgvector's text form and searched by brute force
+-- in Go; there is no vector index.
+CREATE TABLE task_embeddings (
+    task_id TEXT PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    embedding TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
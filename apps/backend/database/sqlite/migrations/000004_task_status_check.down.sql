@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS tasks_status_check_update;
+DROP TRIGGER IF EXISTS tasks_status_check_insert;
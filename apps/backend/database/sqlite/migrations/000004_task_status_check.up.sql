@@ -0,0 +1,16 @@
+-- SQLite can't add a CHECK constraint to an existing table without
+-- rebuilding it, so the status check is enforced with triggers instead.
+UPDATE tasks SET status = 'BACKLOG'
+WHERE status NOT IN ('BACKLOG', 'IN_PROGRESS', 'VALIDATING', 'DONE', 'BLOCKED');
+
+CREATE TRIGGER tasks_status_check_insert BEFORE INSERT ON tasks
+WHEN NEW.status NOT IN ('BACKLOG', 'IN_PROGRESS', 'VALIDATING', 'DONE', 'BLOCKED')
+BEGIN
+    SELECT RAISE(ABORT, 'tasks_status_check');
+END;
+
+CREATE TRIGGER tasks_status_check_update BEFORE UPDATE OF status ON tasks
+WHEN NEW.status NOT IN ('BACKLOG', 'IN_PROGRESS', 'VALIDATING', 'DONE', 'BLOCKED')
+BEGIN
+    SELECT RAISE(ABORT, 'tasks_status_check');
+END;
@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN scanned_at;
+ALTER TABLE projects DROP COLUMN gate_config;
@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN gate_config TEXT;
+ALTER TABLE projects ADD COLUMN scanned_at TEXT;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS gate_findings;
+DROP TABLE IF EXISTS gate_results;
+DROP TABLE IF EXISTS attempts;
@@ -0,0 +1,40 @@
+CREATE TABLE attempts (
+    id TEXT PRIMARY KEY,
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'RUNNING'
+        CONSTRAINT attempts_status_check CHECK (status IN ('RUNNING', 'PASSED', 'FAILED', 'INTERRUPTED')),
+    started_at TEXT NOT NULL,
+    finished_at TEXT
+);
+
+CREATE INDEX attempts_task_id_idx ON attempts (task_id, started_at);
+
+CREATE TABLE gate_results (
+    id TEXT PRIMARY KEY,
+    attempt_id TEXT NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+    gate_name TEXT NOT NULL,
+    status TEXT NOT NULL
+        CONSTRAINT gate_results_status_check CHECK (status IN ('PASS', 'VALIDATION_FAILURE', 'SYSTEM_ERROR')),
+    duration_ms INTEGER NOT NULL,
+    exit_code INTEGER,
+    stdout TEXT NOT NULL DEFAULT '',
+    stderr TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX gate_results_attempt_id_idx ON gate_results (attempt_id, created_at);
+
+CREATE TABLE gate_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gate_result_id TEXT NOT NULL REFERENCES gate_results(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    file TEXT NOT NULL DEFAULT '',
+    line INTEGER NOT NULL DEFAULT 0,
+    message TEXT NOT NULL,
+    tool TEXT NOT NULL DEFAULT '',
+    rule_id TEXT NOT NULL DEFAULT '',
+    hint TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX gate_findings_gate_result_id_idx ON gate_findings (gate_result_id);
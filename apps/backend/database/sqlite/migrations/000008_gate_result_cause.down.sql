@@ -0,0 +1 @@
+ALTER TABLE gate_results DROP COLUMN cause;
@@ -0,0 +1 @@
+ALTER TABLE gate_results ADD COLUMN cause TEXT NOT NULL DEFAULT '';
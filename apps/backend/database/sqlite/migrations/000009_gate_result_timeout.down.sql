@@ -0,0 +1,41 @@
+-- SQLite can't change a CHECK constraint in place, so gate_results is
+-- rebuilt. Dropping it with foreign keys on would cascade to gate_findings,
+-- so the findings are set aside first and restored afterwards.
+CREATE TABLE gate_findings_backup AS SELECT * FROM gate_findings;
+DROP TABLE gate_findings;
+
+ALTER TABLE gate_results RENAME TO gate_results_old;
+CREATE TABLE gate_results (
+    id TEXT PRIMARY KEY,
+    attempt_id TEXT NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+    gate_name TEXT NOT NULL,
+    status TEXT NOT NULL
+        CONSTRAINT gate_results_status_check CHECK (status IN ('PASS', 'VALIDATION_FAILURE', 'SYSTEM_ERROR')),
+    duration_ms INTEGER NOT NULL,
+    exit_code INTEGER,
+    stdout TEXT NOT NULL DEFAULT '',
+    stderr TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    cause TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO gate_results (id, attempt_id, gate_name, status, duration_ms, exit_code, stdout, stderr, created_at, cause)
+SELECT id, attempt_id, gate_name, CASE status WHEN 'TIMEOUT' THEN 'VALIDATION_FAILURE' ELSE status END, duration_ms, exit_code, stdout, stderr, created_at, cause
+FROM gate_results_old ORDER BY rowid;
+DROP TABLE gate_results_old;
+CREATE INDEX gate_results_attempt_id_idx ON gate_results (attempt_id, created_at);
+
+CREATE TABLE gate_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    gate_result_id TEXT NOT NULL REFERENCES gate_results(id) ON DELETE CASCADE,
+    severity TEXT NOT NULL,
+    file TEXT NOT NULL DEFAULT '',
+    line INTEGER NOT NULL DEFAULT 0,
+    message TEXT NOT NULL,
+    tool TEXT NOT NULL DEFAULT '',
+    rule_id TEXT NOT NULL DEFAULT '',
+    hint TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO gate_findings (id, gate_result_id, severity, file, line, message, tool, rule_id, hint)
+SELECT id, gate_result_id, severity, file, line, message, tool, rule_id, hint FROM gate_findings_backup;
+DROP TABLE gate_findings_backup;
+CREATE INDEX gate_findings_gate_result_id_idx ON gate_findings (gate_result_id);
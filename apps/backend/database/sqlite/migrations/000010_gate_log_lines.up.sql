@@ -0,0 +1,11 @@
+CREATE TABLE gate_log_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    attempt_id TEXT NOT NULL REFERENCES attempts(id) ON DELETE CASCADE,
+    gate_name TEXT NOT NULL,
+    seq INTEGER NOT NULL,
+    stream TEXT NOT NULL CHECK (stream IN ('stdout', 'stderr')),
+    line TEXT NOT NULL,
+    logged_at TEXT NOT NULL
+);
+
+CREATE INDEX gate_log_lines_attempt_id_idx ON gate_log_lines (attempt_id, id);
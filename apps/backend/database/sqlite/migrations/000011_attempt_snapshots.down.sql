@@ -0,0 +1,2 @@
+ALTER TABLE attempts DROP COLUMN snapshot_diff_hash;
+ALTER TABLE attempts DROP COLUMN snapshot_commit;
@@ -0,0 +1,2 @@
+ALTER TABLE attempts ADD COLUMN snapshot_commit TEXT NOT NULL DEFAULT '';
+ALTER TABLE attempts ADD COLUMN snapshot_diff_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,374 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+)
+
+// timeFormat is fixed-width so timestamps sort correctly as text.
+const timeFormat = "2006-01-02T15:04:05.000000Z"
+
+// Queries implements database.Querier on SQLite. Each method mirrors the
+// query of the same name in database/query.sql.
+type Queries struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Queries {
+	return &Queries{db: db}
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+const projectColumns = "id, path, created_at, gate_config, scanned_at"
+
+const taskColumns = "id, project_id, title, status, attempt_count, created_at, interrupted_at, description"
+
+func (q *Queries) CreateProject(ctx context.Context, path string) (database.Project, error) {
+	row := q.db.QueryRowContext(ctx,
+		"INSERT INTO projects (id, path, created_at) VALUES (?, ?, ?) RETURNING "+projectColumns,
+		uuid.NewString(), path, now())
+	return scanProject(row)
+}
+
+func (q *Queries) CreateTask(ctx context.Context, arg database.CreateTaskParams) (database.Task, error) {
+	row := q.db.QueryRowContext(ctx,
+		"INSERT INTO tasks (id, project_id, title, description, status, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING "+taskColumns,
+		uuid.NewString(), arg.ProjectID.String(), arg.Title, arg.Description, arg.Status, now())
+	return scanTask(row)
+}
+
+func (q *Queries) DeleteProject(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, "DELETE FROM projects WHERE id = ?", id.String())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (q *Queries) DeleteTask(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, "DELETE FROM tasks WHERE id = ?", id.String())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (q *Queries) GetProject(ctx context.Context, path string) (database.Project, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE path = ? LIMIT 1", path)
+	return scanProject(row)
+}
+
+func (q *Queries) GetProjectByID(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE id = ? LIMIT 1", id.String())
+	return scanProject(row)
+}
+
+func (q *Queries) GetSetting(ctx context.Context, key string) (database.Setting, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT key, value, is_encrypted, updated_at FROM settings WHERE key = ?", key)
+
+	var i database.Setting
+	var encrypted sql.NullBool
+	var updatedAt sql.NullString
+	if err := row.Scan(&i.Key, &i.Value, &encrypted, &updatedAt); err != nil {
+		return i, translate(err)
+	}
+	i.IsEncrypted = pgtype.Bool{Bool: encrypted.Bool, Valid: encrypted.Valid}
+	var err error
+	i.UpdatedAt, err = timestamptz(updatedAt)
+	return i, err
+}
+
+func (q *Queries) GetTask(ctx context.Context, id pgtype.UUID) (database.Task, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ? LIMIT 1", id.String())
+	return scanTask(row)
+}
+
+func (q *Queries) IncrementTaskAttempt(ctx context.Context, id pgtype.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE tasks SET attempt_count = attempt_count + 1, interrupted_at = NULL WHERE id = ? RETURNING attempt_count",
+		id.String())
+	var attemptCount int32
+	err := row.Scan(&attemptCount)
+	return attemptCount, translate(err)
+}
+
+func (q *Queries) ListInterruptedTasks(ctx context.Context) ([]database.Task, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE interrupted_at IS NOT NULL ORDER BY interrupted_at")
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanTask)
+}
+
+func (q *Queries) ListProjects(ctx context.Context) ([]database.Project, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT "+projectColumns+" FROM projects ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanProject)
+}
+
+func (q *Queries) ListTasks(ctx context.Context, projectID pgtype.UUID) ([]database.Task, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE project_id = ? ORDER BY created_at", projectID.String())
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanTask)
+}
+
+func (q *Queries) ListTasksByStatus(ctx context.Context, arg database.ListTasksByStatusParams) ([]database.Task, error) {
+	if len(arg.Statuses) == 0 {
+		// Matches Postgres: ANY of an empty array is false.
+		return nil, nil
+	}
+	args := []any{arg.ProjectID.String()}
+	for _, status := range arg.Statuses {
+		args = append(args, status)
+	}
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT "+taskColumns+" FROM tasks WHERE project_id = ? AND status IN ("+placeholders(len(arg.Statuses))+") ORDER BY created_at",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	return collect(rows, scanTask)
+}
+
+func (q *Queries) MarkTaskInterrupted(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.ExecContext(ctx,
+		"UPDATE tasks SET interrupted_at = ?, attempt_count = MAX(attempt_count - 1, 0) WHERE id = ?",
+		now(), id.String())
+	return err
+}
+
+func (q *Queries) RefundTaskAttempt(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.ExecContext(ctx, "UPDATE tasks SET attempt_count = MAX(attempt_count - 1, 0) WHERE id = ?", id.String())
+	return err
+}
+
+// SearchTasksByEmbedding is the brute-force stand-in for pgvector's <=>
+// operator: it loads every embedding and ranks by cosine distance in Go.
+func (q *Queries) SearchTasksByEmbedding(ctx context.Context, arg database.SearchTasksByEmbeddingParams) ([]database.SearchTasksByEmbeddingRow, error) {
+	query, err := database.ParseVector(arg.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.db.QueryContext(ctx,
+		"SELECT tasks.id, tasks.project_id, tasks.title, tasks.status, tasks.attempt_count, tasks.created_at, tasks.interrupted_at, tasks.description, task_embeddings.embedding "+
+			"FROM task_embeddings JOIN tasks ON tasks.id = task_embeddings.task_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []database.SearchTasksByEmbeddingRow
+	for rows.Next() {
+		var embedding string
+		task, err := scanTaskWith(rows, &embedding)
+		if err != nil {
+			return nil, err
+		}
+		vec, err := database.ParseVector(embedding)
+		if err != nil {
+			return nil, err
+		}
+		distance, err := CosineDistance(query, vec)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, database.SearchTasksByEmbeddingRow{Task: task, Distance: distance})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Distance < items[j].Distance
+	})
+	if limit := int(arg.MaxResults); limit >= 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func (q *Queries) TransitionTaskStatus(ctx context.Context, arg database.TransitionTaskStatusParams) (database.Task, error) {
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE tasks SET status = ? WHERE id = ? AND status = ? RETURNING "+taskColumns,
+		arg.ToStatus, arg.ID.String(), arg.FromStatus)
+	return scanTask(row)
+}
+
+func (q *Queries) UpdateProjectGateConfig(ctx context.Context, arg database.UpdateProjectGateConfigParams) (database.Project, error) {
+	var config any
+	if arg.GateConfig != nil {
+		config = string(arg.GateConfig)
+	}
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE projects SET gate_config = ?, scanned_at = ? WHERE id = ? RETURNING "+projectColumns,
+		config, now(), arg.ID.String())
+	return scanProject(row)
+}
+
+func (q *Queries) UpdateTask(ctx context.Context, arg database.UpdateTaskParams) (database.Task, error) {
+	row := q.db.QueryRowContext(ctx,
+		"UPDATE tasks SET title = COALESCE(?, title), description = COALESCE(?, description) WHERE id = ? RETURNING "+taskColumns,
+		nullText(arg.Title), nullText(arg.Description), arg.ID.String())
+	return scanTask(row)
+}
+
+func (q *Queries) UpsertSetting(ctx context.Context, arg database.UpsertSettingParams) error {
+	var encrypted any
+	if arg.IsEncrypted.Valid {
+		encrypted = arg.IsEncrypted.Bool
+	}
+	_, err := q.db.ExecContext(ctx, `INSERT INTO settings (key, value, is_encrypted, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE
+SET value = excluded.value, is_encrypted = excluded.is_encrypted, updated_at = excluded.updated_at`,
+		arg.Key, arg.Value, encrypted, now())
+	return err
+}
+
+func (q *Queries) UpsertTaskEmbedding(ctx context.Context, arg database.UpsertTaskEmbeddingParams) error {
+	if _, err := database.ParseVector(arg.Embedding); err != nil {
+		return err
+	}
+	_, err := q.db.ExecContext(ctx, `INSERT INTO task_embeddings (task_id, embedding, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (task_id) DO UPDATE
+SET embedding = excluded.embedding, updated_at = excluded.updated_at`,
+		arg.TaskID.String(), arg.Embedding, now())
+	return err
+}
+
+// CosineDistance matches pgvector's <=> operator: 1 - cosine similarity.
+func CosineDistance(a, b []float32) (float64, error) {
+	if len(a) != len(b) {
+		return 0, errors.New("vector dimensions differ")
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return math.NaN(), nil
+	}
+	return 1 - dot/(math.Sqrt(normA)*math.Sqrt(normB)), nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProject(row scanner) (database.Project, error) {
+	var i database.Project
+	var id string
+	var createdAt, gateConfig, scannedAt sql.NullString
+	if err := row.Scan(&id, &i.Path, &createdAt, &gateConfig, &scannedAt); err != nil {
+		return i, translate(err)
+	}
+	if err := i.ID.Scan(id); err != nil {
+		return i, err
+	}
+	if gateConfig.Valid {
+		i.GateConfig = []byte(gateConfig.String)
+	}
+	var err error
+	if i.CreatedAt, err = timestamptz(createdAt); err != nil {
+		return i, err
+	}
+	i.ScannedAt, err = timestamptz(scannedAt)
+	return i, err
+}
+
+func scanTask(row scanner) (database.Task, error) {
+	return scanTaskWith(row)
+}
+
+// scanTaskWith scans the task columns followed by any extra columns.
+func scanTaskWith(row scanner, extra ...any) (database.Task, error) {
+	var i database.Task
+	var id, projectID string
+	var createdAt, interruptedAt sql.NullString
+	dest := append([]any{&id, &projectID, &i.Title, &i.Status, &i.AttemptCount, &createdAt, &interruptedAt, &i.Description}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return i, translate(err)
+	}
+	if err := i.ID.Scan(id); err != nil {
+		return i, err
+	}
+	if err := i.ProjectID.Scan(projectID); err != nil {
+		return i, err
+	}
+	var err error
+	if i.CreatedAt, err = timestamptz(createdAt); err != nil {
+		return i, err
+	}
+	i.InterruptedAt, err = timestamptz(interruptedAt)
+	return i, err
+}
+
+func collect[T any](rows *sql.Rows, scan func(scanner) (T, error)) ([]T, error) {
+	defer rows.Close()
+	var items []T
+	for rows.Next() {
+		i, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func nullText(t pgtype.Text) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.String
+}
+
+func now() string {
+	return time.Now().UTC().Format(timeFormat)
+}
+
+func timestamptz(s sql.NullString) (pgtype.Timestamptz, error) {
+	if !s.Valid {
+		return pgtype.Timestamptz{}, nil
+	}
+	t, err := time.Parse(timeFormat, s.String)
+	if err != nil {
+		return pgtype.Timestamptz{}, err
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}, nil
+}
+
+// translate maps database/sql errors onto their pgx equivalents so callers
+// can check errors the same way regardless of backend.
+func translate(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+	return err
+}
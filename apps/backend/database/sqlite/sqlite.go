@@ -0,0 +1,156 @@
+// Package sqlite is the zero-dependency storage backend for local mode. It
+// implements database.Querier on an embedded SQLite file so the rest of
+// Monarch is unaware of which backend is in use.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/monarch-dev/monarch/database"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// Open opens (creating if needed) the SQLite database at path.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrator applies the embedded SQLite migrations. It mirrors
+// database.Migrator; SQLite's file lock takes the place of the advisory lock.
+type Migrator struct {
+	db         *sql.DB
+	migrations []database.Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := database.LoadMigrations(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrate brings the database up to the latest embedded version.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	m, err := NewMigrator(db)
+	if err != nil {
+		return 0, err
+	}
+	return m.Up(ctx)
+}
+
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, mig := range m.migrations {
+		if mig.Version <= current {
+			continue
+		}
+		if err := m.apply(ctx, mig.Up, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", mig.Version, now()); err != nil {
+			return applied, fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, errors.New("steps must be positive")
+	}
+
+	rolledBack := 0
+	for rolledBack < steps {
+		current, err := m.Version(ctx)
+		if err != nil {
+			return rolledBack, err
+		}
+		if current == 0 {
+			break
+		}
+
+		var mig *database.Migration
+		for i := range m.migrations {
+			if m.migrations[i].Version == current {
+				mig = &m.migrations[i]
+			}
+		}
+		if mig == nil {
+			return rolledBack, fmt.Errorf("applied version %d has no embedded migration", current)
+		}
+		if mig.Down == "" {
+			return rolledBack, fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+		}
+
+		if err := m.apply(ctx, mig.Down, "DELETE FROM schema_migrations WHERE version = ?", mig.Version); err != nil {
+			return rolledBack, fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		rolledBack++
+	}
+	return rolledBack, nil
+}
+
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	if _, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var version int64
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+func (m *Migrator) apply(ctx context.Context, script, record string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,125 @@
+package sqlite_test
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "nested", "monarch.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := sqlite.NewMigrator(db)
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, applied)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+
+	rolledBack, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+
+	after, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version-1, after)
+
+	// Roll everything back and forward again to exercise every down file.
+	_, err = m.Down(ctx, 100)
+	require.NoError(t, err)
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int(version), applied)
+}
+
+// Migration 9 rebuilds gate_results; its findings must survive.
+func TestMigrator_GateResultRebuildKeepsFindings(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "monarch.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := sqlite.NewMigrator(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	_, err = m.Down(ctx, int(version-8))
+	require.NoError(t, err)
+
+	q := sqlite.New(db)
+	proj, err := q.CreateProject(ctx, t.TempDir())
+	require.NoError(t, err)
+	task, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: "BACKLOG"})
+	require.NoError(t, err)
+	// The attempts table predates later columns, so its row is inserted by hand.
+	var attempt database.Attempt
+	require.NoError(t, attempt.ID.Scan("00000000-0000-0000-0000-0000000000a1"))
+	_, err = db.ExecContext(ctx, "INSERT INTO attempts (id, task_id, number, started_at) VALUES (?, ?, 1, '2024-01-01T00:00:00Z')",
+		attempt.ID.String(), task.ID.String())
+	require.NoError(t, err)
+	result, err := q.CreateGateResult(ctx, database.CreateGateResultParams{
+		AttemptID: attempt.ID, GateName: "lint", Status: "VALIDATION_FAILURE", Cause: "kept",
+	})
+	require.NoError(t, err)
+	require.NoError(t, q.CreateGateFinding(ctx, database.CreateGateFindingParams{
+		GateResultID: result.ID, Severity: "ERROR", Message: "bad",
+	}))
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	results, err := q.ListGateResults(ctx, attempt.ID)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "kept", results[0].Cause)
+	findings, err := q.ListAttemptFindings(ctx, attempt.ID)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, result.ID, findings[0].GateResultID)
+
+	// Deleting the attempt still cascades through the rebuilt tables.
+	_, err = db.ExecContext(ctx, "DELETE FROM attempts")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gate_findings").Scan(&n))
+	assert.Zero(t, n)
+}
+
+func TestCosineDistance(t *testing.T) {
+	d, err := sqlite.CosineDistance([]float32{1, 0}, []float32{1, 0})
+	require.NoError(t, err)
+	assert.InDelta(t, 0, d, 1e-9)
+
+	d, err = sqlite.CosineDistance([]float32{1, 0}, []float32{0, 1})
+	require.NoError(t, err)
+	assert.InDelta(t, 1, d, 1e-9)
+
+	d, err = sqlite.CosineDistance([]float32{1, 0}, []float32{-1, 0})
+	require.NoError(t, err)
+	assert.InDelta(t, 2, d, 1e-9)
+
+	d, err = sqlite.CosineDistance([]float32{0, 0}, []float32{1, 0})
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(d))
+
+	_, err = sqlite.CosineDistance([]float32{1}, []float32{1, 0})
+	assert.Error(t, err)
+}
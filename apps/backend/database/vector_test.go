@@ -0,0 +1,27 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/monarch-dev/monarch/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVector_RoundTrip(t *testing.T) {
+	v := []float32{0.5, -1, 0.125}
+
+	s := database.FormatVector(v)
+	assert.Equal(t, "[0.5,-1,0.125]", s)
+
+	parsed, err := database.ParseVector(s)
+	require.NoError(t, err)
+	assert.Equal(t, v, parsed)
+}
+
+func TestParseVector_Invalid(t *testing.T) {
+	for _, s := range []string{"0.5,1", "[0.5,abc]"} {
+		_, err := database.ParseVector(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,155 @@
+package gates
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Argv returns what a standard gate runs: Args as given, Command under
+// sh -c if Shell is set, or else Command split by SplitCommand.
+func (g Gate) Argv() ([]string, error) {
+	switch {
+	case len(g.Args) > 0:
+		return g.Args, nil
+	case g.Shell:
+		return []string{"sh", "-c", g.Command}, nil
+	}
+	return SplitCommand(g.Command)
+}
+
+// SplitCommand splits a gate's command string into arguments. No shell is
+// involved, so pipes, redirects, &&, globs and $VARS are passed on as
+// plain text; set shell: true to use them. Quoting follows the shell's
+// rules:
+//
+//   - unquoted spaces and tabs separate arguments;
+//   - 'single quotes' keep everything up to the next ' as is;
+//   - "double quotes" keep everything as is, except that \" and \\ stand
+//     for " and \;
+//   - outside quotes, a backslash keeps the next character as is.
+//
+// Quoted text joins adjacent text, so -run='TestA|TestB' is one argument.
+func SplitCommand(s string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		inArg bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n', '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			arg.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+					i++
+				}
+				arg.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inArg = true
+		case '\\':
+			if i+1 == len(s) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			arg.WriteByte(s[i])
+			inArg = true
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// envName is a portable environment variable name.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidEnvName reports whether name is a portable environment variable
+// name, as gates' env and secrets must use.
+func ValidEnvName(name string) bool {
+	return envName.MatchString(name)
+}
+
+// Within cleans the slash-separated relative path p and reports whether it
+// stays inside the dir it is relative to, as a gate's workdir and a
+// runner's build paths must.
+func Within(p string) (string, bool) {
+	rel := path.Clean(p)
+	return rel, !path.IsAbs(rel) && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// validate checks the gate's command, workdir and environment, returning
+// every problem found.
+func (g Gate) validate() []error {
+	var errs []error
+	if g.Type == "" || g.Type == "standard" {
+		switch {
+		case len(g.Args) > 0 && g.Command != "":
+			errs = append(errs, errors.New("set either command or args, not both"))
+		case len(g.Args) > 0 && g.Shell:
+			errs = append(errs, errors.New("shell runs command, not args"))
+		case len(g.Args) > 0 && g.Args[0] == "":
+			errs = append(errs, errors.New("args[0] must not be empty"))
+		case len(g.Args) == 0 && strings.TrimSpace(g.Command) == "":
+			errs = append(errs, errors.New("command or args is required"))
+		case !g.Shell:
+			if _, err := SplitCommand(g.Command); err != nil {
+				errs = append(errs, fmt.Errorf("command: %w", err))
+			}
+		}
+	}
+
+	if g.Workdir != "" {
+		if _, ok := Within(g.Workdir); !ok {
+			errs = append(errs, fmt.Errorf("workdir %q must be inside the project", g.Workdir))
+		}
+	}
+
+	if g.Timeout != "" {
+		if d, err := time.ParseDuration(g.Timeout); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("timeout %q must be a positive duration, such as 5m", g.Timeout))
+		}
+	}
+
+	for name := range g.Env {
+		if !ValidEnvName(name) {
+			errs = append(errs, fmt.Errorf("env: invalid variable name %q", name))
+		}
+	}
+	for name, ref := range g.Secrets {
+		switch {
+		case !ValidEnvName(name):
+			errs = append(errs, fmt.Errorf("secrets: invalid variable name %q", name))
+		case ref == "":
+			errs = append(errs, fmt.Errorf("secrets: %s names no secret", name))
+		}
+		if _, ok := g.Env[name]; ok {
+			errs = append(errs, fmt.Errorf("%s is set in both env and secrets", name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+package gates_test
+
+import (
+	"testing"
+
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"go test ./...", []string{"go", "test", "./..."}},
+		{"  go\ttest  ", []string{"go", "test"}},
+		{"go test -run 'TestA|TestB' ./...", []string{"go", "test", "-run", "TestA|TestB", "./..."}},
+		{"go test -run='TestA|TestB'", []string{"go", "test", "-run=TestA|TestB"}},
+		{`echo "a \"quoted\" \\ $HOME"`, []string{"echo", `a "quoted" \ $HOME`}},
+		{`echo "\n"`, []string{"echo", `\n`}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{"make test && make lint", []string{"make", "test", "&&", "make", "lint"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := gates.SplitCommand(tt.command)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitCommand_Errors(t *testing.T) {
+	for command, want := range map[string]string{
+		"go test -run 'TestA": "unterminated single quote",
+		`echo "hi`:            "unterminated double quote",
+		`echo \`:              "trailing backslash",
+	} {
+		_, err := gates.SplitCommand(command)
+		assert.EqualError(t, err, want, command)
+	}
+}
+
+func TestGate_Argv(t *testing.T) {
+	tests := []struct {
+		name string
+		gate gates.Gate
+		want []string
+	}{
+		{"Command", gates.Gate{Command: "go test './...'"}, []string{"go", "test", "./..."}},
+		{"Args", gates.Gate{Args: []string{"go", "test", "-run", "TestA|TestB"}}, []string{"go", "test", "-run", "TestA|TestB"}},
+		{"Shell", gates.Gate{Command: "go vet ./... && go test ./... | tee out.txt", Shell: true},
+			[]string{"sh", "-c", "go vet ./... && go test ./... | tee out.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.gate.Argv()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidEnvName(t *testing.T) {
+	for _, name := range []string{"TOKEN", "_x", "GO111MODULE"} {
+		assert.True(t, gates.ValidEnvName(name), name)
+	}
+	for _, name := range []string{"", "1X", "NODE-ENV", "A B", "A=B"} {
+		assert.False(t, gates.ValidEnvName(name), name)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	for p, want := range map[string]string{".": ".", "pkg/../api/": "api", "a/./b": "a/b", "..x": "..x"} {
+		got, ok := gates.Within(p)
+		assert.True(t, ok, p)
+		assert.Equal(t, want, got, p)
+	}
+	for _, p := range []string{"..", "../x", "a/../../x", "/etc"} {
+		_, ok := gates.Within(p)
+		assert.False(t, ok, p)
+	}
+}
@@ -1,6 +1,8 @@
 package gates
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -9,7 +11,22 @@ import (
 
 const configFile = ".monarch/gates.yaml"
 
+// RunnerDockerfile is built as the project's runner image when it exists,
+// even without a runner.build section.
+const RunnerDockerfile = ".monarch/runner.Dockerfile"
+
 func DetectStack(root string) (*Config, error) {
+	cfg, err := detect(root)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Runner.Build == nil && exists(filepath.Join(root, RunnerDockerfile)) {
+		cfg.Runner.Build = &Build{}
+	}
+	return cfg, nil
+}
+
+func detect(root string) (*Config, error) {
 	// 1. Check for explicit config
 	configPath := filepath.Join(root, configFile)
 	if _, err := os.Stat(configPath); err == nil {
@@ -25,6 +42,8 @@ func DetectStack(root string) (*Config, error) {
 		stack = "node"
 	} else if exists(filepath.Join(root, "requirements.txt")) || exists(filepath.Join(root, "pyproject.toml")) {
 		stack = "python"
+	} else if exists(filepath.Join(root, "Cargo.toml")) {
+		stack = "rust"
 	}
 
 	return &Config{Stack: stack}, nil
@@ -41,9 +60,27 @@ func parseFile(path string) (*Config, error) {
 	if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configFile, err)
+	}
 	return &cfg, nil
 }
 
+// validate checks every gate, reporting all problems at once.
+func (c *Config) validate() error {
+	var errs []error
+	for i, g := range c.Gates {
+		name := g.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		for _, err := range g.validate() {
+			errs = append(errs, fmt.Errorf("gate %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func exists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
@@ -20,6 +20,7 @@ func TestDetectStack_AutoDetect(t *testing.T) {
 		{"Node", []string{"package.json"}, "node"},
 		{"PythonReq", []string{"requirements.txt"}, "python"},
 		{"PythonToml", []string{"pyproject.toml"}, "python"},
+		{"Rust", []string{"Cargo.toml"}, "rust"},
 		{"Unknown", []string{}, "unknown"},
 	}
 
@@ -59,3 +60,136 @@ gates:
 	assert.Len(t, cfg.Gates, 1)
 	assert.Equal(t, "build", cfg.Gates[0].Name)
 }
+
+func TestDetectStack_RunnerSettings(t *testing.T) {
+	tmp := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tmp, ".monarch"), 0755))
+
+	yamlContent := `
+stack: go
+resources:
+  memory: 4g
+  cpus: 2
+gates:
+  - name: deps
+    command: go mod download
+    network: true
+    resources:
+      memory: 1g
+`
+	err := os.WriteFile(filepath.Join(tmp, ".monarch", "gates.yaml"), []byte(yamlContent), 0644)
+	require.NoError(t, err)
+
+	cfg, err := gates.DetectStack(tmp)
+	require.NoError(t, err)
+	assert.Equal(t, gates.Resources{Memory: "4g", CPUs: 2}, cfg.Resources)
+	require.Len(t, cfg.Gates, 1)
+	assert.True(t, cfg.Gates[0].Network)
+	assert.Equal(t, gates.Resources{Memory: "1g", CPUs: 2}, cfg.Resources.Merge(cfg.Gates[0].Resources))
+}
+
+func TestDetectStack_RunnerBuild(t *testing.T) {
+	t.Run("Dockerfile", func(t *testing.T) {
+		tmp := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(tmp, "go.mod"), []byte{}, 0644))
+		require.NoError(t, os.Mkdir(filepath.Join(tmp, ".monarch"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmp, gates.RunnerDockerfile), []byte("FROM golang:1.23\n"), 0644))
+
+		cfg, err := gates.DetectStack(tmp)
+		require.NoError(t, err)
+		assert.Equal(t, "go", cfg.Stack)
+		assert.Equal(t, &gates.Build{}, cfg.Runner.Build)
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		tmp := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(tmp, ".monarch"), 0755))
+		yamlContent := `
+stack: node
+runner:
+  build:
+    dockerfile: docker/ci.Dockerfile
+    context: docker
+    args:
+      NODE_VERSION: "22"
+`
+		require.NoError(t, os.WriteFile(filepath.Join(tmp, ".monarch", "gates.yaml"), []byte(yamlContent), 0644))
+
+		cfg, err := gates.DetectStack(tmp)
+		require.NoError(t, err)
+		assert.Equal(t, &gates.Build{
+			Dockerfile: "docker/ci.Dockerfile",
+			Context:    "docker",
+			Args:       map[string]string{"NODE_VERSION": "22"},
+		}, cfg.Runner.Build)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		cfg, err := gates.DetectStack(t.TempDir())
+		require.NoError(t, err)
+		assert.Nil(t, cfg.Runner.Build)
+	})
+}
+
+func writeGates(t *testing.T, content string) string {
+	t.Helper()
+	tmp := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tmp, ".monarch"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, ".monarch", "gates.yaml"), []byte(content), 0644))
+	return tmp
+}
+
+func TestDetectStack_CommandForms(t *testing.T) {
+	root := writeGates(t, `
+stack: go
+gates:
+  - name: unit
+    args: [go, test, -run, "TestA|TestB", ./...]
+    workdir: services/api
+    env:
+      CGO_ENABLED: "0"
+  - name: lint
+    command: golangci-lint run ./... 2>&1 | tee lint.txt
+    shell: true
+  - name: review
+    type: llm_eval
+    instruction: Check error handling
+`)
+
+	cfg, err := gates.DetectStack(root)
+	require.NoError(t, err)
+	require.Len(t, cfg.Gates, 3)
+	assert.Equal(t, []string{"go", "test", "-run", "TestA|TestB", "./..."}, cfg.Gates[0].Args)
+	assert.Equal(t, map[string]string{"CGO_ENABLED": "0"}, cfg.Gates[0].Env)
+	assert.True(t, cfg.Gates[1].Shell)
+}
+
+func TestDetectStack_InvalidGates(t *testing.T) {
+	tests := []struct {
+		name string
+		gate string
+		want string
+	}{
+		{"NoCommand", "name: test", "gate test: command or args is required"},
+		{"Both", "{name: test, command: go test, args: [go, test]}", "gate test: set either command or args, not both"},
+		{"ShellArgs", "{name: test, shell: true, args: [go, test]}", "gate test: shell runs command, not args"},
+		{"EmptyArg0", `{name: test, args: ["", test]}`, "gate test: args[0] must not be empty"},
+		{"Quote", `{name: test, command: "go test -run 'TestA"}`, "gate test: command: unterminated single quote"},
+		{"Workdir", "{name: test, command: make, workdir: ../other}", `gate test: workdir "../other" must be inside the project`},
+		{"AbsWorkdir", "{name: test, command: make, workdir: /etc}", `gate test: workdir "/etc" must be inside the project`},
+		{"Timeout", "{name: test, command: make, timeout: 5}", `gate test: timeout "5" must be a positive duration, such as 5m`},
+		{"NegativeTimeout", "{name: test, command: make, timeout: -1m}", `gate test: timeout "-1m" must be a positive duration, such as 5m`},
+		{"EnvName", "{name: test, command: make, env: {NODE-ENV: test}}", `gate test: env: invalid variable name "NODE-ENV"`},
+		{"EnvAndSecret", "{name: test, command: make, env: {TOKEN: x}, secrets: {TOKEN: token}}", "gate test: TOKEN is set in both env and secrets"},
+		{"Unnamed", `command: 'echo "hi'`, "gate #1: command: unterminated double quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeGates(t, "stack: go\ngates:\n  - "+tt.gate+"\n")
+
+			_, err := gates.DetectStack(root)
+			assert.ErrorContains(t, err, "invalid .monarch/gates.yaml")
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -2,14 +2,83 @@ package gates
 
 type Config struct {
 	Stack string `yaml:"stack"`
-	Gates []Gate `yaml:"gates"`
+	// Image overrides the runner image for Stack, e.g. "golang:1.22" or a
+	// digest reference.
+	Image     string    `yaml:"image"`
+	Runner    Runner    `yaml:"runner"`
+	Workspace Workspace `yaml:"workspace"`
+	Resources Resources `yaml:"resources"`
+	Gates     []Gate    `yaml:"gates"`
 }
 
+// Resources limits a runner. Unset fields inherit from the layer below:
+// built-in defaults < global config per stack < project < gate.
+type Resources struct {
+	Memory string  `yaml:"memory"` // e.g. "2g"
+	CPUs   float64 `yaml:"cpus"`
+	Pids   int64   `yaml:"pids"`
+	Tmpfs  string  `yaml:"tmpfs"` // size of the writable /tmp
+}
+
+// Merge returns r with the fields set in over replacing its own.
+func (r Resources) Merge(over Resources) Resources {
+	if over.Memory != "" {
+		r.Memory = over.Memory
+	}
+	if over.CPUs != 0 {
+		r.CPUs = over.CPUs
+	}
+	if over.Pids != 0 {
+		r.Pids = over.Pids
+	}
+	if over.Tmpfs != "" {
+		r.Tmpfs = over.Tmpfs
+	}
+	return r
+}
+
+// Runner customizes the runner image.
+type Runner struct {
+	// Build builds the runner image from a Dockerfile in the project; it
+	// wins over Image and the stack default.
+	Build *Build `yaml:"build"`
+}
+
+// Build describes a runner image built from the project. Paths are relative
+// to the project root.
+type Build struct {
+	Dockerfile string            `yaml:"dockerfile"` // defaults to RunnerDockerfile
+	Context    string            `yaml:"context"`    // defaults to the project root
+	Args       map[string]string `yaml:"args"`
+}
+
+// Workspace controls how the project root is mounted into runners.
+type Workspace struct {
+	// Writable layers a throwaway overlay over the read-only project root
+	// for tools that write caches or reports.
+	Writable bool `yaml:"writable"`
+}
+
+// Gate is one check run on an attempt. A standard gate runs either Args,
+// an argv array, or Command, a string split into arguments by SplitCommand
+// or, with Shell set, run by sh -c.
 type Gate struct {
-	Name        string `yaml:"name"`
-	Command     string `yaml:"command"` // For Standard gates
-	Tier        string `yaml:"tier"`    // A, B, C
-	Type        string `yaml:"type"`    // "standard" (default) or "llm_eval"
-	Instruction string `yaml:"instruction"` // For LLM gates
-	File        string `yaml:"file"`        // For LLM gates (target file)
-}
\ No newline at end of file
+	Name        string    `yaml:"name"`
+	Command     string    `yaml:"command"`     // For Standard gates
+	Args        []string  `yaml:"args"`        // Instead of Command, run without splitting or a shell
+	Shell       bool      `yaml:"shell"`       // Run Command with sh -c, for pipes, redirects and &&
+	Tier        string    `yaml:"tier"`        // A, B, C
+	Type        string    `yaml:"type"`        // "standard" (default) or "llm_eval"
+	Instruction string    `yaml:"instruction"` // For LLM gates
+	File        string    `yaml:"file"`        // For LLM gates (target file)
+	Workdir     string    `yaml:"workdir"`     // Relative to the project root, for monorepo subfolders
+	Resources   Resources `yaml:"resources"`   // Overrides the project's limits for this gate
+	Network     bool      `yaml:"network"`     // Runners have no network unless the gate needs it
+	Timeout     string    `yaml:"timeout"`     // e.g. "5m"; overrides the global gate timeout
+	// Env sets environment variables for the command.
+	Env map[string]string `yaml:"env"`
+	// Secrets sets environment variables to secrets from Monarch's secret
+	// store, by name, e.g. NPM_TOKEN: npm_token. Their values are redacted
+	// from the gate's output.
+	Secrets map[string]string `yaml:"secrets"`
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+
+	// StatusUnavailable is the overall status when any component fails.
+	StatusUnavailable = "unavailable"
+)
+
+// defaultTimeout bounds each component check so one hung dependency
+// can't stall the whole report.
+const defaultTimeout = 2 * time.Second
+
+// CheckFunc returns nil when the component is healthy.
+type CheckFunc func(ctx context.Context) error
+
+type Component struct {
+	Name  string
+	Check CheckFunc
+}
+
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Healthy reports whether every component passed.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+type Checker struct {
+	components []Component
+	timeout    time.Duration
+}
+
+func NewChecker(components ...Component) *Checker {
+	return &Checker{components: components, timeout: defaultTimeout}
+}
+
+// WithTimeout overrides the per-component check timeout.
+func (c *Checker) WithTimeout(d time.Duration) *Checker {
+	c.timeout = d
+	return c
+}
+
+// Run checks all components concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus)}
+	if c == nil {
+		return report
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, comp := range c.components {
+		wg.Add(1)
+		go func(comp Component) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := comp.Check(checkCtx)
+			status := ComponentStatus{
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = StatusError
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[comp.Name] = status
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(comp)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Pinger is satisfied by *pgxpool.Pool.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+func Database(db Pinger) Component {
+	return Component{Name: "database", Check: db.Ping}
+}
+
+// Querier is the subset of database.DBTX needed to inspect extensions.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func PGVector(db Querier) Component {
+	return Component{Name: "pgvector", Check: func(ctx context.Context) error {
+		var installed bool
+		err := db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')").Scan(&installed)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return errors.New("vector extension is not installed")
+		}
+		return nil
+	}}
+}
+
+// DockerPinger is satisfied by *client.Client.
+type DockerPinger interface {
+	Ping(ctx context.Context) (types.Ping, error)
+}
+
+func Docker(cli DockerPinger) Component {
+	return Component{Name: "docker", Check: func(ctx context.Context) error {
+		_, err := cli.Ping(ctx)
+		return err
+	}}
+}
+
+// LLM only verifies that a provider key is configured; calling the provider
+// on every probe would cost tokens.
+func LLM(provider, apiKey string) Component {
+	return Component{Name: "llm", Check: func(ctx context.Context) error {
+		if apiKey == "" {
+			return errors.New("no API key configured for " + provider)
+		}
+		return nil
+	}}
+}
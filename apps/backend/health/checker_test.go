@@ -0,0 +1,63 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/monarch-dev/monarch/health"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDocker struct {
+	err error
+}
+
+func (f *fakeDocker) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, f.err
+}
+
+func TestChecker_AllHealthy(t *testing.T) {
+	checker := health.NewChecker(
+		health.Docker(&fakeDocker{}),
+		health.LLM("gemini", "key"),
+	)
+
+	report := checker.Run(context.Background())
+
+	assert.True(t, report.Healthy())
+	assert.Equal(t, health.StatusOK, report.Components["docker"].Status)
+	assert.Equal(t, health.StatusOK, report.Components["llm"].Status)
+}
+
+func TestChecker_ReportsFailingComponent(t *testing.T) {
+	checker := health.NewChecker(
+		health.Docker(&fakeDocker{err: errors.New("cannot connect to the Docker daemon")}),
+		health.LLM("gemini", ""),
+	)
+
+	report := checker.Run(context.Background())
+
+	assert.False(t, report.Healthy())
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusError, report.Components["docker"].Status)
+	assert.Contains(t, report.Components["docker"].Error, "Docker daemon")
+	assert.Contains(t, report.Components["llm"].Error, "no API key configured for gemini")
+}
+
+func TestChecker_TimesOutHungCheck(t *testing.T) {
+	checker := health.NewChecker(health.Component{
+		Name: "hung",
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}).WithTimeout(10 * time.Millisecond)
+
+	report := checker.Run(context.Background())
+
+	assert.Equal(t, health.StatusError, report.Components["hung"].Status)
+	assert.Contains(t, report.Components["hung"].Error, "deadline exceeded")
+}
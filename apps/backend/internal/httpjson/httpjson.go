@@ -0,0 +1,19 @@
+// Package httpjson writes the JSON responses of Monarch's HTTP API.
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Write answers with status and v encoded as JSON.
+func Write(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error answers with status and {"error": msg}.
+func Error(w http.ResponseWriter, status int, msg string) {
+	Write(w, status, map[string]string{"error": msg})
+}
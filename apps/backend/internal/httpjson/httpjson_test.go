@@ -0,0 +1,28 @@
+package httpjson_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/monarch-dev/monarch/internal/httpjson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	httpjson.Write(w, http.StatusCreated, map[string]int{"n": 1})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"n":1}`, w.Body.String())
+}
+
+func TestError(t *testing.T) {
+	w := httptest.NewRecorder()
+	httpjson.Error(w, http.StatusNotFound, "project not found")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"project not found"}`, w.Body.String())
+}
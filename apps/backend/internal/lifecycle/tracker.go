@@ -0,0 +1,132 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned by Begin once Drain has been called.
+var ErrShuttingDown = errors.New("monarch is shutting down, retry after restart")
+
+// ErrInterrupted is the cancellation cause of operations still running when
+// the drain deadline expires.
+var ErrInterrupted = errors.New("interrupted by shutdown")
+
+// interruptGrace is how long interrupted operations get to unwind (and record
+// that they were interrupted) before Drain returns.
+const interruptGrace = 5 * time.Second
+
+// Tracker counts in-flight operations so shutdown can stop accepting new
+// work, wait for running work, and interrupt whatever misses the deadline.
+// A nil *Tracker is valid and tracks nothing.
+type Tracker struct {
+	mu       sync.Mutex
+	draining bool
+	nextID   uint64
+	active   map[uint64]string
+	wg       sync.WaitGroup
+
+	interrupt     context.Context
+	interruptFunc context.CancelCauseFunc
+}
+
+func NewTracker() *Tracker {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &Tracker{
+		active:        make(map[uint64]string),
+		interrupt:     ctx,
+		interruptFunc: cancel,
+	}
+}
+
+// Begin registers an operation described by name. The returned context is
+// cancelled with ErrInterrupted if the operation outlives the drain deadline.
+// end must be called when the operation finishes.
+func (t *Tracker) Begin(ctx context.Context, name string) (context.Context, func(), error) {
+	if t == nil {
+		return ctx, func() {}, nil
+	}
+
+	t.mu.Lock()
+	if t.draining {
+		t.mu.Unlock()
+		return ctx, func() {}, ErrShuttingDown
+	}
+	t.nextID++
+	id := t.nextID
+	t.active[id] = name
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	opCtx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(t.interrupt, func() {
+		cancel(ErrInterrupted)
+	})
+
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			stop()
+			cancel(nil)
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+			t.wg.Done()
+		})
+	}
+	return opCtx, end, nil
+}
+
+// Draining reports whether Drain has been called.
+func (t *Tracker) Draining() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// Drain rejects new operations and waits for in-flight ones until ctx is done.
+// Operations still running at that point are interrupted; their names are
+// returned in sorted order.
+func (t *Tracker) Drain(ctx context.Context) []string {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	interrupted := make([]string, 0, len(t.active))
+	for _, name := range t.active {
+		interrupted = append(interrupted, name)
+	}
+	t.mu.Unlock()
+	sort.Strings(interrupted)
+
+	t.interruptFunc(ErrInterrupted)
+
+	select {
+	case <-done:
+	case <-time.After(interruptGrace):
+	}
+	return interrupted
+}
@@ -0,0 +1,66 @@
+package lifecycle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/monarch-dev/monarch/internal/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_DrainWaitsForInFlight(t *testing.T) {
+	tr := lifecycle.NewTracker()
+
+	_, end, err := tr.Begin(context.Background(), "op")
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		end()
+	}()
+
+	interrupted := tr.Drain(context.Background())
+	assert.Empty(t, interrupted)
+}
+
+func TestTracker_RejectsAfterDrain(t *testing.T) {
+	tr := lifecycle.NewTracker()
+	tr.Drain(context.Background())
+
+	assert.True(t, tr.Draining())
+	_, _, err := tr.Begin(context.Background(), "late")
+	assert.ErrorIs(t, err, lifecycle.ErrShuttingDown)
+}
+
+func TestTracker_InterruptsAfterDeadline(t *testing.T) {
+	tr := lifecycle.NewTracker()
+
+	opCtx, end, err := tr.Begin(context.Background(), "slow gate")
+	require.NoError(t, err)
+
+	// The operation finishes as soon as it notices the interruption.
+	go func() {
+		<-opCtx.Done()
+		end()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	interrupted := tr.Drain(ctx)
+	assert.Equal(t, []string{"slow gate"}, interrupted)
+	assert.ErrorIs(t, context.Cause(opCtx), lifecycle.ErrInterrupted)
+}
+
+func TestTracker_NilIsNoop(t *testing.T) {
+	var tr *lifecycle.Tracker
+
+	ctx := context.Background()
+	opCtx, end, err := tr.Begin(ctx, "op")
+	require.NoError(t, err)
+	assert.Equal(t, ctx, opCtx)
+	end()
+	assert.Nil(t, tr.Drain(ctx))
+}
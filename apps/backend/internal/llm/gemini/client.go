@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
 	"github.com/monarch-dev/monarch/internal/llm"
+	"google.golang.org/api/option"
 )
 
 type Client struct {
@@ -13,13 +13,23 @@ type Client struct {
 	modelName   string
 }
 
+// DefaultModel is used when no model is configured.
+const DefaultModel = "gemini-pro"
+
 func NewClient(apiKey string) (llm.Client, error) {
+	return NewClientWithModel(apiKey, DefaultModel)
+}
+
+func NewClientWithModel(apiKey, model string) (llm.Client, error) {
+	if model == "" {
+		model = DefaultModel
+	}
 	ctx := context.Background()
 	c, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, err
 	}
-	return &Client{genaiClient: c, modelName: "gemini-pro"}, nil
+	return &Client{genaiClient: c, modelName: model}, nil
 }
 
 func (c *Client) Generate(ctx context.Context, prompt string) (string, error) {
@@ -28,11 +38,11 @@ func (c *Client) Generate(ctx context.Context, prompt string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	
+
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
 		return "", fmt.Errorf("no content generated")
 	}
-	
+
 	var output string
 	for _, part := range resp.Candidates[0].Content.Parts {
 		if txt, ok := part.(genai.Text); ok {
@@ -1,9 +1,9 @@
 package gemini_test
 
 import (
-	"testing"
-	"github.com/stretchr/testify/assert"
 	"github.com/monarch-dev/monarch/internal/llm/gemini"
+	"github.com/stretchr/testify/assert"
+	"testing"
 )
 
 func TestGeminiClient_Init(t *testing.T) {
@@ -11,3 +11,9 @@ func TestGeminiClient_Init(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 }
+
+func TestGeminiClient_InitWithModel(t *testing.T) {
+	c, err := gemini.NewClientWithModel("fake-key", "gemini-3-pro-preview")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+}
@@ -2,20 +2,56 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/monarch-dev/monarch/attempt"
 	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/internal/lifecycle"
 	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/snapshot"
+	"github.com/monarch-dev/monarch/task"
 )
 
 type Builder struct {
-	store  database.Querier
-	runner runner.Service
+	store     database.Querier
+	tasks     *task.Service
+	attempts  *attempt.Service
+	runner    runner.Service
+	tracker   *lifecycle.Tracker
+	snapshots *snapshot.Snapshotter
 }
 
-func NewBuilder(store database.Querier, runner runner.Service) *Builder {
-	return &Builder{store: store, runner: runner}
+// NewBuilder creates the Builder toolset. tracker may be nil when graceful
+// shutdown is not needed (e.g. in tests).
+func NewBuilder(store database.Querier, runner runner.Service, tracker *lifecycle.Tracker) *Builder {
+	return &Builder{
+		store:    store,
+		tasks:    task.NewService(store),
+		attempts: attempt.NewService(store),
+		runner:   runner,
+		tracker:  tracker,
+	}
+}
+
+// WithHub publishes live gate output to hub, shared with the API's attempt
+// service so the dashboard can follow it.
+func (b *Builder) WithHub(hub *attempt.Hub) *Builder {
+	b.attempts.WithHub(hub)
+	return b
+}
+
+// WithSnapshots runs each attempt's gates against a snapshot of the
+// workspace taken when it is submitted, rather than the live project dir.
+func (b *Builder) WithSnapshots(s *snapshot.Snapshotter) *Builder {
+	b.snapshots = s
+	return b
 }
 
 func (b *Builder) Register(s *mcp.Server) {
@@ -28,6 +64,11 @@ func (b *Builder) Register(s *mcp.Server) {
 		Name:        "submit_attempt",
 		Description: "Submit a task attempt for validation",
 	}, b.SubmitAttemptHandler)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name:        "get_attempt_history",
+		Description: "List a task's past attempts with each gate's result, output and findings",
+	}, b.AttemptHistoryHandler)
 }
 
 type TaskArgs struct {
@@ -35,16 +76,18 @@ type TaskArgs struct {
 }
 
 func (b *Builder) ClaimTaskHandler(ctx context.Context, req *mcp.CallToolRequest, args TaskArgs) (*mcp.CallToolResult, any, error) {
+	ctx, end, err := b.tracker.Begin(ctx, "claim_task "+args.TaskID)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	defer end()
+
 	uuid := pgtype.UUID{}
 	if err := uuid.Scan(args.TaskID); err != nil {
 		return errorResult("Invalid Task ID format"), nil, nil
 	}
 
-	err := b.store.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
-		ID:     uuid,
-		Status: "IN_PROGRESS",
-	})
-	if err != nil {
+	if _, err := b.tasks.Transition(ctx, uuid, task.StatusInProgress); err != nil {
 		return errorResult(err.Error()), nil, nil
 	}
 
@@ -52,37 +95,223 @@ func (b *Builder) ClaimTaskHandler(ctx context.Context, req *mcp.CallToolRequest
 }
 
 func (b *Builder) SubmitAttemptHandler(ctx context.Context, req *mcp.CallToolRequest, args TaskArgs) (*mcp.CallToolResult, any, error) {
+	runCtx, end, err := b.tracker.Begin(ctx, "submit_attempt "+args.TaskID)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	defer end()
+
 	uuid := pgtype.UUID{}
 	if err := uuid.Scan(args.TaskID); err != nil {
 		return errorResult("Invalid Task ID format"), nil, nil
 	}
 
-	task, err := b.store.GetTask(ctx, uuid)
+	t, err := b.tasks.Get(ctx, uuid)
 	if err != nil {
 		return errorResult(err.Error()), nil, nil
 	}
 
-	if task.AttemptCount >= 5 {
-		_ = b.store.UpdateTaskStatus(ctx, database.UpdateTaskStatusParams{
-			ID:     uuid,
-			Status: "BLOCKED",
-		})
+	if t.AttemptCount >= 5 {
+		if _, err := b.tasks.Transition(ctx, uuid, task.StatusBlocked); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
 		return errorResult("Task Blocked. Human intervention required."), nil, nil
 	}
 
+	// Checked before VALIDATING, which nothing would move the task out of.
+	if b.runner == nil {
+		return errorResult("Validation is unavailable: no gate runner is configured"), nil, nil
+	}
+
+	if _, err := b.tasks.Transition(ctx, uuid, task.StatusValidating); err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+
 	// Increment attempts
-	_, err = b.store.IncrementTaskAttempt(ctx, uuid)
+	number, err := b.store.IncrementTaskAttempt(ctx, uuid)
 	if err != nil {
+		b.reopen(ctx, uuid)
 		return errorResult("Failed to increment attempts"), nil, nil
 	}
 
-	// Trigger Runner (Simplified - just checking if it exists)
-	if b.runner != nil {
-		// Mock execution
-		// In reality: b.runner.Execute(ctx, task.ProjectID.String(), []string{"go", "test", "./..."})
+	proj, err := b.store.GetProjectByID(ctx, t.ProjectID)
+	if err != nil {
+		b.reopen(ctx, uuid)
+		return errorResult(err.Error()), nil, nil
+	}
+
+	cfg, err := gates.DetectStack(proj.Path)
+	if err != nil {
+		b.reopen(ctx, uuid)
+		return errorResult(fmt.Sprintf("Failed to load gates: %v", err)), nil, nil
 	}
 
-	return successResult("Validation triggered"), nil, nil
+	run, err := b.attempts.Start(ctx, uuid, number)
+	if err != nil {
+		b.reopen(ctx, uuid)
+		return errorResult(fmt.Sprintf("Failed to record attempt: %v", err)), nil, nil
+	}
+	// History is written even if the request is cancelled mid-gate.
+	recordCtx := context.WithoutCancel(ctx)
+
+	target := runner.Project{
+		ID:        proj.ID.String(),
+		Stack:     cfg.Stack,
+		Image:     cfg.Image,
+		Path:      proj.Path,
+		Writable:  cfg.Workspace.Writable,
+		Resources: cfg.Resources,
+		Build:     cfg.Runner.Build,
+	}
+	if b.snapshots != nil {
+		snap, err := b.snapshots.Take(ctx, target.ID, proj.Path, run.ID.String())
+		if err != nil {
+			_ = b.store.RefundTaskAttempt(recordCtx, uuid)
+			_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusFailed)
+			b.reopen(ctx, uuid)
+			return errorResult(fmt.Sprintf("Failed to snapshot the workspace: %v. The attempt was not counted; resubmit once the problem is fixed.", err)), nil, nil
+		}
+		defer func() { _ = b.snapshots.Remove(recordCtx, snap) }()
+		_ = b.attempts.SetSnapshot(recordCtx, run.ID, snap.Commit, snap.DiffHash)
+		target.Snapshots = b.snapshots.Root(target.ID)
+		target.Snapshot = snap.Name
+		target.Revision = snap.Commit + ":" + snap.DiffHash
+	}
+	progress := b.progress(ctx, req)
+	// Queued gates are dropped if the task is abandoned meanwhile.
+	runCtx = runner.WithJobOwner(runCtx, uuid.String())
+	for _, gate := range cfg.Gates {
+		log := b.attempts.OpenLog(recordCtx, run.ID, gate.Name)
+		gateCtx := runner.WithLineSink(runCtx, func(l runner.Line) {
+			log.Write(l)
+			progress(gate.Name, l.Text)
+		})
+		gateCtx = runner.WithQueueObserver(gateCtx, func(position int) {
+			if position > 0 {
+				progress(gate.Name, fmt.Sprintf("queued at position %d", position))
+			}
+		})
+		res := b.runner.RunGate(gateCtx, target, gate)
+		_ = log.Close()
+		if !res.Passed() && errors.Is(context.Cause(runCtx), lifecycle.ErrInterrupted) {
+			// Don't count this against the circuit breaker; the agent did nothing wrong.
+			_ = b.store.MarkTaskInterrupted(recordCtx, uuid)
+			_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusInterrupted)
+			b.reopen(ctx, uuid)
+			return errorResult("Validation interrupted by Monarch shutdown. The attempt was not counted; resubmit after restart."), nil, nil
+		}
+
+		_ = b.attempts.RecordGate(recordCtx, run.ID, res)
+		switch res.Outcome {
+		case runner.OutcomePass:
+			continue
+		case runner.OutcomeSystemError:
+			// Fail closed, but the broken infrastructure isn't the agent's mistake.
+			_ = b.store.RefundTaskAttempt(recordCtx, uuid)
+			_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusFailed)
+			b.reopen(ctx, uuid)
+			return errorResult(fmt.Sprintf("Gate %s could not run (system error): %v. The attempt was not counted; resubmit once the problem is fixed.", gate.Name, res.Cause)), nil, nil
+		case runner.OutcomeTimeout:
+			_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusFailed)
+			b.reopen(ctx, uuid)
+			return errorResult(timeoutMessage(res)), nil, nil
+		default:
+			_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusFailed)
+			b.reopen(ctx, uuid)
+			return errorResult(failureMessage(res)), nil, nil
+		}
+	}
+	_ = b.attempts.Finish(recordCtx, run.ID, attempt.StatusPassed)
+
+	if _, err := b.tasks.Transition(ctx, uuid, task.StatusDone); err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+
+	return successResult("All gates passed"), nil, nil
+}
+
+func (b *Builder) AttemptHistoryHandler(ctx context.Context, req *mcp.CallToolRequest, args TaskArgs) (*mcp.CallToolResult, any, error) {
+	uuid := pgtype.UUID{}
+	if err := uuid.Scan(args.TaskID); err != nil {
+		return errorResult("Invalid Task ID format"), nil, nil
+	}
+
+	attempts, err := b.attempts.List(ctx, uuid)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+
+	data, err := json.MarshalIndent(attempts, "", "  ")
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to marshal attempts: %v", err)), nil, nil
+	}
+	return successResult(string(data)), nil, nil
+}
+
+// progress returns a func that forwards gate output and queue updates to
+// the client as progress notifications, if it asked for them with a
+// progress token.
+func (b *Builder) progress(ctx context.Context, req *mcp.CallToolRequest) func(gate, msg string) {
+	if req == nil || req.Session == nil || req.Params == nil || req.Params.GetProgressToken() == nil {
+		return func(string, string) {}
+	}
+	token := req.Params.GetProgressToken()
+	var (
+		mu sync.Mutex
+		n  float64
+	)
+	return func(gate, msg string) {
+		// Queue updates arrive from the scheduler, not the output copy.
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      n,
+			Message:       fmt.Sprintf("[%s] %s", gate, msg),
+		})
+	}
+}
+
+// failureMessage explains a validation failure to the agent, listing the
+// parsed findings when there are any.
+func failureMessage(res runner.GateResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Gate %s failed", res.Gate)
+	if res.ExitCode != nil {
+		fmt.Fprintf(&sb, " (exit %d)", *res.ExitCode)
+	}
+	if len(res.Findings) == 0 {
+		fmt.Fprintf(&sb, ": %s", res.Stderr)
+		return sb.String()
+	}
+	sb.WriteString(":")
+	for _, f := range res.Findings {
+		fmt.Fprintf(&sb, "\n- [%s] %s:%d %s", f.Severity, f.File, f.Line, f.Message)
+		if f.Hint != "" {
+			fmt.Fprintf(&sb, " (hint: %s)", f.Hint)
+		}
+	}
+	return sb.String()
+}
+
+// timeoutOutputBytes caps the partial output quoted back after a timeout.
+const timeoutOutputBytes = 4 << 10
+
+// timeoutMessage tells the agent its gate was killed, with the tail of what
+// it printed before that, which usually shows where it hung.
+func timeoutMessage(res runner.GateResult) string {
+	output := res.Stdout + res.Stderr
+	if len(output) > timeoutOutputBytes {
+		output = "..." + output[len(output)-timeoutOutputBytes:]
+	}
+	return fmt.Sprintf("Gate %s was killed: %v. Look for hanging tests, deadlocks or infinite loops. Output before the timeout:\n%s", res.Gate, res.Cause, output)
+}
+
+// reopen hands a task that failed validation back to the agent. It runs
+// detached from ctx so a cancelled request doesn't strand it in VALIDATING.
+func (b *Builder) reopen(ctx context.Context, id pgtype.UUID) {
+	_, _ = b.tasks.Transition(context.WithoutCancel(ctx), id, task.StatusInProgress)
 }
 
 func errorResult(msg string) *mcp.CallToolResult {
@@ -3,18 +3,119 @@ package tools_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/internal/lifecycle"
 	"github.com/monarch-dev/monarch/mcp/tools"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/runner/parser"
+	"github.com/monarch-dev/monarch/snapshot"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// BlockingRunner implements runner.Service and blocks every gate until its
+// context is cancelled.
+type BlockingRunner struct {
+	started chan struct{}
+}
+
+func (r *BlockingRunner) Execute(ctx context.Context, project runner.Project, cmd []string) (string, error) {
+	return "", nil
+}
+
+func (r *BlockingRunner) RunGate(ctx context.Context, project runner.Project, gate gates.Gate) runner.GateResult {
+	close(r.started)
+	<-ctx.Done()
+	return runner.GateResult{Gate: gate.Name, Outcome: runner.OutcomeSystemError, Cause: ctx.Err()}
+}
+
+func projectWithGate(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".monarch"), 0755))
+	err := os.WriteFile(filepath.Join(dir, ".monarch", "gates.yaml"), []byte(`
+stack: go
+gates:
+  - name: test
+    command: go test ./...
+`), 0644)
+	require.NoError(t, err)
+	return dir
+}
+
+// StaticRunner implements runner.Service and returns res for every gate.
+type StaticRunner struct {
+	res runner.GateResult
+}
+
+func (r *StaticRunner) Execute(ctx context.Context, project runner.Project, cmd []string) (string, error) {
+	return "", nil
+}
+
+func (r *StaticRunner) RunGate(ctx context.Context, project runner.Project, gate gates.Gate) runner.GateResult {
+	res := r.res
+	res.Gate = gate.Name
+	return res
+}
+
 type MockQuerier struct {
 	database.Querier
-	Task *database.Task
+	Task        *database.Task
+	Project     *database.Project
+	Interrupted bool
+	GateResults []database.CreateGateResultParams
+	Finished    string
+	Refunded    bool
+	Snapshot    *database.SetAttemptSnapshotParams
+}
+
+func (m *MockQuerier) SetAttemptSnapshot(ctx context.Context, arg database.SetAttemptSnapshotParams) (database.Attempt, error) {
+	m.Snapshot = &arg
+	return database.Attempt{ID: arg.ID}, nil
+}
+
+func (m *MockQuerier) RefundTaskAttempt(ctx context.Context, id pgtype.UUID) error {
+	m.Refunded = true
+	return nil
+}
+
+func (m *MockQuerier) CreateAttempt(ctx context.Context, arg database.CreateAttemptParams) (database.Attempt, error) {
+	return database.Attempt{ID: pgtype.UUID{Bytes: [16]byte{2}, Valid: true}, TaskID: arg.TaskID, Number: arg.Number}, nil
+}
+
+func (m *MockQuerier) CreateGateResult(ctx context.Context, arg database.CreateGateResultParams) (database.GateResult, error) {
+	m.GateResults = append(m.GateResults, arg)
+	return database.GateResult{AttemptID: arg.AttemptID}, nil
+}
+
+func (m *MockQuerier) CreateGateFinding(ctx context.Context, arg database.CreateGateFindingParams) error {
+	return nil
+}
+
+func (m *MockQuerier) FinishAttempt(ctx context.Context, arg database.FinishAttemptParams) (database.Attempt, error) {
+	m.Finished = arg.Status
+	return database.Attempt{ID: arg.ID, Status: arg.Status}, nil
+}
+
+func (m *MockQuerier) GetProjectByID(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	if m.Project != nil {
+		return *m.Project, nil
+	}
+	return database.Project{}, errors.New("not found")
+}
+
+func (m *MockQuerier) MarkTaskInterrupted(ctx context.Context, id pgtype.UUID) error {
+	m.Interrupted = true
+	return nil
 }
 
 func (m *MockQuerier) GetTask(ctx context.Context, id pgtype.UUID) (database.Task, error) {
@@ -24,8 +125,12 @@ func (m *MockQuerier) GetTask(ctx context.Context, id pgtype.UUID) (database.Tas
 	return database.Task{}, errors.New("not found")
 }
 
-func (m *MockQuerier) UpdateTaskStatus(ctx context.Context, arg database.UpdateTaskStatusParams) error {
-	return nil
+func (m *MockQuerier) TransitionTaskStatus(ctx context.Context, arg database.TransitionTaskStatusParams) (database.Task, error) {
+	if m.Task == nil || m.Task.Status != arg.FromStatus {
+		return database.Task{}, pgx.ErrNoRows
+	}
+	m.Task.Status = arg.ToStatus
+	return *m.Task, nil
 }
 
 func (m *MockQuerier) IncrementTaskAttempt(ctx context.Context, id pgtype.UUID) (int32, error) {
@@ -36,11 +141,12 @@ func TestBuilder_Submit_CircuitBreaker(t *testing.T) {
 	// Setup task with 5 attempts
 	task := &database.Task{
 		ID:           pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Status:       "IN_PROGRESS",
 		AttemptCount: 5,
 	}
 	mockDB := &MockQuerier{Task: task}
 
-	builder := tools.NewBuilder(mockDB, nil)
+	builder := tools.NewBuilder(mockDB, nil, nil)
 
 	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
 	result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
@@ -48,4 +154,222 @@ func TestBuilder_Submit_CircuitBreaker(t *testing.T) {
 	assert.NoError(t, err) // Handler returns error in result, not as return value usually
 	assert.True(t, result.IsError)
 	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Task Blocked")
+	assert.Equal(t, "BLOCKED", task.Status)
+}
+
+func TestBuilder_Submit_WithoutRunnerLeavesTaskInProgress(t *testing.T) {
+	task := &database.Task{
+		ID:     pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Status: "IN_PROGRESS",
+	}
+	mockDB := &MockQuerier{Task: task}
+	builder := tools.NewBuilder(mockDB, nil, nil)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "no gate runner")
+	assert.Equal(t, "IN_PROGRESS", task.Status)
+	assert.Equal(t, int32(0), task.AttemptCount)
+}
+
+func TestBuilder_Claim_RejectsIllegalTransition(t *testing.T) {
+	mockDB := &MockQuerier{Task: &database.Task{
+		ID:     pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Status: "DONE",
+	}}
+	builder := tools.NewBuilder(mockDB, nil, nil)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.ClaimTaskHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "DONE -> IN_PROGRESS")
+	assert.Equal(t, "DONE", mockDB.Task.Status)
+}
+
+func TestBuilder_Claim(t *testing.T) {
+	mockDB := &MockQuerier{Task: &database.Task{
+		ID:     pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Status: "BACKLOG",
+	}}
+	builder := tools.NewBuilder(mockDB, nil, nil)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.ClaimTaskHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "IN_PROGRESS", mockDB.Task.Status)
+}
+
+func TestBuilder_Submit_RejectedWhileDraining(t *testing.T) {
+	tracker := lifecycle.NewTracker()
+	tracker.Drain(context.Background())
+
+	builder := tools.NewBuilder(&MockQuerier{}, nil, tracker)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "shutting down")
+}
+
+func TestBuilder_Submit_InterruptedByShutdown(t *testing.T) {
+	mockDB := &MockQuerier{
+		Task:    &database.Task{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Status: "IN_PROGRESS"},
+		Project: &database.Project{Path: projectWithGate(t)},
+	}
+	runner := &BlockingRunner{started: make(chan struct{})}
+	tracker := lifecycle.NewTracker()
+	builder := tools.NewBuilder(mockDB, runner, tracker)
+
+	done := make(chan *mcp.CallToolResult)
+	go func() {
+		args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+		result, _, _ := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+		done <- result
+	}()
+	<-runner.started
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	interrupted := tracker.Drain(drainCtx)
+
+	result := <-done
+	assert.Equal(t, []string{"submit_attempt 00000000-0000-0000-0000-000000000001"}, interrupted)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "interrupted")
+	assert.True(t, mockDB.Interrupted)
+	assert.Equal(t, "IN_PROGRESS", mockDB.Task.Status)
+	assert.Equal(t, "INTERRUPTED", mockDB.Finished)
+	assert.Empty(t, mockDB.GateResults, "an interrupted gate has no result")
+}
+
+func TestBuilder_Submit_RecordsGateResults(t *testing.T) {
+	exit := 2
+	tests := []struct {
+		name         string
+		res          runner.GateResult
+		wantStatus   string
+		wantExit     pgtype.Int4
+		wantRefunded bool
+		wantMessage  string
+	}{
+		{
+			name: "ValidationFailure",
+			res: runner.GateResult{
+				Outcome:  runner.OutcomeValidationFailure,
+				ExitCode: &exit,
+				Findings: []parser.LogEntry{{Severity: parser.SeverityError, File: "main.go", Line: 7, Message: "TestLogin failed"}},
+			},
+			wantStatus:  "VALIDATION_FAILURE",
+			wantExit:    pgtype.Int4{Int32: 2, Valid: true},
+			wantMessage: "main.go:7 TestLogin failed",
+		},
+		{
+			name:         "SystemError",
+			res:          runner.GateResult{Outcome: runner.OutcomeSystemError, Cause: errors.New("docker unreachable")},
+			wantStatus:   "SYSTEM_ERROR",
+			wantRefunded: true,
+			wantMessage:  "not counted",
+		},
+		{
+			name: "Timeout",
+			res: runner.GateResult{
+				Outcome: runner.OutcomeTimeout,
+				Stdout:  "=== RUN TestHang",
+				Cause:   fmt.Errorf("%w after 5m0s", runner.ErrTimeout),
+			},
+			wantStatus:  "TIMEOUT",
+			wantMessage: "gate timed out after 5m0s. Look for hanging tests, deadlocks or infinite loops. Output before the timeout:\n=== RUN TestHang",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockQuerier{
+				Task:    &database.Task{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Status: "IN_PROGRESS"},
+				Project: &database.Project{Path: projectWithGate(t)},
+			}
+			builder := tools.NewBuilder(mockDB, &StaticRunner{res: tt.res}, nil)
+
+			args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+			result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+			assert.NoError(t, err)
+			assert.True(t, result.IsError)
+			assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, tt.wantMessage)
+			require.Len(t, mockDB.GateResults, 1)
+			assert.Equal(t, "test", mockDB.GateResults[0].GateName)
+			assert.Equal(t, tt.wantStatus, mockDB.GateResults[0].Status)
+			assert.Equal(t, tt.wantExit, mockDB.GateResults[0].ExitCode)
+			assert.Equal(t, tt.wantRefunded, mockDB.Refunded)
+			assert.Equal(t, "FAILED", mockDB.Finished)
+			assert.Equal(t, "IN_PROGRESS", mockDB.Task.Status)
+		})
+	}
+}
+
+func TestBuilder_Submit_AllGatesPass(t *testing.T) {
+	mockDB := &MockQuerier{
+		Task:    &database.Task{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Status: "IN_PROGRESS"},
+		Project: &database.Project{Path: projectWithGate(t)},
+	}
+	builder := tools.NewBuilder(mockDB, &StaticRunner{res: runner.GateResult{Outcome: runner.OutcomePass}}, nil)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "PASSED", mockDB.Finished)
+	assert.Equal(t, "DONE", mockDB.Task.Status)
+}
+
+// SnapshotRunner implements runner.Service and records the project each
+// gate ran against, checking its snapshot is in place.
+type SnapshotRunner struct {
+	t       *testing.T
+	project runner.Project
+}
+
+func (r *SnapshotRunner) Execute(ctx context.Context, project runner.Project, cmd []string) (string, error) {
+	return "", nil
+}
+
+func (r *SnapshotRunner) RunGate(ctx context.Context, project runner.Project, gate gates.Gate) runner.GateResult {
+	r.project = project
+	assert.FileExists(r.t, filepath.Join(project.Snapshots, project.Snapshot, ".monarch", "gates.yaml"))
+	return runner.GateResult{Gate: gate.Name, Outcome: runner.OutcomePass}
+}
+
+func TestBuilder_Submit_RunsGatesAgainstSnapshot(t *testing.T) {
+	mockDB := &MockQuerier{
+		Task:    &database.Task{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Status: "IN_PROGRESS"},
+		Project: &database.Project{ID: pgtype.UUID{Bytes: [16]byte{3}, Valid: true}, Path: projectWithGate(t)},
+	}
+	run := &SnapshotRunner{t: t}
+	snapshots := snapshot.NewSnapshotter(t.TempDir())
+	builder := tools.NewBuilder(mockDB, run, nil).WithSnapshots(snapshots)
+
+	args := tools.TaskArgs{TaskID: "00000000-0000-0000-0000-000000000001"}
+	result, _, err := builder.SubmitAttemptHandler(ctx(), &mcp.CallToolRequest{}, args)
+
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, snapshots.Root(mockDB.Project.ID.String()), run.project.Snapshots)
+	assert.Equal(t, "02000000-0000-0000-0000-000000000000", run.project.Snapshot)
+
+	// Outside git the whole tree is hashed.
+	require.NotNil(t, mockDB.Snapshot)
+	assert.Empty(t, mockDB.Snapshot.SnapshotCommit)
+	assert.Len(t, mockDB.Snapshot.SnapshotDiffHash, 64)
+
+	assert.NoDirExists(t, filepath.Join(run.project.Snapshots, run.project.Snapshot), "the snapshot is removed afterwards")
 }
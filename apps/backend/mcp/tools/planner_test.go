@@ -13,7 +13,9 @@ import (
 )
 
 // MockStore for Project Service
-type MockProjectStore struct{}
+type MockProjectStore struct {
+	project.Store
+}
 
 func (m *MockProjectStore) Create(ctx context.Context, path string) (database.Project, error) {
 	return database.Project{}, nil
@@ -2,7 +2,12 @@ package project
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/internal/httpjson"
 )
 
 type RegisterRequest struct {
@@ -11,27 +16,103 @@ type RegisterRequest struct {
 
 func (s *Service) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httpjson.Error(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpjson.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, "Path is required", http.StatusBadRequest)
+		httpjson.Error(w, http.StatusBadRequest, "Path is required")
 		return
 	}
 
 	proj, err := s.Register(r.Context(), req.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, proj)
+}
+
+func (s *Service) ListHandler(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.List(r.Context())
+	if err != nil {
+		httpjson.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if projects == nil {
+		projects = []database.Project{}
+	}
+
+	httpjson.Write(w, http.StatusOK, projects)
+}
+
+func (s *Service) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	proj, err := s.Get(r.Context(), id)
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, proj)
+}
+
+func (s *Service) RescanHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	proj, err := s.Rescan(r.Context(), id)
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, proj)
+}
+
+func (s *Service) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(proj)
+	if err := s.Delete(r.Context(), id); err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (pgtype.UUID, bool) {
+	var id pgtype.UUID
+	if err := id.Scan(r.PathValue("id")); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, "Invalid project ID")
+		return id, false
+	}
+	return id, true
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPathNotExists):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
 }
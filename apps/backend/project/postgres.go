@@ -3,6 +3,7 @@ package project
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/monarch-dev/monarch/database"
 )
@@ -22,3 +23,15 @@ func (s *PostgresStore) Create(ctx context.Context, path string) (database.Proje
 func (s *PostgresStore) List(ctx context.Context) ([]database.Project, error) {
 	return s.q.ListProjects(ctx)
 }
+
+func (s *PostgresStore) Get(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	return s.q.GetProjectByID(ctx, id)
+}
+
+func (s *PostgresStore) SaveGateConfig(ctx context.Context, id pgtype.UUID, config []byte) (database.Project, error) {
+	return s.q.UpdateProjectGateConfig(ctx, database.UpdateProjectGateConfigParams{ID: id, GateConfig: config})
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, id pgtype.UUID) (int64, error) {
+	return s.q.DeleteProject(ctx, id)
+}
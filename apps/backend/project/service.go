@@ -2,37 +2,52 @@ package project
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/monarch-dev/monarch/database"
 	"github.com/monarch-dev/monarch/gates"
 )
 
+var (
+	ErrNotFound      = errors.New("project not found")
+	ErrPathNotExists = errors.New("path does not exist")
+)
+
 type Project struct {
 	*database.Project
 	HasGit bool          `json:"has_git"`
 	Config *gates.Config `json:"config"`
 }
 
+// Runners stops a project's warm runners; runner.Manager implements it.
+type Runners interface {
+	StopProject(ctx context.Context, projectID string) (int, error)
+}
+
 type Service struct {
-	store Store
+	store   Store
+	runners Runners
 }
 
 func NewService(store Store) *Service {
 	return &Service{store: store}
 }
 
+// WithRunners lets Delete stop the project's warm runners.
+func (s *Service) WithRunners(r Runners) *Service {
+	s.runners = r
+	return s
+}
+
 func (s *Service) Register(ctx context.Context, path string) (*Project, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, errors.New("path does not exist")
-	}
-
-	// Check Git
-	hasGit := false
-	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
-		hasGit = true
+		return nil, ErrPathNotExists
 	}
 
 	// Detect Gates
@@ -47,13 +62,102 @@ func (s *Service) Register(ctx context.Context, path string) (*Project, error) {
 		return nil, err
 	}
 
+	return s.saveConfig(ctx, dbProj.ID, config)
+}
+
+func (s *Service) List(ctx context.Context) ([]database.Project, error) {
+	return s.store.List(ctx)
+}
+
+// Get returns the project with its live git status and the gate config from
+// the last scan.
+func (s *Service) Get(ctx context.Context, id pgtype.UUID) (*Project, error) {
+	dbProj, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var config *gates.Config
+	if dbProj.GateConfig != nil {
+		if err := json.Unmarshal(dbProj.GateConfig, &config); err != nil {
+			return nil, fmt.Errorf("failed to decode gate config: %w", err)
+		}
+	}
+
 	return &Project{
 		Project: &dbProj,
-		HasGit:  hasGit,
+		HasGit:  hasGit(dbProj.Path),
 		Config:  config,
 	}, nil
 }
 
-func (s *Service) List(ctx context.Context) ([]database.Project, error) {
-	return s.store.List(ctx)
+// Rescan re-runs stack detection and stores the result.
+func (s *Service) Rescan(ctx context.Context, id pgtype.UUID) (*Project, error) {
+	dbProj, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dbProj.Path); os.IsNotExist(err) {
+		return nil, ErrPathNotExists
+	}
+
+	config, err := gates.DetectStack(dbProj.Path)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveConfig(ctx, id, config)
+}
+
+// Delete stops the project's warm runners, then removes the project and its
+// tasks.
+func (s *Service) Delete(ctx context.Context, id pgtype.UUID) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+
+	if s.runners != nil {
+		if _, err := s.runners.StopProject(ctx, id.String()); err != nil {
+			return fmt.Errorf("failed to stop runners: %w", err)
+		}
+	}
+
+	n, err := s.store.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) get(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	dbProj, err := s.store.Get(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dbProj, ErrNotFound
+	}
+	return dbProj, err
+}
+
+func (s *Service) saveConfig(ctx context.Context, id pgtype.UUID, config *gates.Config) (*Project, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dbProj, err := s.store.SaveGateConfig(ctx, id, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Project{
+		Project: &dbProj,
+		HasGit:  hasGit(dbProj.Path),
+		Config:  config,
+	}, nil
+}
+
+func hasGit(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil
 }
@@ -2,8 +2,11 @@ package project_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/monarch-dev/monarch/database"
 	"github.com/monarch-dev/monarch/project"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +33,69 @@ func (m *MockStore) List(ctx context.Context) ([]database.Project, error) {
 	return []database.Project{m.proj}, nil
 }
 
+func (m *MockStore) Get(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(database.Project), args.Error(1)
+}
+
+func (m *MockStore) SaveGateConfig(ctx context.Context, id pgtype.UUID, config []byte) (database.Project, error) {
+	args := m.Called(ctx, id, config)
+	return args.Get(0).(database.Project), args.Error(1)
+}
+
+func (m *MockStore) Delete(ctx context.Context, id pgtype.UUID) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockRunners struct {
+	mock.Mock
+}
+
+func (m *MockRunners) StopProject(ctx context.Context, projectID string) (int, error) {
+	args := m.Called(ctx, projectID)
+	return args.Int(0), args.Error(1)
+}
+
+func TestDelete_StopsRunners(t *testing.T) {
+	ctx := context.Background()
+	id := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	store := &MockStore{}
+	runners := &MockRunners{}
+	svc := project.NewService(store).WithRunners(runners)
+
+	store.On("Get", ctx, id).Return(database.Project{ID: id}, nil)
+	runners.On("StopProject", ctx, id.String()).Return(2, nil)
+	store.On("Delete", ctx, id).Return(int64(1), nil)
+
+	assert.NoError(t, svc.Delete(ctx, id))
+	store.AssertExpectations(t)
+	runners.AssertExpectations(t)
+}
+
+func TestDelete_KeepsProjectIfRunnersFailToStop(t *testing.T) {
+	ctx := context.Background()
+	id := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	store := &MockStore{}
+	runners := &MockRunners{}
+	svc := project.NewService(store).WithRunners(runners)
+
+	store.On("Get", ctx, id).Return(database.Project{ID: id}, nil)
+	runners.On("StopProject", ctx, id.String()).Return(0, errors.New("docker down"))
+
+	assert.ErrorContains(t, svc.Delete(ctx, id), "docker down")
+	store.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestGet_NotFound(t *testing.T) {
+	ctx := context.Background()
+	store := &MockStore{}
+	store.On("Get", ctx, mock.Anything).Return(database.Project{}, pgx.ErrNoRows)
+
+	_, err := project.NewService(store).Get(ctx, pgtype.UUID{Valid: true})
+	assert.ErrorIs(t, err, project.ErrNotFound)
+}
+
 func TestRegister_InvalidPath(t *testing.T) {
 	svc := project.NewService(&MockStore{})
 	_, err := svc.Register(context.Background(), "/invalid/path/123")
@@ -0,0 +1,38 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/sqlite"
+)
+
+type SQLiteStore struct {
+	q *sqlite.Queries
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{q: sqlite.New(db)}
+}
+
+func (s *SQLiteStore) Create(ctx context.Context, path string) (database.Project, error) {
+	return s.q.CreateProject(ctx, path)
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]database.Project, error) {
+	return s.q.ListProjects(ctx)
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id pgtype.UUID) (database.Project, error) {
+	return s.q.GetProjectByID(ctx, id)
+}
+
+func (s *SQLiteStore) SaveGateConfig(ctx context.Context, id pgtype.UUID, config []byte) (database.Project, error) {
+	return s.q.UpdateProjectGateConfig(ctx, database.UpdateProjectGateConfigParams{ID: id, GateConfig: config})
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id pgtype.UUID) (int64, error) {
+	return s.q.DeleteProject(ctx, id)
+}
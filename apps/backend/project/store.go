@@ -3,10 +3,16 @@ package project
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/monarch-dev/monarch/database"
 )
 
 type Store interface {
 	Create(ctx context.Context, path string) (database.Project, error)
 	List(ctx context.Context) ([]database.Project, error)
+	// Get returns pgx.ErrNoRows if the project does not exist.
+	Get(ctx context.Context, id pgtype.UUID) (database.Project, error)
+	SaveGateConfig(ctx context.Context, id pgtype.UUID, config []byte) (database.Project, error)
+	// Delete reports how many projects were removed (0 or 1).
+	Delete(ctx context.Context, id pgtype.UUID) (int64, error)
 }
@@ -0,0 +1,82 @@
+package project_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/monarch-dev/monarch/project"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stores(t *testing.T) map[string]project.Store {
+	stores := map[string]project.Store{
+		"sqlite": project.NewSQLiteStore(dbtest.SQLite(t)),
+	}
+	if pool := dbtest.PostgresIfConfigured(t); pool != nil {
+		stores["postgres"] = project.NewPostgresStore(pool)
+	}
+	return stores
+}
+
+func TestStore_RegisterAndList(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			svc := project.NewService(store)
+			dir := t.TempDir()
+
+			proj, err := svc.Register(context.Background(), dir)
+			require.NoError(t, err)
+			assert.Equal(t, dir, proj.Path)
+
+			projects, err := svc.List(context.Background())
+			require.NoError(t, err)
+
+			var paths []string
+			for _, p := range projects {
+				paths = append(paths, p.Path)
+			}
+			assert.Contains(t, paths, dir)
+		})
+	}
+}
+
+func TestStore_RescanAndDelete(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := project.NewService(store)
+			dir := t.TempDir()
+
+			proj, err := svc.Register(ctx, dir)
+			require.NoError(t, err)
+			assert.Equal(t, "unknown", proj.Config.Stack)
+
+			// The stored config is stale until a rescan.
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644))
+			require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+
+			got, err := svc.Get(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "unknown", got.Config.Stack)
+			assert.True(t, got.HasGit, "git status is live")
+
+			rescanned, err := svc.Rescan(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "go", rescanned.Config.Stack)
+			assert.True(t, rescanned.ScannedAt.Valid)
+
+			got, err = svc.Get(ctx, proj.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "go", got.Config.Stack)
+
+			require.NoError(t, svc.Delete(ctx, proj.ID))
+			_, err = svc.Get(ctx, proj.ID)
+			assert.ErrorIs(t, err, project.ErrNotFound)
+			assert.ErrorIs(t, svc.Delete(ctx, proj.ID), project.ErrNotFound)
+		})
+	}
+}
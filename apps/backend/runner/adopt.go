@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ImageCheck reports whether image is still the one a runner for the
+// project's stack would be started from. build is the BuildKey the image was
+// built with, or "" if it wasn't built. It returns false for projects that
+// no longer exist.
+type ImageCheck func(ctx context.Context, projectID, stack, image, build string) bool
+
+// Adopt rehydrates the Manager from runners left by a previous run of this
+// instance, so a quick restart keeps them warm. It adopts running, healthy
+// runners whose image is current and reaps the rest: stopped or unhealthy
+// runners, stale images, duplicates, and runners from before instances were
+// labelled. Runners owned by another instance are left alone.
+func (m *Manager) Adopt(ctx context.Context, current ImageCheck) (adopted, reaped int, err error) {
+	containers, err := m.rt.List(ctx, map[string]string{"monarch.managed": "true"})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, c := range containers {
+		if !m.owns(c.Labels) {
+			continue
+		}
+		if reason := m.rejectReason(ctx, c, current); reason != "" {
+			if err := m.rt.Remove(ctx, c.ID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s runner %s: %w", reason, c.ID, err))
+				continue
+			}
+			if dir := c.Labels["monarch.overlay"]; dir != "" {
+				_ = os.RemoveAll(dir)
+			}
+			reaped++
+			continue
+		}
+
+		pid := c.Labels["monarch.project"]
+		if _, ok := m.runners[pid]; !ok {
+			m.runners[pid] = make(map[string]*runnerState)
+		}
+		m.runners[pid][adoptKey(c)] = &runnerState{
+			Runner: Runner{
+				ID:        c.ID,
+				ProjectID: pid,
+				Stack:     c.Labels["monarch.stack"],
+				Image:     c.Labels["monarch.image"],
+				Profile:   c.Labels["monarch.profile"],
+				State:     StateIdle,
+				StartedAt: c.Created,
+			},
+			// The idle clock restarts; the previous run's usage isn't known.
+			lastUsed:  time.Now(),
+			workspace: c.Labels["monarch.workspace"],
+			overlay:   c.Labels["monarch.overlay"],
+		}
+		adopted++
+	}
+	return adopted, reaped, errors.Join(errs...)
+}
+
+// rejectReason returns why c can't be adopted, or "" if it can. Callers hold
+// m.mu.
+func (m *Manager) rejectReason(ctx context.Context, c ContainerInfo, current ImageCheck) string {
+	pid, stack := c.Labels["monarch.project"], c.Labels["monarch.stack"]
+	switch {
+	case m.instance == "" || c.Labels["monarch.instance"] != m.instance:
+		return "legacy"
+	case !c.Running:
+		return "stopped"
+	case c.Unhealthy:
+		return "unhealthy"
+	case pid == "" || stack == "" || c.Labels["monarch.profile"] == "":
+		return "unlabelled"
+	case c.Labels["monarch.attempt"] != "":
+		return "finished attempt"
+	case m.runners[pid][adoptKey(c)] != nil:
+		return "duplicate"
+	case current == nil || !current(ctx, pid, stack, c.Labels["monarch.image"], c.Labels["monarch.build"]):
+		return "stale"
+	}
+	return ""
+}
+
+// owns reports whether labels mark a runner, volume or network as this
+// instance's. Those from before instances were labelled belong to whichever
+// instance finds them.
+func (m *Manager) owns(labels map[string]string) bool {
+	owner := labels["monarch.instance"]
+	return owner == "" || owner == m.instance
+}
+
+// tracks reports whether id is the container of a runner the Manager knows
+// of.
+func (m *Manager) tracks(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.find(id) != nil
+}
+
+// adoptKey rebuilds the runner's Profile.key from its labels.
+func adoptKey(c ContainerInfo) string {
+	return c.Labels["monarch.stack"] + "|" + c.Labels["monarch.profile"]
+}
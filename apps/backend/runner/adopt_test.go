@@ -0,0 +1,116 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func runnerContainer(id, instance, project, state, status, image string) types.Container {
+	return types.Container{
+		ID:     id,
+		State:  state,
+		Status: status,
+		Labels: map[string]string{
+			"monarch.managed":  "true",
+			"monarch.instance": instance,
+			"monarch.project":  project,
+			"monarch.stack":    "go",
+			"monarch.image":    image,
+			"monarch.profile":  runner.Profile{}.String(),
+			// Matches project(project, "go").
+			"monarch.workspace": "/src/" + project,
+		},
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli)).WithInstance("me")
+	ctx := context.Background()
+
+	attempt := runnerContainer("attempt", "me", "proj-6", "running", "Up 5 minutes", "golang@sha256:new")
+	attempt.Labels["monarch.attempt"] = "attempt-1"
+	// Built images are checked by how they were built, not their tag.
+	built := runnerContainer("built", "me", "proj-7", "running", "Up 5 minutes", runner.BuiltImageRepo+":abc")
+	built.Labels["monarch.build"] = "same"
+	rebuilt := runnerContainer("rebuilt", "me", "proj-8", "running", "Up 5 minutes", runner.BuiltImageRepo+":abc")
+	rebuilt.Labels["monarch.build"] = "changed"
+	mockCli.On("ContainerList", ctx, mock.Anything).Return([]types.Container{
+		runnerContainer("warm", "me", "proj-1", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("duplicate", "me", "proj-1", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("foreign", "other", "proj-2", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("legacy", "", "proj-2", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("stopped", "me", "proj-3", "exited", "Exited (137) 1 minute ago", "golang@sha256:new"),
+		runnerContainer("sick", "me", "proj-4", "running", "Up 5 minutes (unhealthy)", "golang@sha256:new"),
+		runnerContainer("stale", "me", "proj-5", "running", "Up 5 minutes", "golang@sha256:old"),
+		attempt,
+		built,
+		rebuilt,
+	}, nil)
+	for _, id := range []string{"duplicate", "legacy", "stopped", "sick", "stale", "attempt", "rebuilt"} {
+		mockCli.On("ContainerRemove", ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true}).Return(nil).Once()
+	}
+
+	current := func(_ context.Context, projectID, stack, image, build string) bool {
+		if build != "" {
+			return build == "same"
+		}
+		return image == "golang@sha256:new"
+	}
+	adopted, reaped, err := mgr.Adopt(ctx, current)
+	require.NoError(t, err)
+	assert.Equal(t, 2, adopted)
+	assert.Equal(t, 7, reaped)
+
+	// The adopted runner is reused once it is seen to be up.
+	mockCli.On("ContainerInspect", ctx, "warm").Return(inspectState(container.StateRunning), nil).Once()
+	id, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:new")
+	require.NoError(t, err)
+	assert.Equal(t, "warm", id)
+
+	mockCli.AssertExpectations(t)
+	mockCli.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockCli.AssertNotCalled(t, "ContainerRemove", ctx, "foreign", mock.Anything)
+}
+
+func TestAdopt_WithoutInstanceReapsEverything(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerList", ctx, mock.Anything).Return([]types.Container{
+		runnerContainer("warm", "", "proj-1", "running", "Up", "golang@sha256:new"),
+	}, nil)
+	mockCli.On("ContainerRemove", ctx, "warm", mock.Anything).Return(errors.New("busy"))
+
+	adopted, reaped, err := mgr.Adopt(ctx, nil)
+
+	assert.ErrorContains(t, err, "busy")
+	assert.Zero(t, adopted)
+	assert.Zero(t, reaped)
+}
+
+func TestGetOrStart_LabelsInstance(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli)).WithInstance("me")
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", ctx, mock.MatchedBy(func(cfg *container.Config) bool {
+		return cfg.Labels["monarch.instance"] == "me"
+	}), mock.Anything, mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	mockCli.On("ContainerStart", ctx, "runner-1", container.StartOptions{}).Return(nil)
+
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	mockCli.AssertExpectations(t)
+}
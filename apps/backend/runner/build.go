@@ -0,0 +1,397 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"github.com/monarch-dev/monarch/gates"
+)
+
+// BuiltImageRepo is the repository built runner images are tagged in; the
+// tag is the hash of what they were built from.
+const BuiltImageRepo = "monarch-runner"
+
+// BuildClient is the subset of the Docker API the builder needs.
+type BuildClient interface {
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImageBuild(ctx context.Context, buildContext io.Reader, options build.ImageBuildOptions) (build.ImageBuildResponse, error)
+}
+
+// ImageBuilder builds runner images from project Dockerfiles. An image is
+// tagged with the hash of its Dockerfile, build args and build context, so
+// it is reused until one of them changes.
+type ImageBuilder struct {
+	cli        BuildClient
+	mu         sync.Mutex
+	built      map[string]bool
+	buildLocks map[string]*sync.Mutex
+	// tags caches tags by revision and BuildKey.
+	tags map[string]string
+}
+
+func NewImageBuilder(cli BuildClient) *ImageBuilder {
+	return &ImageBuilder{
+		cli:        cli,
+		built:      make(map[string]bool),
+		buildLocks: make(map[string]*sync.Mutex),
+		tags:       make(map[string]string),
+	}
+}
+
+// BuildKey identifies how b builds an image, though not what from: images
+// with the same key differ only in their build context's contents.
+func BuildKey(b gates.Build) string {
+	if b.Dockerfile == "" {
+		b.Dockerfile = gates.RunnerDockerfile
+	}
+	if b.Context == "" {
+		b.Context = "."
+	}
+	// Maps marshal with sorted keys.
+	spec, _ := json.Marshal(b)
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+// buildContext is a resolved gates.Build.
+type buildContext struct {
+	dir        string
+	dockerfile string // relative to dir, slash-separated
+	args       map[string]string
+	ignore     *patternmatcher.PatternMatcher
+}
+
+// Tag returns the tag the image for b in the project at root is built as,
+// without building it.
+func (ib *ImageBuilder) Tag(root string, b gates.Build) (string, error) {
+	bc, err := resolveBuild(root, b)
+	if err != nil {
+		return "", err
+	}
+	return bc.tag()
+}
+
+// Build returns the image for b in the project at root, building it unless
+// an image with the same hash exists. revision, if set, identifies root's
+// contents, such as a snapshot's commit and diff, so the build context is
+// only hashed the first time it is seen. Build output is sent to ctx's
+// LineSink, if any. Concurrent callers for the same image wait for a single
+// build.
+func (ib *ImageBuilder) Build(ctx context.Context, root, revision string, b gates.Build) (string, error) {
+	bc, err := resolveBuild(root, b)
+	if err != nil {
+		return "", err
+	}
+	tag, err := ib.tag(bc, revision, b)
+	if err != nil {
+		return "", err
+	}
+
+	ib.mu.Lock()
+	lock, ok := ib.buildLocks[tag]
+	if !ok {
+		lock = &sync.Mutex{}
+		ib.buildLocks[tag] = lock
+	}
+	ib.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	ib.mu.Lock()
+	built := ib.built[tag]
+	ib.mu.Unlock()
+	if built {
+		return tag, nil
+	}
+
+	_, err = ib.cli.ImageInspect(ctx, tag)
+	if cerrdefs.IsNotFound(err) {
+		err = ib.build(ctx, bc, tag)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ib.mu.Lock()
+	ib.built[tag] = true
+	ib.mu.Unlock()
+	return tag, nil
+}
+
+// tag returns bc's tag, cached by revision if it is set.
+func (ib *ImageBuilder) tag(bc *buildContext, revision string, b gates.Build) (string, error) {
+	if revision == "" {
+		return bc.tag()
+	}
+	key := revision + "|" + BuildKey(b)
+	ib.mu.Lock()
+	tag, ok := ib.tags[key]
+	ib.mu.Unlock()
+	if ok {
+		return tag, nil
+	}
+	tag, err := bc.tag()
+	if err != nil {
+		return "", err
+	}
+	ib.mu.Lock()
+	ib.tags[key] = tag
+	ib.mu.Unlock()
+	return tag, nil
+}
+
+// buildMessage is one line of the Docker build stream.
+type buildMessage struct {
+	Stream string `json:"stream"`
+	Error  string `json:"error"`
+}
+
+func (ib *ImageBuilder) build(ctx context.Context, bc *buildContext, tag string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(bc.writeTar(pw))
+	}()
+	defer pr.Close()
+
+	args := make(map[string]*string, len(bc.args))
+	for k, v := range bc.args {
+		args[k] = &v
+	}
+	resp, err := ib.cli.ImageBuild(ctx, pr, build.ImageBuildOptions{
+		Tags:        []string{tag},
+		Dockerfile:  bc.dockerfile,
+		BuildArgs:   args,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build runner image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	out := &lineWriter{stream: StreamStdout, buf: io.Discard, sink: lineSinkFrom(ctx)}
+	defer out.flush()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg buildMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to build runner image: %w", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to build runner image: %s", msg.Error)
+		}
+		_, _ = io.WriteString(out, msg.Stream)
+	}
+}
+
+// resolveBuild applies b's defaults and checks its paths stay inside root.
+func resolveBuild(root string, b gates.Build) (*buildContext, error) {
+	dockerfile, contextDir := b.Dockerfile, b.Context
+	if dockerfile == "" {
+		dockerfile = gates.RunnerDockerfile
+	}
+	if contextDir == "" {
+		contextDir = "."
+	}
+	dir, ok := gates.Within(filepath.ToSlash(contextDir))
+	if !ok {
+		return nil, fmt.Errorf("build context %q must be inside the project", contextDir)
+	}
+	file, ok := gates.Within(filepath.ToSlash(dockerfile))
+	if !ok {
+		return nil, fmt.Errorf("dockerfile %q must be inside the project", dockerfile)
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	if rel, ok = gates.Within(filepath.ToSlash(rel)); !ok {
+		return nil, fmt.Errorf("dockerfile %q must be inside the build context %q", dockerfile, contextDir)
+	}
+
+	bc := &buildContext{
+		dir:        filepath.Join(root, filepath.FromSlash(dir)),
+		dockerfile: rel,
+		args:       b.Args,
+	}
+	patterns, err := readIgnoreFile(filepath.Join(bc.dir, ".dockerignore"))
+	if err != nil {
+		return nil, err
+	}
+	// Git metadata changes with every commit and differs between a
+	// checkout and a snapshot of it.
+	patterns = append(patterns, ".git")
+	if bc.ignore, err = patternmatcher.New(patterns); err != nil {
+		return nil, fmt.Errorf("invalid .dockerignore: %w", err)
+	}
+	return bc, nil
+}
+
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ignorefile.ReadAll(f)
+}
+
+// tag hashes the build context, args and Dockerfile path into the image's
+// tag.
+func (bc *buildContext) tag() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "dockerfile %s\x00", bc.dockerfile)
+	keys := make([]string, 0, len(bc.args))
+	for k := range bc.args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "arg %s=%s\x00", k, bc.args[k])
+	}
+
+	err := bc.walk(func(rel string, info fs.FileInfo, path string) error {
+		switch {
+		case info.IsDir():
+			fmt.Fprintf(h, "dir %s\x00", rel)
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link %s %s\x00", rel, target)
+		default:
+			fmt.Fprintf(h, "file %s %o %d\x00", rel, info.Mode().Perm(), info.Size())
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash build context: %w", err)
+	}
+	return BuiltImageRepo + ":" + hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// writeTar writes the build context to w as the tar archive Docker expects.
+func (bc *buildContext) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := bc.walk(func(rel string, info fs.FileInfo, path string) error {
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// As with docker build, files belong to root in the image.
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// walk calls fn, in lexical order, for every directory, regular file and
+// symlink in the context that isn't ignored, with its slash-separated path
+// relative to the context. The Dockerfile and .dockerignore are always
+// included, as Docker needs them.
+func (bc *buildContext) walk(fn func(rel string, info fs.FileInfo, path string) error) error {
+	return filepath.WalkDir(bc.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == bc.dir {
+			return nil
+		}
+		rel, err := filepath.Rel(bc.dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != bc.dockerfile && rel != ".dockerignore" {
+			ignored, err := bc.ignore.MatchesOrParentMatches(rel)
+			if err != nil {
+				return err
+			}
+			if ignored {
+				// Exclusions may re-include files below an ignored dir.
+				if d.IsDir() && !bc.ignore.Exclusions() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&fs.ModeSymlink == 0 {
+			// Sockets, pipes and devices can't be sent.
+			return nil
+		}
+		return fn(rel, info, path)
+	})
+}
+
+// projectRoot is the project root on the host as gates see it: inside its
+// snapshot, if it has one.
+func projectRoot(project Project) string {
+	if project.Snapshots != "" && project.Snapshot != "" {
+		return filepath.Join(project.Snapshots, project.Snapshot)
+	}
+	return project.Path
+}
@@ -0,0 +1,199 @@
+package runner_test
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockBuildClient records the files of every build context it is sent.
+type MockBuildClient struct {
+	mock.Mock
+	contexts [][]string
+}
+
+func (m *MockBuildClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	args := m.Called(ctx, imageID)
+	return args.Get(0).(image.InspectResponse), args.Error(1)
+}
+
+func (m *MockBuildClient) ImageBuild(ctx context.Context, buildContext io.Reader, options build.ImageBuildOptions) (build.ImageBuildResponse, error) {
+	var files []string
+	tr := tar.NewReader(buildContext)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return build.ImageBuildResponse{}, err
+		}
+		files = append(files, hdr.Name)
+	}
+	m.contexts = append(m.contexts, files)
+
+	args := m.Called(ctx, options)
+	return build.ImageBuildResponse{Body: io.NopCloser(strings.NewReader(args.String(0)))}, args.Error(1)
+}
+
+const buildOK = `{"stream":"Step 1/2 : FROM golang:1.23\n"}
+{"stream":"Step 2/2 : RUN apt-get install -y protobuf-compiler\n"}
+{"stream":"Successfully tagged monarch-runner:x\n"}
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// buildProject returns a project root with a runner Dockerfile.
+func buildProject(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, gates.RunnerDockerfile), "FROM golang:1.23\nRUN apt-get install -y protobuf-compiler\n")
+	writeFile(t, filepath.Join(root, "go.mod"), "module app\n")
+	writeFile(t, filepath.Join(root, "node_modules", "x", "index.js"), "")
+	writeFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(root, ".dockerignore"), "node_modules\n")
+	return root
+}
+
+func TestImageBuilder_BuildsOnceAndStreamsLog(t *testing.T) {
+	root := buildProject(t)
+	cli := new(MockBuildClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{}, cerrdefs.ErrNotFound).Once()
+	cli.On("ImageBuild", mock.Anything, mock.MatchedBy(func(o build.ImageBuildOptions) bool {
+		return o.Dockerfile == ".monarch/runner.Dockerfile" && len(o.Tags) == 1 && strings.HasPrefix(o.Tags[0], runner.BuiltImageRepo+":")
+	})).Return(buildOK, nil).Once()
+	ib := runner.NewImageBuilder(cli)
+
+	var lines []string
+	ctx := runner.WithLineSink(context.Background(), func(l runner.Line) { lines = append(lines, l.Text) })
+	tag, err := ib.Build(ctx, root, "", gates.Build{})
+	require.NoError(t, err)
+
+	want, err := ib.Tag(root, gates.Build{})
+	require.NoError(t, err)
+	assert.Equal(t, want, tag)
+	assert.Equal(t, []string{
+		"Step 1/2 : FROM golang:1.23",
+		"Step 2/2 : RUN apt-get install -y protobuf-compiler",
+		"Successfully tagged monarch-runner:x",
+	}, lines)
+	require.Len(t, cli.contexts, 1)
+	assert.ElementsMatch(t, []string{".dockerignore", ".monarch/", ".monarch/runner.Dockerfile", "go.mod"}, cli.contexts[0],
+		".dockerignore'd files and .git aren't sent")
+
+	again, err := ib.Build(context.Background(), root, "", gates.Build{})
+	require.NoError(t, err)
+	assert.Equal(t, tag, again)
+	cli.AssertExpectations(t)
+}
+
+func TestImageBuilder_ReusesExistingImage(t *testing.T) {
+	root := buildProject(t)
+	cli := new(MockBuildClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{ID: "sha256:abc"}, nil).Once()
+	ib := runner.NewImageBuilder(cli)
+
+	_, err := ib.Build(context.Background(), root, "", gates.Build{})
+	require.NoError(t, err)
+	cli.AssertNotCalled(t, "ImageBuild", mock.Anything, mock.Anything)
+}
+
+func TestImageBuilder_TagFollowsContent(t *testing.T) {
+	root := buildProject(t)
+	ib := runner.NewImageBuilder(nil)
+
+	tag, err := ib.Tag(root, gates.Build{})
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(root, "node_modules", "y.js"), "ignored")
+	writeFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/other\n")
+	same, err := ib.Tag(root, gates.Build{})
+	require.NoError(t, err)
+	assert.Equal(t, tag, same, "ignored files don't change the image")
+
+	withArgs, err := ib.Tag(root, gates.Build{Args: map[string]string{"PROTOC": "25"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, tag, withArgs)
+
+	writeFile(t, filepath.Join(root, gates.RunnerDockerfile), "FROM golang:1.24\n")
+	changed, err := ib.Tag(root, gates.Build{})
+	require.NoError(t, err)
+	assert.NotEqual(t, tag, changed)
+}
+
+func TestImageBuilder_CachesTagByRevision(t *testing.T) {
+	root := buildProject(t)
+	cli := new(MockBuildClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{ID: "sha256:abc"}, nil)
+	ib := runner.NewImageBuilder(cli)
+	ctx := context.Background()
+
+	tag, err := ib.Build(ctx, root, "rev-1", gates.Build{})
+	require.NoError(t, err)
+
+	// Content can't change within a revision, so it isn't hashed again.
+	writeFile(t, filepath.Join(root, "go.mod"), "module changed\n")
+	same, err := ib.Build(ctx, root, "rev-1", gates.Build{})
+	require.NoError(t, err)
+	assert.Equal(t, tag, same)
+
+	changed, err := ib.Build(ctx, root, "rev-2", gates.Build{})
+	require.NoError(t, err)
+	assert.NotEqual(t, tag, changed)
+	withArgs, err := ib.Build(ctx, root, "rev-1", gates.Build{Args: map[string]string{"PROTOC": "25"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, same, withArgs)
+}
+
+func TestBuildKey(t *testing.T) {
+	assert.Equal(t, runner.BuildKey(gates.Build{}), runner.BuildKey(gates.Build{Dockerfile: gates.RunnerDockerfile, Context: "."}),
+		"defaults are applied")
+	assert.NotEqual(t, runner.BuildKey(gates.Build{}), runner.BuildKey(gates.Build{Args: map[string]string{"PROTOC": "25"}}))
+	assert.NotEqual(t, runner.BuildKey(gates.Build{}), runner.BuildKey(gates.Build{Dockerfile: "Dockerfile"}))
+}
+
+func TestImageBuilder_RejectsPathsOutsideContext(t *testing.T) {
+	root := buildProject(t)
+	ib := runner.NewImageBuilder(nil)
+
+	_, err := ib.Tag(root, gates.Build{Context: "../"})
+	assert.ErrorContains(t, err, "must be inside the project")
+	_, err = ib.Tag(root, gates.Build{Context: "docker"})
+	assert.ErrorContains(t, err, "must be inside the build context")
+}
+
+func TestImageBuilder_BuildError(t *testing.T) {
+	root := buildProject(t)
+	cli := new(MockBuildClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{}, cerrdefs.ErrNotFound)
+	cli.On("ImageBuild", mock.Anything, mock.Anything).
+		Return(`{"stream":"Step 1/2 : FROM golang:1.23\n"}`+"\n"+`{"error":"The command '/bin/sh -c apt-get install' returned a non-zero code: 100"}`, nil)
+	ib := runner.NewImageBuilder(cli)
+
+	_, err := ib.Build(context.Background(), root, "", gates.Build{})
+	assert.ErrorContains(t, err, "failed to build runner image: The command '/bin/sh -c apt-get install' returned a non-zero code: 100")
+
+	cli.On("ImageBuild", mock.Anything, mock.Anything).Unset()
+	cli.On("ImageBuild", mock.Anything, mock.Anything).Return("", errors.New("daemon unreachable"))
+	_, err = ib.Build(context.Background(), root, "", gates.Build{})
+	assert.ErrorContains(t, err, "daemon unreachable", "failed builds aren't cached")
+}
@@ -0,0 +1,325 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DockerAPI is the subset of client.Client the Docker runtime uses. This
+// interface allows for mocking in tests.
+type DockerAPI interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerExecCreate(ctx context.Context, container string, config container.ExecOptions) (types.IDResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error)
+	VolumeRemove(ctx context.Context, volumeID string, force bool) error
+	NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error)
+	NetworkRemove(ctx context.Context, networkID string) error
+}
+
+// removeOptions also drops the anonymous overlay volume of writable runners.
+var removeOptions = container.RemoveOptions{Force: true, RemoveVolumes: true}
+
+// Docker runs runners as containers on a Docker daemon.
+type Docker struct {
+	cli DockerAPI
+}
+
+func NewDocker(cli DockerAPI) *Docker {
+	return &Docker{cli: cli}
+}
+
+func (d *Docker) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	hostConfig, err := dockerHostConfig(spec)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.cli.ContainerCreate(ctx, &container.Config{
+		Image:      spec.Image,
+		Cmd:        spec.Cmd,
+		User:       spec.User,
+		Env:        spec.Env,
+		WorkingDir: spec.WorkingDir,
+		Labels:     spec.Labels,
+	}, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (d *Docker) Start(ctx context.Context, id string) error {
+	return notFound(d.cli.ContainerStart(ctx, id, container.StartOptions{}))
+}
+
+func (d *Docker) Stop(ctx context.Context, id string) error {
+	return notFound(d.cli.ContainerStop(ctx, id, container.StopOptions{}))
+}
+
+func (d *Docker) Remove(ctx context.Context, id string) error {
+	return notFound(d.cli.ContainerRemove(ctx, id, removeOptions))
+}
+
+func (d *Docker) Inspect(ctx context.Context, id string) (ContainerInfo, error) {
+	resp, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return ContainerInfo{}, notFound(err)
+	}
+	info := ContainerInfo{ID: id}
+	if resp.ContainerJSONBase != nil {
+		info.ID = resp.ID
+		info.Created, _ = time.Parse(time.RFC3339Nano, resp.Created)
+		if s := resp.State; s != nil {
+			info.Running = s.Running
+			info.Unhealthy = s.Health != nil && s.Health.Status == container.Unhealthy
+		}
+	}
+	if resp.Config != nil {
+		info.Labels = resp.Config.Labels
+	}
+	return info, nil
+}
+
+func (d *Docker) List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error) {
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: labelFilter(labels),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ContainerInfo, len(containers))
+	for i, c := range containers {
+		infos[i] = ContainerInfo{
+			ID:        c.ID,
+			Labels:    c.Labels,
+			Created:   time.Unix(c.Created, 0),
+			Running:   c.State == container.StateRunning,
+			Unhealthy: strings.Contains(c.Status, "(unhealthy)"),
+		}
+	}
+	return infos, nil
+}
+
+func (d *Docker) Exec(ctx context.Context, id string, spec ExecSpec, stdout, stderr io.Writer) (int, error) {
+	// 1. Create Exec
+	resp, err := d.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          spec.Cmd,
+		Env:          spec.Env,
+		WorkingDir:   spec.WorkingDir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// 2. Attach
+	attachResp, err := d.cli.ContainerExecAttach(ctx, resp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	// 3. Capture Output until the stream closes or ctx ends
+	copied := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, attachResp.Reader)
+		copied <- err
+	}()
+
+	select {
+	case err = <-copied:
+	case <-ctx.Done():
+		// Closing the stream unblocks the copy, which must return before
+		// the writers are handed back.
+		attachResp.Close()
+		<-copied
+		return 0, fmt.Errorf("exec cancelled: %w", context.Cause(ctx))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy output: %w", err)
+	}
+
+	// 4. Inspect for Exit Code
+	inspectResp, err := d.cli.ContainerExecInspect(ctx, resp.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return inspectResp.ExitCode, nil
+}
+
+func (d *Docker) Volumes(ctx context.Context, labels map[string]string) ([]Resource, error) {
+	resp, err := d.cli.VolumeList(ctx, volume.ListOptions{Filters: labelFilter(labels)})
+	if err != nil {
+		return nil, err
+	}
+	volumes, _, err := d.inUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Resource, 0, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		if v == nil {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		res = append(res, Resource{ID: v.Name, Labels: v.Labels, Created: created, InUse: volumes[v.Name]})
+	}
+	return res, nil
+}
+
+func (d *Docker) RemoveVolume(ctx context.Context, id string) error {
+	return d.cli.VolumeRemove(ctx, id, false)
+}
+
+func (d *Docker) Networks(ctx context.Context, labels map[string]string) ([]Resource, error) {
+	list, err := d.cli.NetworkList(ctx, network.ListOptions{Filters: labelFilter(labels)})
+	if err != nil {
+		return nil, err
+	}
+	_, networks, err := d.inUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Resource, len(list))
+	for i, n := range list {
+		res[i] = Resource{ID: n.ID, Labels: n.Labels, Created: n.Created, InUse: networks[n.ID]}
+	}
+	return res, nil
+}
+
+func (d *Docker) RemoveNetwork(ctx context.Context, id string) error {
+	return d.cli.NetworkRemove(ctx, id)
+}
+
+// inUse returns the names of the volumes and the IDs of the networks any
+// container, running or not, uses. The daemon doesn't list a network's
+// containers, nor a volume's.
+func (d *Docker) inUse(ctx context.Context) (volumes, networks map[string]bool, err error) {
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	volumes, networks = make(map[string]bool), make(map[string]bool)
+	for _, c := range containers {
+		for _, m := range c.Mounts {
+			if m.Type == mount.TypeVolume {
+				volumes[m.Name] = true
+			}
+		}
+		if c.NetworkSettings != nil {
+			for _, ep := range c.NetworkSettings.Networks {
+				if ep != nil {
+					networks[ep.NetworkID] = true
+				}
+			}
+		}
+	}
+	return volumes, networks, nil
+}
+
+// labelFilter matches objects carrying all of labels.
+func labelFilter(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	for k, v := range labels {
+		args.Add("label", k+"="+v)
+	}
+	return args
+}
+
+// notFound translates the daemon's not-found errors to ErrContainerNotFound.
+func notFound(err error) error {
+	if cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrContainerNotFound, err)
+	}
+	return err
+}
+
+// dockerHostConfig builds the hardened host config for a runner: non-root
+// via RunnerUser, no capabilities, no privilege escalation, read-only root
+// filesystem with a size-capped /tmp, and no network unless opted in.
+func dockerHostConfig(spec ContainerSpec) (*container.HostConfig, error) {
+	l, err := spec.Profile.limits()
+	if err != nil {
+		return nil, err
+	}
+
+	hc := &container.HostConfig{
+		Mounts:         []mount.Mount{workspaceVolume(spec.Workspace, spec.Labels)},
+		NetworkMode:    "none",
+		CapDrop:        []string{"ALL"},
+		SecurityOpt:    []string{"no-new-privileges:true"},
+		ReadonlyRootfs: true,
+		Resources: container.Resources{
+			Memory: l.memory,
+			// No swap on top of the limit.
+			MemorySwap: l.memory,
+			NanoCPUs:   l.nanoCPUs,
+		},
+	}
+	if spec.Profile.Network {
+		hc.NetworkMode = "bridge"
+	}
+	if l.pids > 0 {
+		hc.PidsLimit = &l.pids
+	}
+
+	// exec is allowed: go test and friends run binaries built in /tmp.
+	tmpOpts := "rw,nosuid,nodev"
+	if l.tmpfs > 0 {
+		tmpOpts += fmt.Sprintf(",size=%d", l.tmpfs)
+	}
+	hc.Tmpfs = map[string]string{"/tmp": tmpOpts}
+
+	return hc, nil
+}
+
+// workspaceVolume mounts ws at WorkspaceDir. An overlay is an overlayfs
+// volume set up by the daemon, which must therefore run on this host; it
+// carries the runner's labels, so the Reaper finds it if it outlives the
+// runner.
+func workspaceVolume(ws Workspace, labels map[string]string) mount.Mount {
+	if ws.Overlay == "" {
+		return mount.Mount{Type: mount.TypeBind, Source: ws.Source, Target: WorkspaceDir, ReadOnly: ws.ReadOnly}
+	}
+	upper, work := filepath.Join(ws.Overlay, "upper"), filepath.Join(ws.Overlay, "work")
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Target: WorkspaceDir,
+		VolumeOptions: &mount.VolumeOptions{
+			Labels: labels,
+			DriverConfig: &mount.Driver{
+				Name: "local",
+				Options: map[string]string{
+					"type":   "overlay",
+					"device": "overlay",
+					"o":      fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", ws.Source, upper, work),
+				},
+			},
+		},
+	}
+}
@@ -1,62 +1,97 @@
 package runner
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"slices"
+	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/uuid"
 )
 
-// ExecClient defines the interface for executing commands in containers.
-// It matches a subset of the Docker client API.
-type ExecClient interface {
-	ContainerExecCreate(ctx context.Context, container string, config container.ExecOptions) (types.IDResponse, error)
-	ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error)
-	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+type Executor struct {
+	rt Runtime
 }
 
-type Executor struct {
-	cli ExecClient
+func NewExecutor(rt Runtime) *Executor {
+	return &Executor{rt: rt}
 }
 
-func NewExecutor(cli ExecClient) *Executor {
-	return &Executor{cli: cli}
+// killTimeout bounds the exec that kills a cancelled command.
+const killTimeout = 10 * time.Second
+
+// execIDEnv tags every process an exec starts, children included, so a
+// cancelled run can find and kill its whole process tree.
+const execIDEnv = "MONARCH_EXEC_ID"
+
+// Command is a command for Stream to run.
+type Command struct {
+	Cmd []string
+	// Workdir defaults to the container's working directory.
+	Workdir string
+	// Env is added to the command's environment as KEY=value pairs.
+	Env []string
+	// Secrets are values, typically passed in Env, that are replaced by
+	// Redacted wherever they appear in the command's output.
+	Secrets []string
 }
 
-func (e *Executor) Run(ctx context.Context, containerID string, cmd []string) (string, string, int, error) {
-	// 1. Create Exec
-	cfg := container.ExecOptions{
-		Cmd:          cmd,
-		AttachStdout: true,
-		AttachStderr: true,
-	}
-	resp, err := e.cli.ContainerExecCreate(ctx, containerID, cfg)
-	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to create exec: %w", err)
-	}
+// Run executes cmd in the container from workdir, or from the container's
+// working directory if workdir is empty.
+//
+// If ctx ends first, the command's process tree is killed inside the
+// container and Run returns the output captured so far with an error
+// wrapping context.Cause(ctx).
+func (e *Executor) Run(ctx context.Context, containerID string, cmd []string, workdir string) (string, string, int, error) {
+	return e.Stream(ctx, containerID, Command{Cmd: cmd, Workdir: workdir}, nil)
+}
 
-	// 2. Attach
-	attachResp, err := e.cli.ContainerExecAttach(ctx, resp.ID, container.ExecAttachOptions{})
-	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to attach exec: %w", err)
-	}
-	defer attachResp.Close()
+// Stream is Run for c, but also sends each line of output to sink while
+// the command runs. Both the returned output and the lines have c's secrets
+// redacted. The returned output is each stream's last MaxOutputBytes, marked
+// as truncated if any was dropped; only the sink sees all of it.
+func (e *Executor) Stream(ctx context.Context, containerID string, c Command, sink LineSink) (string, string, int, error) {
+	execID := uuid.NewString()
+	redact := newRedactor(c.Secrets)
 
-	// 3. Capture Output
-	var outBuf, errBuf bytes.Buffer
-	_, err = stdcopy.StdCopy(&outBuf, &errBuf, attachResp.Reader)
-	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to copy output: %w", err)
-	}
+	// Only the tail of each stream is kept; the sink sees all of it.
+	outBuf, errBuf := newTailBuffer(MaxOutputBytes), newTailBuffer(MaxOutputBytes)
+	outW := &lineWriter{stream: StreamStdout, buf: outBuf, sink: sink, redact: redact}
+	errW := &lineWriter{stream: StreamStderr, buf: errBuf, sink: sink, redact: redact}
+	// The exec ID goes last, so Env can't override it.
+	env := append(slices.Clone(c.Env), execIDEnv+"="+execID)
+	exitCode, err := e.rt.Exec(ctx, containerID, ExecSpec{
+		Cmd:        c.Cmd,
+		Env:        env,
+		WorkingDir: c.Workdir,
+	}, outW, errW)
+	outW.flush()
+	errW.flush()
+	stdout, stderr := redact.redact(outBuf.String(redact.hold())), redact.redact(errBuf.String(redact.hold()))
 
-	// 4. Inspect for Exit Code
-	inspectResp, err := e.cli.ContainerExecInspect(ctx, resp.ID)
+	if err != nil && ctx.Err() != nil {
+		e.kill(context.WithoutCancel(ctx), containerID, execID)
+		return stdout, stderr, 0, fmt.Errorf("exec cancelled: %w", context.Cause(ctx))
+	}
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to inspect exec: %w", err)
+		return "", "", 0, err
 	}
+	return stdout, stderr, exitCode, nil
+}
+
+// kill SIGKILLs every process in the container tagged with execID. Neither
+// runtime has an API to signal an exec, so this runs as a second exec. It is
+// best effort: the runner's pids limit still bounds anything that escapes.
+func (e *Executor) kill(ctx context.Context, containerID, execID string) {
+	ctx, cancel := context.WithTimeout(ctx, killTimeout)
+	defer cancel()
+
+	script := fmt.Sprintf(`for p in /proc/[0-9]*; do
+  tr '\0' '\n' < "$p/environ" 2>/dev/null | grep -qx '%s=%s' && kill -9 "${p#/proc/}" 2>/dev/null
+done
+exit 0`, execIDEnv, execID)
 
-	return outBuf.String(), errBuf.String(), inspectResp.ExitCode, nil
+	// Waits for the kill to finish.
+	_, _ = e.rt.Exec(ctx, containerID, ExecSpec{Cmd: []string{"sh", "-c", script}}, io.Discard, io.Discard)
 }
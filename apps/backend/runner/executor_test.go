@@ -1,39 +1,27 @@
 package runner_test
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/monarch-dev/monarch/runner"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockExecClient is a mock of the ExecClient interface
-type MockExecClient struct {
-	mock.Mock
-}
-
-func (m *MockExecClient) ContainerExecCreate(ctx context.Context, containerID string, config container.ExecOptions) (types.IDResponse, error) {
-	args := m.Called(ctx, containerID, config)
-	return args.Get(0).(types.IDResponse), args.Error(1)
-}
-
-func (m *MockExecClient) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
-	args := m.Called(ctx, execID, config)
-	return args.Get(0).(types.HijackedResponse), args.Error(1)
-}
-
-func (m *MockExecClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
-	args := m.Called(ctx, execID)
-	return args.Get(0).(container.ExecInspect), args.Error(1)
-}
-
 func TestExecutor_Run(t *testing.T) {
-	mockCli := new(MockExecClient)
-	exec := runner.NewExecutor(mockCli)
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
 	ctx := context.Background()
 
 	// 1. Mock ContainerExecCreate
@@ -52,6 +40,168 @@ func TestExecutor_Run(t *testing.T) {
 	
 		mockCli.On("ContainerExecAttach", ctx, "exec-123", mock.Anything).Return(types.HijackedResponse{}, assert.AnError)
 	
-		_, _, _, err := exec.Run(ctx, "test-container", []string{"echo", "hello"})
+		_, _, _, err := exec.Run(ctx, "test-container", []string{"echo", "hello"}, "")
 		assert.Error(t, err)
 	}
+
+func TestExecutor_Run_KillsProcessTreeOnCancel(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
+
+	var execID string
+	mockCli.On("ContainerExecCreate", mock.Anything, "test-container", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return cfg.Cmd[0] == "go"
+	})).Run(func(args mock.Arguments) {
+		env := args.Get(2).(container.ExecOptions).Env
+		require.Len(t, env, 1)
+		execID = strings.TrimPrefix(env[0], "MONARCH_EXEC_ID=")
+	}).Return(types.IDResponse{ID: "exec-123"}, nil).Once()
+	mockCli.On("ContainerExecAttach", mock.Anything, "exec-123", mock.Anything).Return(hangingExec(t, "partial"), nil)
+
+	// The kill targets the tagged processes and must run even though ctx is done.
+	mockCli.On("ContainerExecCreate", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Err() == nil
+	}), "test-container", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return cfg.Cmd[0] == "sh" && strings.Contains(cfg.Cmd[2], "MONARCH_EXEC_ID="+execID)
+	})).Return(types.IDResponse{ID: "kill-1"}, nil).Once()
+	mockCli.On("ContainerExecAttach", mock.Anything, "kill-1", mock.Anything).Return(execOutput(t, "", ""), nil).Once()
+	mockCli.On("ContainerExecInspect", mock.Anything, "kill-1").Return(container.ExecInspect{}, nil).Once()
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel(assert.AnError)
+	}()
+
+	stdout, _, _, err := exec.Run(ctx, "test-container", []string{"go", "test", "./..."}, "/workspace")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, "partial", stdout)
+	mockCli.AssertExpectations(t)
+}
+
+func TestExecutor_Stream(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
+
+	long := strings.Repeat("x", 20<<10)
+	mockCli.On("ContainerExecCreate", mock.Anything, "test-container", mock.Anything).Return(types.IDResponse{ID: "exec-123"}, nil)
+	mockCli.On("ContainerExecAttach", mock.Anything, "exec-123", mock.Anything).
+		Return(execOutput(t, "=== RUN TestA\r\n--- PASS: TestA\n"+long+"\nno newline", "warning: slow\n"), nil)
+	mockCli.On("ContainerExecInspect", mock.Anything, "exec-123").Return(container.ExecInspect{ExitCode: 3}, nil)
+
+	var lines []runner.Line
+	stdout, stderr, exitCode, err := exec.Stream(context.Background(), "test-container", runner.Command{Cmd: []string{"go", "test"}}, func(l runner.Line) {
+		lines = append(lines, l)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "=== RUN TestA\r\n--- PASS: TestA\n"+long+"\nno newline", stdout, "the returned output is unchanged")
+	assert.Equal(t, "warning: slow\n", stderr)
+
+	var texts []string
+	for _, l := range lines {
+		assert.False(t, l.Time.IsZero())
+		texts = append(texts, l.Stream+":"+l.Text)
+	}
+	assert.Equal(t, []string{
+		"stdout:=== RUN TestA",
+		"stdout:--- PASS: TestA",
+		"stdout:" + long[:16<<10],
+		"stdout:" + long[16<<10:],
+		"stderr:warning: slow",
+		"stdout:no newline",
+	}, texts)
+}
+
+func TestExecutor_StreamKeepsOnlyTheTail(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
+
+	var out strings.Builder
+	for i := range 4096 {
+		fmt.Fprintf(&out, "line %04d %s\n", i, strings.Repeat("x", 40))
+	}
+	require.Greater(t, out.Len(), 2*runner.MaxOutputBytes)
+	mockCli.On("ContainerExecCreate", mock.Anything, "test-container", mock.Anything).Return(types.IDResponse{ID: "exec-123"}, nil)
+	mockCli.On("ContainerExecAttach", mock.Anything, "exec-123", mock.Anything).
+		Return(execOutput(t, out.String(), ""), nil)
+	mockCli.On("ContainerExecInspect", mock.Anything, "exec-123").Return(container.ExecInspect{}, nil)
+
+	var lines int
+	stdout, _, _, err := exec.Stream(context.Background(), "test-container", runner.Command{Cmd: []string{"make"}}, func(runner.Line) {
+		lines++
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4096, lines, "the sink sees every line")
+	assert.LessOrEqual(t, len(stdout), runner.MaxOutputBytes)
+	marker, tail, ok := strings.Cut(stdout, "\n")
+	require.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("[truncated %d bytes]", out.Len()-len(tail)), marker)
+	assert.Regexp(t, `^line \d{4} x+$`, strings.SplitN(tail, "\n", 2)[0], "the tail starts at a whole line")
+	assert.True(t, strings.HasSuffix(out.String(), tail))
+}
+
+func TestExecutor_StreamRedactsSecrets(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
+
+	key := "-----BEGIN KEY-----\nAAAAB3NzaC1yc2E\n-----END KEY-----"
+	mockCli.On("ContainerExecCreate", mock.Anything, "test-container", mock.Anything).Return(types.IDResponse{ID: "exec-123"}, nil)
+	mockCli.On("ContainerExecAttach", mock.Anything, "exec-123", mock.Anything).
+		Return(execOutput(t, "token=tok-123 prefix=tok\n"+key+"\n", "using tok-1234\n"), nil)
+	mockCli.On("ContainerExecInspect", mock.Anything, "exec-123").Return(container.ExecInspect{}, nil)
+
+	var lines []string
+	stdout, stderr, _, err := exec.Stream(context.Background(), "test-container", runner.Command{
+		Cmd:     []string{"deploy"},
+		Env:     []string{"TOKEN=tok-123"},
+		Secrets: []string{"tok-123", "tok-1234", key},
+	}, func(l runner.Line) { lines = append(lines, l.Text) })
+
+	require.NoError(t, err)
+	assert.Equal(t, "token=[REDACTED] prefix=tok\n[REDACTED]\n", stdout)
+	assert.Equal(t, "using [REDACTED]\n", stderr, "the longest secret wins")
+	assert.Equal(t, []string{"token=[REDACTED] prefix=tok", "[REDACTED]", "[REDACTED]", "[REDACTED]", "using [REDACTED]"}, lines,
+		"streamed lines of a multi-line secret are redacted one by one")
+	mockCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "test-container", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return len(cfg.Env) == 2 && cfg.Env[0] == "TOKEN=tok-123"
+	}))
+}
+
+func TestExecutor_StreamRedactsSecretsAcrossLongLineCuts(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	exec := runner.NewExecutor(runner.NewDocker(mockCli))
+
+	const secret = "s3cr3t-t0k3n"
+	// The secret spans the 16 KiB cut of a long line, and arrives split
+	// across two writes; the unterminated last line does too.
+	before := strings.Repeat("x", 16<<10-4) + secret[:6]
+	after := secret[6:] + strings.Repeat("y", 8<<10) + "\n" + strings.Repeat("z", 16<<10-3) + secret
+	var buf bytes.Buffer
+	for _, chunk := range []string{before, after} {
+		_, err := stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	conn, peer := net.Pipe()
+	t.Cleanup(func() { peer.Close() })
+	mockCli.On("ContainerExecCreate", mock.Anything, "test-container", mock.Anything).Return(types.IDResponse{ID: "exec-123"}, nil)
+	mockCli.On("ContainerExecAttach", mock.Anything, "exec-123", mock.Anything).
+		Return(types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(&buf)}, nil)
+	mockCli.On("ContainerExecInspect", mock.Anything, "exec-123").Return(container.ExecInspect{}, nil)
+
+	var lines []string
+	_, _, _, err := exec.Stream(context.Background(), "test-container", runner.Command{
+		Cmd:     []string{"deploy"},
+		Secrets: []string{secret},
+	}, func(l runner.Line) { lines = append(lines, l.Text) })
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		strings.Repeat("x", 16<<10-4),
+		runner.Redacted + strings.Repeat("y", 8<<10),
+		strings.Repeat("z", 16<<10-3) + runner.Redacted,
+	}, lines)
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/monarch-dev/monarch/runner/parser"
+)
+
+// Outcome separates "the code is wrong" from "we couldn't check the code"
+// (FR12). Only VALIDATION_FAILURE counts against the agent.
+type Outcome string
+
+const (
+	OutcomePass              Outcome = "PASS"
+	OutcomeValidationFailure Outcome = "VALIDATION_FAILURE"
+	OutcomeSystemError       Outcome = "SYSTEM_ERROR"
+	// OutcomeTimeout means the gate ran past its timeout and was killed.
+	// Like a validation failure it counts against the agent, whose code
+	// most likely hung.
+	OutcomeTimeout Outcome = "TIMEOUT"
+)
+
+// ErrTimeout is the cause of a TIMEOUT result.
+var ErrTimeout = errors.New("gate timed out")
+
+// GateResult is what RunGate reports for a single gate.
+type GateResult struct {
+	Gate     string            `json:"gate"`
+	Outcome  Outcome           `json:"outcome"`
+	Findings []parser.LogEntry `json:"findings"`
+	Stdout   string            `json:"stdout"`
+	Stderr   string            `json:"stderr"`
+	// ExitCode is nil when the gate never ran to completion.
+	ExitCode *int          `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	// Cause is the infrastructure error behind a SYSTEM_ERROR, or wraps
+	// ErrTimeout for a TIMEOUT.
+	Cause error `json:"-"`
+}
+
+func (r GateResult) Passed() bool {
+	return r.Outcome == OutcomePass
+}
+
+// Err summarises a failed gate, or returns nil if it passed.
+func (r GateResult) Err() error {
+	switch r.Outcome {
+	case OutcomePass:
+		return nil
+	case OutcomeSystemError:
+		return fmt.Errorf("gate %s could not run: %w", r.Gate, r.Cause)
+	case OutcomeTimeout:
+		return fmt.Errorf("gate %s: %w", r.Gate, r.Cause)
+	default:
+		if r.Stderr != "" {
+			return fmt.Errorf("gate %s failed: %s", r.Gate, r.Stderr)
+		}
+		if len(r.Findings) > 0 {
+			return fmt.Errorf("gate %s failed: %s", r.Gate, r.Findings[0].Message)
+		}
+		return fmt.Errorf("gate %s failed", r.Gate)
+	}
+}
+
+func systemError(gate string, cause error) GateResult {
+	if cause == nil {
+		cause = errors.New("unknown error")
+	}
+	return GateResult{Gate: gate, Outcome: OutcomeSystemError, Cause: cause}
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/monarch-dev/monarch/internal/httpjson"
+)
+
+// ListHandler serves GET /runners.
+func (m *Manager) ListHandler(w http.ResponseWriter, r *http.Request) {
+	runners := m.Runners()
+	if runners == nil {
+		runners = []Runner{}
+	}
+	httpjson.Write(w, http.StatusOK, runners)
+}
+
+// StopHandler serves POST /runners/{id}/stop.
+func (m *Manager) StopHandler(w http.ResponseWriter, r *http.Request) {
+	runner, err := m.Stop(r.Context(), r.PathValue("id"))
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, runner)
+}
+
+// RestartHandler serves POST /runners/{id}/restart.
+func (m *Manager) RestartHandler(w http.ResponseWriter, r *http.Request) {
+	runner, err := m.Restart(r.Context(), r.PathValue("id"))
+	if err != nil {
+		httpjson.Error(w, statusCode(err), err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, runner)
+}
+
+// StatsHandler serves GET /reaper.
+func (rp *Reaper) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	httpjson.Write(w, http.StatusOK, rp.Stats())
+}
+
+// RunHandler serves POST /reaper/run, which runs a pass now. With
+// ?dry_run=true it only lists what would be reaped.
+func (rp *Reaper) RunHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			httpjson.Error(w, http.StatusBadRequest, "dry_run must be a boolean")
+			return
+		}
+	}
+	// Failures are part of the report.
+	report, _ := rp.Reap(r.Context(), dryRun)
+	httpjson.Write(w, http.StatusOK, report)
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrRunnerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRunnerStarting):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,70 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	mockCli.On("ContainerStart", mock.Anything, "runner-1", mock.Anything).Return(nil)
+	mockCli.On("ContainerStop", mock.Anything, "runner-1", mock.Anything).Return(nil)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /runners", mgr.ListHandler)
+	mux.HandleFunc("POST /runners/{id}/stop", mgr.StopHandler)
+	mux.HandleFunc("POST /runners/{id}/restart", mgr.RestartHandler)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/runners", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	_, err := mgr.GetOrStart(context.Background(), project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantState runner.State
+	}{
+		{"Stop", "POST", "/runners/runner-1/stop", http.StatusOK, runner.StateStopped},
+		{"Restart", "POST", "/runners/runner-1/restart", http.StatusOK, runner.StateIdle},
+		{"StopUnknown", "POST", "/runners/nope/stop", http.StatusNotFound, ""},
+		{"RestartUnknown", "POST", "/runners/nope/restart", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			if tt.wantState != "" {
+				var got runner.Runner
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				assert.Equal(t, tt.wantState, got.State)
+			}
+		})
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/runners", nil))
+	var list []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "IDLE", list[0]["state"])
+	assert.Equal(t, "alpine", list[0]["image"])
+}
@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// DefaultImages maps each detected stack to the image its runners use unless
+// the project's .monarch/gates.yaml or the global config overrides it.
+var DefaultImages = map[string]string{
+	"go":     "golang:1.23-bookworm",
+	"node":   "node:22-bookworm",
+	"python": "python:3.12-bookworm",
+	"rust":   "rust:1.83-bookworm",
+}
+
+var ErrNoImage = errors.New("no runner image for stack")
+
+// ImageClient is the subset of the Docker API the registry needs.
+type ImageClient interface {
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+}
+
+// PullProgress is one status update from an image pull. Current and Total
+// are bytes and are zero for plain status lines.
+type PullProgress struct {
+	Image   string
+	Layer   string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+// ImageRegistry resolves a stack to a runner image and pins it by digest.
+// Tags move, so the first resolution of a tag is cached and every runner
+// started afterwards uses the same content, even if the tag is re-pulled.
+type ImageRegistry struct {
+	cli       ImageClient
+	images    map[string]string
+	onPull    func(PullProgress)
+	mu        sync.Mutex
+	pinned    map[string]string
+	pullLocks map[string]*sync.Mutex
+}
+
+// NewImageRegistry returns a registry using DefaultImages with overrides
+// (stack -> image) from the global config applied on top. A nil cli leaves
+// pulling to the runtime, as on Kubernetes where each node pulls its own:
+// references are normalized but not pinned.
+func NewImageRegistry(cli ImageClient, overrides map[string]string) *ImageRegistry {
+	images := make(map[string]string, len(DefaultImages)+len(overrides))
+	for stack, img := range DefaultImages {
+		images[stack] = img
+	}
+	for stack, img := range overrides {
+		images[stack] = img
+	}
+	return &ImageRegistry{
+		cli:       cli,
+		images:    images,
+		pinned:    make(map[string]string),
+		pullLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+// WithPullProgress reports pull progress to fn.
+func (r *ImageRegistry) WithPullProgress(fn func(PullProgress)) *ImageRegistry {
+	r.onPull = fn
+	return r
+}
+
+// Image returns the configured image for stack; override, the project's own
+// choice, wins when set.
+func (r *ImageRegistry) Image(stack, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if img, ok := r.images[stack]; ok {
+		return img, nil
+	}
+	return "", fmt.Errorf("%w %q: set image in .monarch/gates.yaml", ErrNoImage, stack)
+}
+
+// Resolve returns the digest-pinned reference for stack's image, pulling it
+// if it isn't available locally.
+func (r *ImageRegistry) Resolve(ctx context.Context, stack, override string) (string, error) {
+	img, err := r.Image(stack, override)
+	if err != nil {
+		return "", err
+	}
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %q: %w", img, err)
+	}
+	ref := reference.TagNameOnly(named).String()
+
+	r.mu.Lock()
+	if pinned, ok := r.pinned[ref]; ok {
+		r.mu.Unlock()
+		return pinned, nil
+	}
+	lock, ok := r.pullLocks[ref]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.pullLocks[ref] = lock
+	}
+	r.mu.Unlock()
+
+	// One pull per image; concurrent gates wait for it instead of pulling again.
+	lock.Lock()
+	defer lock.Unlock()
+
+	r.mu.Lock()
+	pinned, ok := r.pinned[ref]
+	r.mu.Unlock()
+	if ok {
+		return pinned, nil
+	}
+
+	pinned, err = r.pin(ctx, named, ref)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.pinned[ref] = pinned
+	r.mu.Unlock()
+	return pinned, nil
+}
+
+func (r *ImageRegistry) pin(ctx context.Context, named reference.Named, ref string) (string, error) {
+	if r.cli == nil {
+		return ref, nil
+	}
+	inspect, err := r.cli.ImageInspect(ctx, ref)
+	if cerrdefs.IsNotFound(err) {
+		if err := r.pull(ctx, ref); err != nil {
+			return "", err
+		}
+		inspect, err = r.cli.ImageInspect(ctx, ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	if _, ok := named.(reference.Digested); ok {
+		return ref, nil
+	}
+	for _, rd := range inspect.RepoDigests {
+		d, err := reference.ParseNormalizedNamed(rd)
+		if err != nil {
+			continue
+		}
+		if d.Name() == named.Name() {
+			return d.String(), nil
+		}
+	}
+	// Locally built images have no registry digest; the image ID is just as
+	// immutable.
+	if inspect.ID != "" {
+		return inspect.ID, nil
+	}
+	return "", fmt.Errorf("image %s has no digest", ref)
+}
+
+// pullMessage is one line of the Docker pull stream.
+type pullMessage struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+func (r *ImageRegistry) pull(ctx context.Context, ref string) error {
+	rc, err := r.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer rc.Close()
+
+	dec := json.NewDecoder(rc)
+	for {
+		var msg pullMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to pull image %s: %w", ref, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", ref, msg.Error)
+		}
+		if r.onPull != nil {
+			r.onPull(PullProgress{
+				Image:   ref,
+				Layer:   msg.ID,
+				Status:  msg.Status,
+				Current: msg.ProgressDetail.Current,
+				Total:   msg.ProgressDetail.Total,
+			})
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package runner_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const golangDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+type MockImageClient struct {
+	mock.Mock
+}
+
+func (m *MockImageClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	args := m.Called(ctx, imageID)
+	return args.Get(0).(image.InspectResponse), args.Error(1)
+}
+
+func (m *MockImageClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	args := m.Called(ctx, ref, options)
+	return io.NopCloser(strings.NewReader(args.String(0))), args.Error(1)
+}
+
+func TestImageRegistry_Image(t *testing.T) {
+	reg := runner.NewImageRegistry(nil, map[string]string{"node": "node:20", "elixir": "elixir:1.17"})
+
+	tests := []struct {
+		name     string
+		stack    string
+		override string
+		want     string
+	}{
+		{"Default", "go", "", runner.DefaultImages["go"]},
+		{"GlobalOverride", "node", "", "node:20"},
+		{"GlobalNewStack", "elixir", "", "elixir:1.17"},
+		{"ProjectOverrideWins", "node", "node:18", "node:18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reg.Image(tt.stack, tt.override)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := reg.Image("unknown", "")
+	assert.ErrorIs(t, err, runner.ErrNoImage)
+}
+
+func TestImageRegistry_Resolve_PinsLocalImage(t *testing.T) {
+	cli := new(MockImageClient)
+	cli.On("ImageInspect", mock.Anything, "docker.io/library/golang:1.22").
+		Return(image.InspectResponse{RepoDigests: []string{"golang@" + golangDigest}}, nil).Once()
+	reg := runner.NewImageRegistry(cli, nil)
+
+	for range 2 {
+		ref, err := reg.Resolve(context.Background(), "go", "golang:1.22")
+		require.NoError(t, err)
+		assert.Equal(t, "docker.io/library/golang@"+golangDigest, ref)
+	}
+
+	cli.AssertExpectations(t)
+	cli.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImageRegistry_Resolve_PullsMissingImageOnce(t *testing.T) {
+	stream := `{"status":"Pulling from library/golang","id":"1.22"}
+{"status":"Downloading","id":"abc","progressDetail":{"current":10,"total":100}}
+{"status":"Pull complete","id":"abc"}
+{"status":"Digest: ` + golangDigest + `"}
+`
+	cli := new(MockImageClient)
+	cli.On("ImageInspect", mock.Anything, "docker.io/library/golang:1.22").
+		Return(image.InspectResponse{}, cerrdefs.ErrNotFound).Once()
+	cli.On("ImagePull", mock.Anything, "docker.io/library/golang:1.22", image.PullOptions{}).
+		Return(stream, nil).Once()
+	cli.On("ImageInspect", mock.Anything, "docker.io/library/golang:1.22").
+		Return(image.InspectResponse{RepoDigests: []string{"golang@" + golangDigest}}, nil).Once()
+
+	var mu sync.Mutex
+	var progress []runner.PullProgress
+	reg := runner.NewImageRegistry(cli, map[string]string{"go": "golang:1.22"}).
+		WithPullProgress(func(p runner.PullProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, p)
+		})
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref, err := reg.Resolve(context.Background(), "go", "")
+			assert.NoError(t, err)
+			assert.Equal(t, "docker.io/library/golang@"+golangDigest, ref)
+		}()
+	}
+	wg.Wait()
+
+	cli.AssertExpectations(t)
+	require.Len(t, progress, 4)
+	assert.Equal(t, runner.PullProgress{Image: "docker.io/library/golang:1.22", Layer: "abc", Status: "Downloading", Current: 10, Total: 100}, progress[1])
+}
+
+func TestImageRegistry_Resolve_PullError(t *testing.T) {
+	cli := new(MockImageClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{}, cerrdefs.ErrNotFound)
+	cli.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).
+		Return(`{"error":"manifest unknown"}`+"\n", nil)
+	reg := runner.NewImageRegistry(cli, nil)
+
+	_, err := reg.Resolve(context.Background(), "rust", "")
+
+	assert.ErrorContains(t, err, "manifest unknown")
+}
+
+func TestImageRegistry_Resolve_KeepsDigestReference(t *testing.T) {
+	pinned := "docker.io/library/node@" + golangDigest
+	cli := new(MockImageClient)
+	cli.On("ImageInspect", mock.Anything, pinned).Return(image.InspectResponse{ID: "sha256:local"}, nil)
+	reg := runner.NewImageRegistry(cli, nil)
+
+	ref, err := reg.Resolve(context.Background(), "node", "node@"+golangDigest)
+
+	require.NoError(t, err)
+	assert.Equal(t, pinned, ref)
+}
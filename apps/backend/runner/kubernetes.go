@@ -0,0 +1,476 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// runnerContainer is the name of the container in every runner pod.
+const runnerContainer = "runner"
+
+// labelsAnnotation holds a runner's full labels as JSON. Pod labels only
+// take a restricted character set, so paths, image digests and profiles
+// only survive in the annotation.
+const labelsAnnotation = "monarch.labels"
+
+// PodExec runs cmd in a pod's container through the exec subresource,
+// feeding it stdin and copying its output to stdout and stderr, and returns
+// its exit code.
+type PodExec func(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+
+// Kubernetes runs runners as pods, one container each, with gates run
+// through the exec subresource.
+//
+// Pods can't be paused, so Stop deletes the pod and Start creates it again
+// from the same spec: a resumed runner loses its /tmp caches. Workspaces
+// are hostPath volumes, so SnapshotDir must exist on every node runners
+// are scheduled on; pin them with a node selector on the namespace if need
+// be. Offline runners carry the monarch.network=none label for a
+// NetworkPolicy to isolate them; Kubernetes has no per-pod switch. The
+// pids limit is the kubelet's podPidsLimit and writable overlays aren't
+// supported.
+type Kubernetes struct {
+	cs           kubernetes.Interface
+	namespace    string
+	exec         PodExec
+	pollInterval time.Duration
+
+	mu sync.Mutex
+	// pods holds the spec of every pod created by this instance that
+	// hasn't been removed, so stopped runners can be listed and restarted.
+	pods map[string]*corev1.Pod
+}
+
+// NewKubernetes creates runner pods in namespace. config is used to exec
+// into pods; without it, only WithPodExec can run commands.
+func NewKubernetes(cs kubernetes.Interface, config *rest.Config, namespace string) *Kubernetes {
+	k := &Kubernetes{
+		cs:           cs,
+		namespace:    namespace,
+		pollInterval: 500 * time.Millisecond,
+		pods:         make(map[string]*corev1.Pod),
+	}
+	k.exec = func(context.Context, string, string, string, []string, io.Reader, io.Writer, io.Writer) (int, error) {
+		return 0, errors.New("no REST config to exec with")
+	}
+	if config != nil {
+		k.exec = remoteExec(cs, config)
+	}
+	return k
+}
+
+// WithPodExec replaces how commands are exec'd into pods.
+func (k *Kubernetes) WithPodExec(exec PodExec) *Kubernetes {
+	k.exec = exec
+	return k
+}
+
+// WithPollInterval sets how often Start checks whether a pod is up.
+func (k *Kubernetes) WithPollInterval(d time.Duration) *Kubernetes {
+	k.pollInterval = d
+	return k
+}
+
+// Ping checks that runner pods can be listed in the namespace.
+func (k *Kubernetes) Ping(ctx context.Context) error {
+	_, err := k.cs.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	return err
+}
+
+func (k *Kubernetes) Create(ctx context.Context, spec ContainerSpec) (string, error) {
+	pod, err := k.podSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	k.mu.Lock()
+	k.pods[pod.Name] = pod
+	k.mu.Unlock()
+	return pod.Name, nil
+}
+
+// Start creates the pod if it doesn't exist and waits until it is running.
+// A pod still terminating from Stop is waited out first.
+func (k *Kubernetes) Start(ctx context.Context, id string) error {
+	pods := k.cs.CoreV1().Pods(k.namespace)
+	for {
+		pod, err := pods.Get(ctx, id, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			spec := k.spec(id)
+			if spec == nil {
+				return fmt.Errorf("%w: %w", ErrContainerNotFound, err)
+			}
+			if _, err := pods.Create(ctx, spec, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+		case err != nil:
+			return err
+		case pod.DeletionTimestamp != nil:
+		case pod.Status.Phase == corev1.PodRunning:
+			return nil
+		case pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded:
+			return fmt.Errorf("pod %s exited: %s", id, pod.Status.Phase)
+		default:
+			if reason := waitingError(pod); reason != "" {
+				return fmt.Errorf("pod %s can't start: %s", id, reason)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(k.pollInterval):
+		}
+	}
+}
+
+// waitingError returns why the pod's container is stuck, or "" if it may
+// still come up.
+func waitingError(pod *corev1.Pod) string {
+	for _, s := range pod.Status.ContainerStatuses {
+		w := s.State.Waiting
+		if w == nil {
+			continue
+		}
+		switch w.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError":
+			return strings.TrimSpace(w.Reason + " " + w.Message)
+		}
+	}
+	return ""
+}
+
+// Stop deletes the pod but keeps its spec for Start.
+func (k *Kubernetes) Stop(ctx context.Context, id string) error {
+	err := k.delete(ctx, id)
+	if errors.Is(err, ErrContainerNotFound) && k.spec(id) != nil {
+		return nil
+	}
+	return err
+}
+
+func (k *Kubernetes) Remove(ctx context.Context, id string) error {
+	err := k.delete(ctx, id)
+	k.mu.Lock()
+	_, known := k.pods[id]
+	delete(k.pods, id)
+	k.mu.Unlock()
+	if errors.Is(err, ErrContainerNotFound) && known {
+		return nil
+	}
+	return err
+}
+
+func (k *Kubernetes) delete(ctx context.Context, id string) error {
+	// Runners idle in sleep, which ignores SIGTERM; there is nothing to
+	// shut down gracefully.
+	var grace int64
+	err := k.cs.CoreV1().Pods(k.namespace).Delete(ctx, id, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrContainerNotFound, err)
+	}
+	return err
+}
+
+func (k *Kubernetes) Inspect(ctx context.Context, id string) (ContainerInfo, error) {
+	pod, err := k.cs.CoreV1().Pods(k.namespace).Get(ctx, id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if spec := k.spec(id); spec != nil {
+			return podInfo(spec), nil
+		}
+		return ContainerInfo{}, fmt.Errorf("%w: %w", ErrContainerNotFound, err)
+	}
+	if err != nil {
+		return ContainerInfo{}, err
+	}
+	return podInfo(pod), nil
+}
+
+// List returns the pods carrying labels, plus stopped runners whose pod
+// was deleted by Stop.
+func (k *Kubernetes) List(ctx context.Context, want map[string]string) ([]ContainerInfo, error) {
+	// Values pods can't be labelled with are matched on the annotation.
+	selector := make(map[string]string)
+	for key, v := range want {
+		if len(validation.IsValidLabelValue(v)) == 0 {
+			selector[key] = v
+		}
+	}
+	list, err := k.cs.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []ContainerInfo
+	seen := make(map[string]bool)
+	for i := range list.Items {
+		info := podInfo(&list.Items[i])
+		seen[info.ID] = true
+		if hasLabels(info.Labels, want) {
+			infos = append(infos, info)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for id, spec := range k.pods {
+		if info := podInfo(spec); !seen[id] && hasLabels(info.Labels, want) {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func hasLabels(have, want map[string]string) bool {
+	for key, v := range want {
+		if have[key] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Exec runs spec in the pod. The exec subresource takes neither an
+// environment nor a working directory, so the command is wrapped in a shell
+// that sets them. The environment holds secrets and the exec's command
+// line ends up in API server audit logs and the pod's process table, so it
+// is sent as a script on stdin that the wrapper sources.
+func (k *Kubernetes) Exec(ctx context.Context, id string, spec ExecSpec, stdout, stderr io.Writer) (int, error) {
+	workdir := spec.WorkingDir
+	if workdir == "" {
+		workdir = "."
+	}
+	cmd := append([]string{"sh", "-c", `cd "$0" && . /dev/stdin && exec "$@"`, workdir}, spec.Cmd...)
+	var env strings.Builder
+	for _, kv := range spec.Env {
+		env.WriteString("export " + shellQuote(kv) + "\n")
+	}
+
+	// The stream may still be draining when the exec gives up on ctx;
+	// nothing may be written once Exec returns.
+	out, errOut := &cutoffWriter{w: stdout}, &cutoffWriter{w: stderr}
+	defer out.cut()
+	defer errOut.cut()
+
+	code, err := k.exec(ctx, k.namespace, id, runnerContainer, cmd, strings.NewReader(env.String()), out, errOut)
+	if err != nil && ctx.Err() != nil {
+		return 0, fmt.Errorf("exec cancelled: %w", context.Cause(ctx))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec: %w", err)
+	}
+	return code, nil
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cutoffWriter drops writes once cut.
+type cutoffWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	off bool
+}
+
+func (c *cutoffWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.off {
+		return 0, io.ErrClosedPipe
+	}
+	return c.w.Write(p)
+}
+
+func (c *cutoffWriter) cut() {
+	c.mu.Lock()
+	c.off = true
+	c.mu.Unlock()
+}
+
+// spec returns the remembered spec of pod id, or nil.
+func (k *Kubernetes) spec(id string) *corev1.Pod {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if pod, ok := k.pods[id]; ok {
+		return pod.DeepCopy()
+	}
+	return nil
+}
+
+// podInfo describes pod; a pod being deleted counts as stopped.
+func podInfo(pod *corev1.Pod) ContainerInfo {
+	info := ContainerInfo{
+		ID:      pod.Name,
+		Created: pod.CreationTimestamp.Time,
+		Running: pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil,
+	}
+	if err := json.Unmarshal([]byte(pod.Annotations[labelsAnnotation]), &info.Labels); err != nil {
+		info.Labels = pod.Labels
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if info.Running && s.State.Running != nil && !s.Ready {
+			info.Unhealthy = true
+		}
+	}
+	return info
+}
+
+// podSpec builds the hardened pod for a runner, the Kubernetes counterpart
+// of dockerHostConfig.
+func (k *Kubernetes) podSpec(spec ContainerSpec) (*corev1.Pod, error) {
+	if spec.Workspace.Overlay != "" {
+		return nil, errors.New("writable overlay workspaces are not supported on kubernetes")
+	}
+	l, err := spec.Profile.limits()
+	if err != nil {
+		return nil, err
+	}
+	uid, gid, err := parseUser(spec.User)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation, err := json.Marshal(spec.Labels)
+	if err != nil {
+		return nil, err
+	}
+	podLabels := make(map[string]string)
+	for key, v := range spec.Labels {
+		if len(validation.IsValidLabelValue(v)) == 0 {
+			podLabels[key] = v
+		}
+	}
+	if !spec.Profile.Network {
+		podLabels["monarch.network"] = "none"
+	}
+
+	env := make([]corev1.EnvVar, 0, len(spec.Env))
+	for _, kv := range spec.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		env = append(env, corev1.EnvVar{Name: name, Value: value})
+	}
+
+	limits := corev1.ResourceList{}
+	if l.memory > 0 {
+		limits[corev1.ResourceMemory] = *resource.NewQuantity(l.memory, resource.BinarySI)
+	}
+	if l.nanoCPUs > 0 {
+		limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(l.nanoCPUs/1e6, resource.DecimalSI)
+	}
+	tmp := &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}
+	if l.tmpfs > 0 {
+		tmp.SizeLimit = resource.NewQuantity(l.tmpfs, resource.BinarySI)
+	}
+
+	hostPathType := corev1.HostPathDirectory
+	no, yes := false, true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "monarch-runner-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12],
+			Namespace:   k.namespace,
+			Labels:      podLabels,
+			Annotations: map[string]string{labelsAnnotation: string(annotation)},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                corev1.RestartPolicyNever,
+			AutomountServiceAccountToken: &no,
+			EnableServiceLinks:           &no,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:    &uid,
+				RunAsGroup:   &gid,
+				RunAsNonRoot: &yes,
+			},
+			Containers: []corev1.Container{{
+				Name:            runnerContainer,
+				Image:           spec.Image,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         spec.Cmd,
+				Env:             env,
+				WorkingDir:      spec.WorkingDir,
+				Resources:       corev1.ResourceRequirements{Limits: limits},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: &no,
+					ReadOnlyRootFilesystem:   &yes,
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "workspace", MountPath: WorkspaceDir, ReadOnly: spec.Workspace.ReadOnly},
+					{Name: "tmp", MountPath: "/tmp"},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				{Name: "workspace", VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: spec.Workspace.Source, Type: &hostPathType},
+				}},
+				{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: tmp}},
+			},
+		},
+	}, nil
+}
+
+// parseUser parses a uid:gid user.
+func parseUser(user string) (uid, gid int64, err error) {
+	u, g, ok := strings.Cut(user, ":")
+	if ok {
+		uid, err = strconv.ParseInt(u, 10, 64)
+	}
+	if ok && err == nil {
+		gid, err = strconv.ParseInt(g, 10, 64)
+	}
+	if !ok || err != nil {
+		return 0, 0, fmt.Errorf("invalid runner user %q: expected uid:gid", user)
+	}
+	return uid, gid, nil
+}
+
+// remoteExec execs into pods over SPDY, as kubectl exec does.
+func remoteExec(cs kubernetes.Interface, config *rest.Config) PodExec {
+	return func(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+		req := cs.CoreV1().RESTClient().Post().
+			Resource("pods").
+			Namespace(namespace).
+			Name(pod).
+			SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: container,
+				Command:   cmd,
+				Stdin:     stdin != nil,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+		if err != nil {
+			return 0, err
+		}
+		err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitStatus(), nil
+		}
+		return 0, err
+	}
+}
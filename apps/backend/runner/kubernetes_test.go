@@ -0,0 +1,250 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newKubernetes returns a Kubernetes runtime on a fake cluster whose pods
+// come up as soon as they are created.
+func newKubernetes(t *testing.T) (*runner.Kubernetes, *fake.Clientset) {
+	t.Helper()
+	cs := fake.NewClientset()
+	cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = corev1.PodRunning
+		return false, nil, nil
+	})
+	return runner.NewKubernetes(cs, nil, "monarch").WithPollInterval(time.Millisecond), cs
+}
+
+func runnerSpec() runner.ContainerSpec {
+	return runner.ContainerSpec{
+		Image:      "golang:1.23",
+		Cmd:        []string{"sleep", "infinity"},
+		User:       runner.RunnerUser,
+		Env:        []string{"HOME=/tmp"},
+		WorkingDir: runner.WorkspaceDir,
+		Labels: map[string]string{
+			"monarch.managed":   "true",
+			"monarch.project":   "p1",
+			"monarch.workspace": "/src/p1",
+		},
+		Workspace: runner.Workspace{Source: "/src/p1", ReadOnly: true},
+		Profile: runner.Profile{Resources: gates.Resources{
+			Memory: "1g", CPUs: 1.5, Pids: 256, Tmpfs: "256m",
+		}},
+	}
+}
+
+func TestKubernetes_StartsHardenedPod(t *testing.T) {
+	ctx := context.Background()
+	k, cs := newKubernetes(t)
+
+	id, err := k.Create(ctx, runnerSpec())
+	require.NoError(t, err)
+	require.NoError(t, k.Start(ctx, id))
+
+	pod, err := cs.CoreV1().Pods("monarch").Get(ctx, id, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"monarch.managed": "true",
+		"monarch.project": "p1",
+		"monarch.network": "none",
+	}, pod.Labels, "values that aren't valid labels only go in the annotation")
+	assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.False(t, *pod.Spec.AutomountServiceAccountToken)
+	assert.Equal(t, int64(1000), *pod.Spec.SecurityContext.RunAsUser)
+	assert.True(t, *pod.Spec.SecurityContext.RunAsNonRoot)
+
+	c := pod.Spec.Containers[0]
+	assert.Equal(t, "golang:1.23", c.Image)
+	assert.Equal(t, []string{"sleep", "infinity"}, c.Command)
+	assert.Equal(t, []corev1.EnvVar{{Name: "HOME", Value: "/tmp"}}, c.Env)
+	assert.False(t, *c.SecurityContext.AllowPrivilegeEscalation)
+	assert.True(t, *c.SecurityContext.ReadOnlyRootFilesystem)
+	assert.Equal(t, []corev1.Capability{"ALL"}, c.SecurityContext.Capabilities.Drop)
+	assert.Equal(t, "1Gi", c.Resources.Limits.Memory().String())
+	assert.Equal(t, "1500m", c.Resources.Limits.Cpu().String())
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "workspace", MountPath: runner.WorkspaceDir, ReadOnly: true},
+		{Name: "tmp", MountPath: "/tmp"},
+	}, c.VolumeMounts)
+	assert.Equal(t, "/src/p1", pod.Spec.Volumes[0].HostPath.Path)
+	assert.Equal(t, corev1.StorageMediumMemory, pod.Spec.Volumes[1].EmptyDir.Medium)
+	assert.Equal(t, "256Mi", pod.Spec.Volumes[1].EmptyDir.SizeLimit.String())
+
+	info, err := k.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, info.Running)
+	assert.Equal(t, runnerSpec().Labels, info.Labels)
+}
+
+func TestKubernetes_RejectsOverlay(t *testing.T) {
+	k, _ := newKubernetes(t)
+	spec := runnerSpec()
+	spec.Workspace = runner.Workspace{Source: "/src/p1", Overlay: "/tmp/overlay"}
+
+	_, err := k.Create(context.Background(), spec)
+	assert.ErrorContains(t, err, "not supported on kubernetes")
+}
+
+func TestKubernetes_StopDeletesPodAndStartRecreatesIt(t *testing.T) {
+	ctx := context.Background()
+	k, cs := newKubernetes(t)
+	id, err := k.Create(ctx, runnerSpec())
+	require.NoError(t, err)
+	require.NoError(t, k.Start(ctx, id))
+
+	require.NoError(t, k.Stop(ctx, id))
+	_, err = cs.CoreV1().Pods("monarch").Get(ctx, id, metav1.GetOptions{})
+	require.Error(t, err, "the pod is deleted")
+
+	info, err := k.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.False(t, info.Running)
+	list, err := k.List(ctx, map[string]string{"monarch.managed": "true"})
+	require.NoError(t, err)
+	require.Len(t, list, 1, "stopped runners are still listed")
+	assert.False(t, list[0].Running)
+
+	require.NoError(t, k.Start(ctx, id))
+	info, err = k.Inspect(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, info.Running)
+}
+
+func TestKubernetes_RemoveForgetsRunner(t *testing.T) {
+	ctx := context.Background()
+	k, _ := newKubernetes(t)
+	id, err := k.Create(ctx, runnerSpec())
+	require.NoError(t, err)
+	require.NoError(t, k.Start(ctx, id))
+
+	require.NoError(t, k.Remove(ctx, id))
+
+	_, err = k.Inspect(ctx, id)
+	assert.ErrorIs(t, err, runner.ErrContainerNotFound)
+	assert.ErrorIs(t, k.Remove(ctx, id), runner.ErrContainerNotFound)
+	assert.ErrorIs(t, k.Start(ctx, id), runner.ErrContainerNotFound)
+}
+
+func TestKubernetes_ListMatchesAnnotatedLabels(t *testing.T) {
+	ctx := context.Background()
+	k, _ := newKubernetes(t)
+	for _, ws := range []string{"/src/p1", "/src/p2"} {
+		spec := runnerSpec()
+		spec.Labels["monarch.workspace"] = ws
+		id, err := k.Create(ctx, spec)
+		require.NoError(t, err)
+		require.NoError(t, k.Start(ctx, id))
+	}
+
+	list, err := k.List(ctx, map[string]string{"monarch.managed": "true", "monarch.workspace": "/src/p2"})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "/src/p2", list[0].Labels["monarch.workspace"])
+}
+
+func TestKubernetes_StartFailsOnImagePullError(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewClientset()
+	cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = corev1.PodPending
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "runner",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+				Reason: "ImagePullBackOff", Message: "no such image",
+			}},
+		}}
+		return false, nil, nil
+	})
+	k := runner.NewKubernetes(cs, nil, "monarch").WithPollInterval(time.Millisecond)
+
+	id, err := k.Create(ctx, runnerSpec())
+	require.NoError(t, err)
+	err = k.Start(ctx, id)
+	assert.ErrorContains(t, err, "ImagePullBackOff no such image")
+}
+
+func TestKubernetes_ExecSetsEnvAndWorkdir(t *testing.T) {
+	ctx := context.Background()
+	k, _ := newKubernetes(t)
+	workdir := t.TempDir()
+	var gotPod, gotContainer string
+	var gotCmd []string
+	// Runs the wrapped command with the local shell, as the pod's would.
+	k.WithPodExec(func(ctx context.Context, namespace, pod, container string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+		gotPod, gotContainer, gotCmd = pod, container, cmd
+		c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = stdin, stdout, stderr
+		err := c.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	})
+
+	const secret = "it's a \"secret\"\n$HOME `id`"
+	var stdout, stderr strings.Builder
+	code, err := k.Exec(ctx, "pod-1", runner.ExecSpec{
+		Cmd:        []string{"sh", "-c", `pwd; printf '%s|%s' "$TOKEN" "$MONARCH_EXEC_ID"; echo warn >&2; exit 3`},
+		Env:        []string{"TOKEN=" + secret, "MONARCH_EXEC_ID=x"},
+		WorkingDir: workdir,
+	}, &stdout, &stderr)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "pod-1", gotPod)
+	assert.Equal(t, "runner", gotContainer)
+	assert.Equal(t, workdir+"\n"+secret+"|x", stdout.String())
+	assert.Equal(t, "warn\n", stderr.String())
+	for _, arg := range gotCmd {
+		assert.NotContains(t, arg, "secret", "env must not be passed on the command line")
+	}
+}
+
+func TestKubernetes_ManagerAndReaper(t *testing.T) {
+	ctx := context.Background()
+	k, cs := newKubernetes(t)
+	mgr := runner.NewManager(k).WithInstance("me")
+
+	id, err := mgr.GetOrStart(ctx, runner.Project{ID: "p1", Stack: "go", Path: "/src/p1"}, runner.Profile{}, "golang:1.23")
+	require.NoError(t, err)
+	again, err := mgr.GetOrStart(ctx, runner.Project{ID: "p1", Stack: "go", Path: "/src/p1"}, runner.Profile{}, "golang:1.23")
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "the warm pod is reused")
+
+	pod, err := cs.CoreV1().Pods("monarch").Get(ctx, id, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, pod.Spec.Containers[0].VolumeMounts[0].ReadOnly)
+
+	report, err := runner.NewReaper(k, mgr).WithGrace(0).Reap(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Reaped, "the Manager's pods are left alone")
+
+	// A Manager that lost track of the pod.
+	report, err = runner.NewReaper(k, runner.NewManager(k).WithInstance("me")).WithGrace(0).Reap(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []runner.Reaped{{Kind: "container", ID: id, Reason: "untracked"}}, report.Reaped)
+	pods, err := cs.CoreV1().Pods("monarch").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, pods.Items)
+}
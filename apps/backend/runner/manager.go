@@ -2,41 +2,120 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/network"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/monarch-dev/monarch/gates"
 )
 
-// ExtendedDockerClient adds ContainerCreate and ContainerStart to our interface
-type ExtendedDockerClient interface {
-	DockerClient
-	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error)
-	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
-	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+// WorkspaceDir is where the project root is mounted in every runner.
+const WorkspaceDir = "/workspace"
+
+// State is where a runner is in its lifecycle. A runner is STARTING until
+// its container is up, then IDLE, or ACTIVE while commands run in it. It is
+// STOPPED once stopped or if it failed to start; a stopped runner is resumed
+// on next use, keeping its caches.
+type State string
+
+const (
+	StateStarting State = "STARTING"
+	StateIdle     State = "IDLE"
+	StateActive   State = "ACTIVE"
+	StateStopped  State = "STOPPED"
+)
+
+var (
+	ErrRunnerNotFound = errors.New("runner not found")
+	ErrRunnerStarting = errors.New("runner is still starting")
+)
+
+// Runner is a snapshot of a runner's state, as reported by Runners.
+type Runner struct {
+	// ID is the container ID; it is empty until the container is created.
+	ID        string     `json:"id"`
+	ProjectID string     `json:"project_id"`
+	Stack     string     `json:"stack"`
+	Image     string     `json:"image"`
+	Profile   string     `json:"profile"`
+	State     State      `json:"state"`
+	StartedAt time.Time  `json:"started_at"`
+	LastExec  *time.Time `json:"last_exec,omitempty"`
+	InFlight  int        `json:"in_flight"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// runnerState is the Manager's record of one runner.
+type runnerState struct {
+	Runner
+	lastUsed  time.Time
+	stoppedAt time.Time
+	// workspace is the host dir mounted at WorkspaceDir.
+	workspace string
+	// overlay is the host dir holding the runner's overlay upper layer.
+	overlay string
+	// started is closed once a STARTING runner is up or has failed.
+	started chan struct{}
+	// attempt is set for the runners of writable projects, which mount a
+	// single attempt's snapshot; they are removed once idle, as nothing can
+	// resume them.
+	attempt string
+	// creating is the container ID of a STARTING runner, set as soon as
+	// the container is created and until it is up, so the Reaper leaves
+	// it alone while it starts, however long its image takes to pull.
+	creating string
 }
 
 type Manager struct {
-	cli ExtendedDockerClient
-	mu  sync.RWMutex
-	// runners maps ProjectID -> Stack -> ContainerID
-	runners map[string]map[string]string
-	// lastUsed maps ContainerID -> timestamp
-	lastUsed map[string]time.Time
+	rt Runtime
+	mu sync.Mutex
+	// runners maps ProjectID -> slotKey -> the runner in that slot. A STOPPED runner stays until the slot is started again.
+	runners map[string]map[string]*runnerState
+	// instance labels runners so a restart only adopts its own.
+	instance string
+	// retention is how long stopped runners are kept; zero keeps them until
+	// they are replaced or the Manager shuts down.
+	retention time.Duration
+	// removeOnShutdown makes Shutdown remove runners instead of leaving
+	// them for the next start to adopt.
+	removeOnShutdown bool
 }
 
-func NewManager(cli ExtendedDockerClient) *Manager {
+func NewManager(rt Runtime) *Manager {
 	return &Manager{
-		cli:      cli,
-		runners:  make(map[string]map[string]string),
-		lastUsed: make(map[string]time.Time),
+		rt:      rt,
+		runners: make(map[string]map[string]*runnerState),
 	}
 }
 
-// StartMonitor runs a background loop to clean up idle containers
+// WithInstance sets the ID of this Monarch instance. Runners are labelled
+// with it and Adopt only takes over runners carrying the same ID.
+func (m *Manager) WithInstance(id string) *Manager {
+	m.instance = id
+	return m
+}
+
+// WithRetention removes runners that have been stopped for longer than d,
+// rather than keeping them to resume.
+func (m *Manager) WithRetention(d time.Duration) *Manager {
+	m.retention = d
+	return m
+}
+
+// WithRemoveOnShutdown makes Shutdown force-remove every runner instead of
+// leaving them running for the next start to adopt.
+func (m *Manager) WithRemoveOnShutdown(remove bool) *Manager {
+	m.removeOnShutdown = remove
+	return m
+}
+
+// StartMonitor runs a background loop that stops idle runners and removes
+// stopped ones past their retention.
 func (m *Manager) StartMonitor(ctx context.Context, interval, timeout time.Duration) {
 	ticker := time.NewTicker(interval)
 	go func() {
@@ -57,86 +136,594 @@ func (m *Manager) checkIdle(ctx context.Context, timeout time.Duration) {
 	defer m.mu.Unlock()
 
 	now := time.Now()
-	for pid, stacks := range m.runners {
-		for stack, cid := range stacks {
-			if last, ok := m.lastUsed[cid]; ok {
-				if now.Sub(last) > timeout {
-					// Stop container
-					// We ignore error here as it's a background cleanup
-					// and we want to proceed with cleanup from map
-					_ = m.cli.ContainerStop(ctx, cid, container.StopOptions{})
-					
-					// Remove from maps
-					delete(stacks, stack)
-					delete(m.lastUsed, cid)
+	for pid, runners := range m.runners {
+		for key, r := range runners {
+			switch {
+			// Runners with commands in flight are busy, however long ago
+			// they were handed out.
+			case r.State == StateIdle && now.Sub(r.lastUsed) > timeout && r.attempt != "":
+				if err := m.rt.Remove(ctx, r.ID); err != nil && !errors.Is(err, ErrContainerNotFound) {
+					r.LastError = fmt.Sprintf("failed to remove idle runner: %v", err)
+					continue
+				}
+				delete(runners, key)
+				forget(r)
+			case r.State == StateIdle && now.Sub(r.lastUsed) > timeout:
+				// Stopped, not removed, so the next gate resumes it warm.
+				if err := m.rt.Stop(ctx, r.ID); err != nil {
+					r.LastError = fmt.Sprintf("failed to stop idle runner: %v", err)
+					continue
 				}
+				r.State = StateStopped
+				r.stoppedAt = now
+			case r.State == StateStopped && m.retention > 0 && now.Sub(r.stoppedAt) > m.retention:
+				if r.ID != "" {
+					if err := m.rt.Remove(ctx, r.ID); err != nil && !errors.Is(err, ErrContainerNotFound) {
+						r.LastError = fmt.Sprintf("failed to remove stopped runner: %v", err)
+						continue
+					}
+				}
+				delete(runners, key)
+				forget(r)
 			}
 		}
-		// Clean up empty project maps
-		if len(stacks) == 0 {
+		if len(runners) == 0 {
 			delete(m.runners, pid)
 		}
 	}
 }
 
-func (m *Manager) GetOrStart(ctx context.Context, projectID, stack string) (string, error) {
-	m.mu.RLock()
-	if stacks, ok := m.runners[projectID]; ok {
-		if id, ok := stacks[stack]; ok {
-			m.mu.RUnlock()
-			m.touch(id)
-			return id, nil
+// Runners returns a snapshot of every runner the Manager knows of, ordered
+// by project, stack and profile.
+func (m *Manager) Runners() []Runner {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []Runner
+	for _, runners := range m.runners {
+		for _, r := range runners {
+			list = append(list, r.Runner)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		if a.Stack != b.Stack {
+			return a.Stack < b.Stack
 		}
+		return a.Profile < b.Profile
+	})
+	return list
+}
+
+// Stop stops the runner with container ID id. It stays listed as STOPPED
+// and is resumed in place, keeping its caches, the next time its project
+// needs it; it is only replaced if its image or workspace has changed
+// since, or removed once past its retention.
+func (m *Manager) Stop(ctx context.Context, id string) (Runner, error) {
+	if _, err := m.lookup(id); err != nil {
+		return Runner{}, err
 	}
-	m.mu.RUnlock()
+	err := m.rt.Stop(ctx, id)
+	return m.update(id, func(r *runnerState) {
+		if err != nil {
+			r.LastError = fmt.Sprintf("failed to stop runner: %v", err)
+			return
+		}
+		r.State = StateStopped
+		r.stoppedAt = time.Now()
+	}), err
+}
 
-	return m.startContainer(ctx, projectID, stack)
+// Restart stops the runner with container ID id, killing anything running
+// in it, and starts it again.
+func (m *Manager) Restart(ctx context.Context, id string) (Runner, error) {
+	if _, err := m.lookup(id); err != nil {
+		return Runner{}, err
+	}
+	err := m.rt.Stop(ctx, id)
+	if err == nil {
+		err = m.rt.Start(ctx, id)
+	}
+	return m.update(id, func(r *runnerState) {
+		if err != nil {
+			r.State = StateStopped
+			r.stoppedAt = time.Now()
+			r.LastError = fmt.Sprintf("failed to restart runner: %v", err)
+			return
+		}
+		r.State = StateIdle
+		r.StartedAt = time.Now()
+		r.lastUsed = r.StartedAt
+		r.LastError = ""
+	}), err
 }
 
-func (m *Manager) touch(id string) {
+// lookup returns the runner with container ID id; STARTING runners can't
+// be controlled yet.
+func (m *Manager) lookup(id string) (Runner, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.lastUsed[id] = time.Now()
+	r := m.find(id)
+	if r == nil {
+		return Runner{}, fmt.Errorf("%w: %s", ErrRunnerNotFound, id)
+	}
+	if r.State == StateStarting {
+		return Runner{}, fmt.Errorf("%w: %s", ErrRunnerStarting, id)
+	}
+	return r.Runner, nil
 }
 
-func (m *Manager) startContainer(ctx context.Context, projectID, stack string) (string, error) {
+// update applies fn to the runner with container ID id, if it is still
+// known, and returns its new state.
+func (m *Manager) update(id string, fn func(*runnerState)) Runner {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	r := m.find(id)
+	if r == nil {
+		return Runner{ID: id, State: StateStopped}
+	}
+	fn(r)
+	return r.Runner
+}
 
-	// Double-check locking
-	if stacks, ok := m.runners[projectID]; ok {
-		if id, ok := stacks[stack]; ok {
-			m.lastUsed[id] = time.Now()
-			return id, nil
+// find returns the runner with container ID id, including one whose
+// container is still starting. Callers hold m.mu.
+func (m *Manager) find(id string) *runnerState {
+	if id == "" {
+		return nil
+	}
+	for _, runners := range m.runners {
+		for _, r := range runners {
+			if r.ID == id || r.creating == id {
+				return r
+			}
 		}
 	}
+	return nil
+}
 
-	// For MVP, we use a placeholder image. Real implementation would map stack -> image
-	image := "alpine" 
-	cmd := []string{"sleep", "infinity"}
+// beginExec marks a command running in runner id; the returned func marks
+// it finished, recording err as the runner's last error.
+func (m *Manager) beginExec(id string) func(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.find(id)
+	if r == nil {
+		return func(error) {}
+	}
+	now := time.Now()
+	r.InFlight++
+	r.LastExec = &now
+	r.lastUsed = now
+	if r.State == StateIdle {
+		r.State = StateActive
+	}
+
+	return func(err error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		r.InFlight--
+		r.lastUsed = time.Now()
+		if r.InFlight == 0 && r.State == StateActive {
+			r.State = StateIdle
+		}
+		if err != nil {
+			r.LastError = err.Error()
+		}
+	}
+}
+
+// Shutdown releases every runner the Manager started. It is called once on
+// process exit. Running runners are left up so the next start can Adopt
+// them warm, and kept counts them; stopped ones would only be reaped by
+// Adopt, so they are removed. With WithRemoveOnShutdown, every runner is
+// force-removed instead.
+func (m *Manager) Shutdown(ctx context.Context) (kept, removed int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for pid, runners := range m.runners {
+		if !m.removeOnShutdown {
+			for key, r := range runners {
+				if r.ID != "" && r.State != StateStopped {
+					delete(runners, key)
+					kept++
+				}
+			}
+		}
+		n, err := m.removeProject(ctx, pid)
+		removed += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return kept, removed, errors.Join(errs...)
+}
+
+// StopProject force-removes the project's warm runners, e.g. when the
+// project is deleted, and returns how many were removed.
+func (m *Manager) StopProject(ctx context.Context, projectID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.removeProject(ctx, projectID)
+}
+
+// removeProject removes the project's runners. Runners that fail to be
+// removed stay listed. Callers hold m.mu.
+func (m *Manager) removeProject(ctx context.Context, projectID string) (int, error) {
+	var errs []error
+	removed := 0
+	for key, r := range m.runners[projectID] {
+		// A runner still starting has no container yet; GetOrStart removes
+		// it once it sees its slot is gone.
+		if r.ID != "" {
+			if err := m.rt.Remove(ctx, r.ID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove runner %s: %w", r.ID, err))
+				continue
+			}
+			removed++
+		}
+		delete(m.runners[projectID], key)
+		forget(r)
+	}
+	if len(m.runners[projectID]) == 0 {
+		delete(m.runners, projectID)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// GetOrStart returns the project's warm runner for its stack and profile.
+// A stopped runner is resumed if it still uses image and mounts the
+// project's workspace; otherwise, or if the runner died or was removed
+// outside Monarch, a new one is started from image. Concurrent callers for
+// the same runner wait for a single start.
+func (m *Manager) GetOrStart(ctx context.Context, project Project, profile Profile, image string) (string, error) {
+	ws, err := workspaceSource(project)
+	if err != nil {
+		return "", err
+	}
+	attempt := writableAttempt(project)
+	key := profile.key(project.Stack)
+	if attempt != "" {
+		key += "|" + attempt
+	}
+
+	for {
+		m.mu.Lock()
+		r := m.runners[project.ID][key]
+
+		if r != nil && r.State == StateStarting {
+			started := r.started
+			m.mu.Unlock()
+			select {
+			case <-started:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			continue
+		}
+
+		if r != nil && r.State != StateStopped {
+			r.lastUsed = time.Now()
+			m.mu.Unlock()
+			if r.workspace == ws && m.running(ctx, r.ID) {
+				return r.ID, nil
+			}
+			m.discard(ctx, project.ID, key, r)
+			continue
+		}
 
-	resp, err := m.cli.ContainerCreate(ctx, &container.Config{
+		// The slot is empty or stopped: claim it.
+		var stale *runnerState
+		resume := r != nil && r.ID != "" && r.Image == image && r.workspace == ws
+		if resume {
+			r.State = StateStarting
+			r.started = make(chan struct{})
+		} else {
+			stale = r
+			r = &runnerState{
+				Runner: Runner{
+					ProjectID: project.ID,
+					Stack:     project.Stack,
+					Image:     image,
+					Profile:   profile.String(),
+					State:     StateStarting,
+					StartedAt: time.Now(),
+				},
+				workspace: ws,
+				attempt:   attempt,
+				started:   make(chan struct{}),
+			}
+			if _, ok := m.runners[project.ID]; !ok {
+				m.runners[project.ID] = make(map[string]*runnerState)
+			}
+			m.runners[project.ID][key] = r
+		}
+		m.mu.Unlock()
+
+		if stale != nil {
+			// Its image or workspace changed, or it failed to start.
+			m.remove(ctx, stale)
+		}
+		if resume {
+			id, ok, err := m.resume(ctx, project.ID, key, r)
+			if ok || err != nil {
+				return id, err
+			}
+			continue
+		}
+		return m.start(ctx, project, profile, image, key, r)
+	}
+}
+
+// running reports whether runner id is still up. A runner that can't be
+// inspected for any reason other than being gone is assumed up; the gate
+// fails on it if it isn't.
+func (m *Manager) running(ctx context.Context, id string) bool {
+	info, err := m.rt.Inspect(ctx, id)
+	if err != nil {
+		return !errors.Is(err, ErrContainerNotFound)
+	}
+	return info.Running
+}
+
+// discard drops r, which died, was removed outside Monarch or mounts the
+// wrong workspace, from its slot and removes what is left of it.
+func (m *Manager) discard(ctx context.Context, projectID, key string, r *runnerState) {
+	m.mu.Lock()
+	if m.runners[projectID][key] != r {
+		// Another caller got there first.
+		m.mu.Unlock()
+		return
+	}
+	delete(m.runners[projectID], key)
+	m.mu.Unlock()
+	m.remove(ctx, r)
+}
+
+// remove force-removes r's container, if it has one, and its overlay. It is
+// best effort: the container may already be gone.
+func (m *Manager) remove(ctx context.Context, r *runnerState) {
+	if r.ID != "" {
+		_ = m.rt.Remove(ctx, r.ID)
+	}
+	m.mu.Lock()
+	forget(r)
+	m.mu.Unlock()
+}
+
+// resume starts the stopped runner r. If it can't be started, it is
+// discarded and ok is false so the caller starts a new one; err is only set
+// if ctx ended.
+func (m *Manager) resume(ctx context.Context, projectID, key string, r *runnerState) (id string, ok bool, err error) {
+	startErr := m.rt.Start(ctx, r.ID)
+
+	m.mu.Lock()
+	defer close(r.started)
+	if m.runners[projectID][key] != r {
+		m.mu.Unlock()
+		return "", false, fmt.Errorf("runner for project %s was removed while starting", projectID)
+	}
+	if startErr != nil {
+		r.State = StateStopped
+		r.LastError = fmt.Sprintf("failed to resume runner: %v", startErr)
+		if ctx.Err() != nil {
+			m.mu.Unlock()
+			return "", false, ctx.Err()
+		}
+		delete(m.runners[projectID], key)
+		m.mu.Unlock()
+		m.remove(ctx, r)
+		return "", false, nil
+	}
+	r.State = StateIdle
+	r.StartedAt = time.Now()
+	r.lastUsed = r.StartedAt
+	r.LastError = ""
+	m.mu.Unlock()
+	return r.ID, true, nil
+}
+
+// start creates the container for the STARTING runner r.
+func (m *Manager) start(ctx context.Context, project Project, profile Profile, image, key string, r *runnerState) (string, error) {
+	id, overlay, err := m.startContainer(ctx, project, profile, image, func(id string) {
+		m.mu.Lock()
+		r.creating = id
+		m.mu.Unlock()
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer close(r.started)
+	r.creating = ""
+	if err != nil {
+		r.State = StateStopped
+		r.stoppedAt = time.Now()
+		r.LastError = err.Error()
+		return "", err
+	}
+	if m.runners[project.ID][key] != r {
+		// The project's runners were removed while this one started.
+		_ = m.rt.Remove(context.WithoutCancel(ctx), id)
+		if overlay != "" {
+			_ = os.RemoveAll(overlay)
+		}
+		return "", fmt.Errorf("runner for project %s was removed while starting", project.ID)
+	}
+	r.ID = id
+	r.overlay = overlay
+	r.State = StateIdle
+	r.lastUsed = time.Now()
+	return id, nil
+}
+
+// forget drops a replaced or removed runner's overlay upper layer.
+func forget(r *runnerState) {
+	if r.overlay != "" {
+		_ = os.RemoveAll(r.overlay)
+		r.overlay = ""
+	}
+}
+
+// startContainer creates and starts a runner, returning its container ID
+// and overlay dir, if it has one. created is called with the container ID
+// as soon as the container is created, before it is started.
+func (m *Manager) startContainer(ctx context.Context, project Project, profile Profile, image string, created func(id string)) (string, string, error) {
+	ws, err := workspaceMount(project)
+	if err != nil {
+		return "", "", err
+	}
+	cleanup := func() {
+		if ws.Overlay != "" {
+			_ = os.RemoveAll(ws.Overlay)
+		}
+	}
+
+	labels := map[string]string{
+		"monarch.managed":  "true",
+		"monarch.project":  project.ID,
+		"monarch.stack":    project.Stack,
+		"monarch.image":    image,
+		"monarch.instance": m.instance,
+		"monarch.profile":  profile.String(),
+		// Lets a restarted instance tell whether the runner mounts the
+		// project's current workspace.
+		"monarch.workspace": ws.Source,
+	}
+	if ws.Overlay != "" {
+		// Lets a restarted instance clean up the upper layer.
+		labels["monarch.overlay"] = ws.Overlay
+	}
+	if project.Build != nil {
+		// A built image's tag hashes a snapshot that is gone by the time
+		// an instance restarts; this tells it how the image was built.
+		labels["monarch.build"] = BuildKey(*project.Build)
+	}
+	if attempt := writableAttempt(project); attempt != "" {
+		// The attempt is over by the time an instance restarts.
+		labels["monarch.attempt"] = attempt
+	}
+
+	id, err := m.rt.Create(ctx, ContainerSpec{
 		Image: image,
-		Cmd:   cmd,
-		Labels: map[string]string{
-			"monarch.managed": "true",
-			"monarch.project": projectID,
-			"monarch.stack":   stack,
-		},
-	}, nil, nil, nil, "")
+		// Runners idle between gate runs; gates are exec'd into them.
+		Cmd:        []string{"sleep", "infinity"},
+		User:       RunnerUser,
+		Env:        runnerEnv,
+		WorkingDir: WorkspaceDir,
+		Labels:     labels,
+		Workspace:  ws,
+		Profile:    profile,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create container: %w", err)
+		cleanup()
+		return "", "", fmt.Errorf("failed to create container: %w", err)
 	}
+	created(id)
 
-	if err := m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", fmt.Errorf("failed to start container: %w", err)
+	if err := m.rt.Start(ctx, id); err != nil {
+		_ = m.rt.Remove(ctx, id)
+		cleanup()
+		return "", "", fmt.Errorf("failed to start container: %w", err)
 	}
 
-	if _, ok := m.runners[projectID]; !ok {
-		m.runners[projectID] = make(map[string]string)
+	return id, ws.Overlay, nil
+}
+
+// workspaceSource returns the host dir mounted at WorkspaceDir: the
+// project's snapshots, as snapshotMount picks them, if it has any, or else
+// its root.
+func workspaceSource(project Project) (string, error) {
+	if project.Snapshots != "" {
+		src, _, err := snapshotMount(project)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(src)
 	}
-	m.runners[projectID][stack] = resp.ID
-	m.lastUsed[resp.ID] = time.Now()
+	if project.Path == "" {
+		return "", fmt.Errorf("project %s has no workspace path", project.ID)
+	}
+	return filepath.Abs(project.Path)
+}
 
-	return resp.ID, nil
+// snapshotMount returns the host dir of the project's snapshots to mount
+// at WorkspaceDir, and where the project root is inside it. Read-only
+// projects mount the whole snapshot dir, so one runner serves every
+// attempt. A writable project mounts only its attempt's own snapshot, so
+// its gates can't touch other attempts' files, and gets a runner per
+// attempt.
+func snapshotMount(project Project) (src, rel string, err error) {
+	if project.Snapshot == "" {
+		if project.Writable {
+			return "", "", fmt.Errorf("writable project %s has no snapshot to mount", project.ID)
+		}
+		return project.Snapshots, ".", nil
+	}
+	rel, ok := gates.Within(filepath.ToSlash(project.Snapshot))
+	if !ok || (project.Writable && rel == ".") {
+		return "", "", fmt.Errorf("snapshot %q must be inside the snapshot dir", project.Snapshot)
+	}
+	if !project.Writable {
+		return project.Snapshots, rel, nil
+	}
+	attempt, rest, _ := strings.Cut(rel, "/")
+	if rest == "" {
+		rest = "."
+	}
+	return filepath.Join(project.Snapshots, attempt), rest, nil
+}
+
+// writableAttempt returns the attempt whose snapshot a writable project's
+// runner mounts, or "" if the project isn't writable or has no snapshot.
+// Such runners are per attempt, so one attempt never replaces a runner
+// another attempt's gates are running in.
+func writableAttempt(project Project) string {
+	if !project.Writable || project.Snapshots == "" {
+		return ""
+	}
+	src, _, err := snapshotMount(project)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(src)
+}
+
+// workspaceMount mounts the project root at WorkspaceDir. It is read-only
+// unless the project opts into a writable overlay, whose upper layer lives
+// in a temporary host dir that is discarded with the runner, so the
+// project's files are never modified.
+//
+// Snapshots are copies made for the gates, so a writable project's attempt
+// snapshot is simply mounted read-write; an overlay's lower layer mustn't
+// change while mounted.
+func workspaceMount(project Project) (Workspace, error) {
+	src, err := workspaceSource(project)
+	if err != nil {
+		return Workspace{}, err
+	}
+
+	if !project.Writable || project.Snapshots != "" {
+		return Workspace{Source: src, ReadOnly: !project.Writable}, nil
+	}
+
+	// Commas separate overlay options and colons separate lower layers.
+	if strings.ContainsAny(src, ",:") {
+		return Workspace{}, fmt.Errorf("writable workspace is not supported for path %q", src)
+	}
+	dir, err := os.MkdirTemp("", "monarch-overlay-")
+	if err != nil {
+		return Workspace{}, fmt.Errorf("failed to create overlay dir: %w", err)
+	}
+	for _, d := range []string{"upper", "work"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			_ = os.RemoveAll(dir)
+			return Workspace{}, fmt.Errorf("failed to create overlay dir: %w", err)
+		}
+	}
+	return Workspace{Source: src, Overlay: dir}, nil
 }
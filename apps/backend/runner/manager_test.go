@@ -2,12 +2,19 @@ package runner_test
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/monarch-dev/monarch/gates"
 	"github.com/monarch-dev/monarch/runner"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
@@ -15,7 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// MockDockerClient implements runner.ExtendedDockerClient
+// MockDockerClient implements runner.DockerAPI
 type MockDockerClient struct {
 	mock.Mock
 }
@@ -45,23 +52,76 @@ func (m *MockDockerClient) ContainerStop(ctx context.Context, containerID string
 	return args.Error(0)
 }
 
+func (m *MockDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	args := m.Called(ctx, containerID)
+	return args.Get(0).(container.InspectResponse), args.Error(1)
+}
+
+func (m *MockDockerClient) ContainerExecCreate(ctx context.Context, containerID string, config container.ExecOptions) (types.IDResponse, error) {
+	args := m.Called(ctx, containerID, config)
+	return args.Get(0).(types.IDResponse), args.Error(1)
+}
+
+func (m *MockDockerClient) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+	args := m.Called(ctx, execID, config)
+	return args.Get(0).(types.HijackedResponse), args.Error(1)
+}
+
+func (m *MockDockerClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	args := m.Called(ctx, execID)
+	return args.Get(0).(container.ExecInspect), args.Error(1)
+}
+
+func (m *MockDockerClient) VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error) {
+	args := m.Called(ctx, options)
+	return args.Get(0).(volume.ListResponse), args.Error(1)
+}
+
+func (m *MockDockerClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	args := m.Called(ctx, volumeID, force)
+	return args.Error(0)
+}
+
+func (m *MockDockerClient) NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error) {
+	args := m.Called(ctx, options)
+	return args.Get(0).([]network.Summary), args.Error(1)
+}
+
+func (m *MockDockerClient) NetworkRemove(ctx context.Context, networkID string) error {
+	args := m.Called(ctx, networkID)
+	return args.Error(0)
+}
+
+// inspectState is a ContainerInspect response for a runner in state.
+func inspectState(state container.ContainerState) container.InspectResponse {
+	return container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{
+		State: &container.State{Status: state, Running: state == container.StateRunning},
+	}}
+}
+
+func project(id, stack string) runner.Project {
+	return runner.Project{ID: id, Stack: stack, Path: "/src/" + id}
+}
+
 func TestGetOrStart_StartsNewContainer(t *testing.T) {
 	mockCli := new(MockDockerClient)
-	mgr := runner.NewManager(mockCli)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
 	ctx := context.Background()
 
 	// Expectation: ContainerCreate called with correct labels
 	mockCli.On("ContainerCreate", ctx, mock.MatchedBy(func(cfg *container.Config) bool {
 		return cfg.Labels["monarch.managed"] == "true" &&
 			cfg.Labels["monarch.project"] == "proj-1" &&
-			cfg.Labels["monarch.stack"] == "python-3.11"
-	}), (*container.HostConfig)(nil), (*network.NetworkingConfig)(nil), (*v1.Platform)(nil), "").
+			cfg.Labels["monarch.stack"] == "python-3.11" &&
+			cfg.Image == "python@sha256:abc" &&
+			cfg.WorkingDir == runner.WorkspaceDir
+	}), mock.Anything, (*network.NetworkingConfig)(nil), (*v1.Platform)(nil), "").
 		Return(container.CreateResponse{ID: "new-container-123"}, nil)
 
 	mockCli.On("ContainerStart", ctx, "new-container-123", container.StartOptions{}).
 		Return(nil)
 
-	id, err := mgr.GetOrStart(ctx, "proj-1", "python-3.11")
+	id, err := mgr.GetOrStart(ctx, project("proj-1", "python-3.11"), runner.Profile{}, "python@sha256:abc")
 	assert.NoError(t, err)
 	assert.Equal(t, "new-container-123", id)
 
@@ -70,7 +130,7 @@ func TestGetOrStart_StartsNewContainer(t *testing.T) {
 
 func TestGetOrStart_ReturnsExistingContainer(t *testing.T) {
 	mockCli := new(MockDockerClient)
-	mgr := runner.NewManager(mockCli)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
 	ctx := context.Background()
 
 	// Pre-seed map logic (simulated by calling GetOrStart once)
@@ -81,10 +141,11 @@ func TestGetOrStart_ReturnsExistingContainer(t *testing.T) {
 		Return(nil).Once()
 
 	// Priming call
-	_, _ = mgr.GetOrStart(ctx, "proj-1", "stack-1")
+	_, _ = mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
 
-	// Second call - should NOT call Docker API
-	id, err := mgr.GetOrStart(ctx, "proj-1", "stack-1")
+	// Second call - only checks the runner is still up
+	mockCli.On("ContainerInspect", mock.Anything, "existing-id").Return(inspectState(container.StateRunning), nil).Once()
+	id, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
 	assert.NoError(t, err)
 	assert.Equal(t, "existing-id", id)
 
@@ -93,7 +154,7 @@ func TestGetOrStart_ReturnsExistingContainer(t *testing.T) {
 
 func TestMonitor_StopsIdleContainers(t *testing.T) {
 	mockCli := new(MockDockerClient)
-	mgr := runner.NewManager(mockCli)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -103,7 +164,7 @@ func TestMonitor_StopsIdleContainers(t *testing.T) {
 	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil).Once()
 
-	_, err := mgr.GetOrStart(ctx, "proj-1", "stack-1")
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
 	require.NoError(t, err)
 
 	// 2. Expect Stop
@@ -116,16 +177,520 @@ func TestMonitor_StopsIdleContainers(t *testing.T) {
 	// 4. Wait for idle (simulated by sleep > timeout)
 	time.Sleep(100 * time.Millisecond)
 
-	// 5. Verify it's gone from map (internal state check via public behavior)
-	// If we call GetOrStart again, it should try to Create again, not return existing
+	// 5. The stopped runner is resumed, not replaced, so its caches survive
+	mockCli.On("ContainerStart", mock.Anything, "idle-container", mock.Anything).
+		Return(nil).Once()
+
+	id, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
+	assert.NoError(t, err)
+	assert.Equal(t, "idle-container", id)
+	assert.Equal(t, runner.StateIdle, mgr.Runners()[0].State)
+
+	mockCli.AssertExpectations(t)
+	mockCli.AssertNumberOfCalls(t, "ContainerCreate", 1)
+}
+
+func TestMonitor_RemovesStoppedRunnersAfterRetention(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli)).WithRetention(50 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, "runner-1", mock.Anything).Return(nil).Once()
+	mockCli.On("ContainerStop", mock.Anything, "runner-1", mock.Anything).Return(nil).Once()
+	mockCli.On("ContainerRemove", mock.Anything, "runner-1", container.RemoveOptions{Force: true, RemoveVolumes: true}).Return(nil).Once()
+
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	mgr.StartMonitor(ctx, 10*time.Millisecond, 20*time.Millisecond)
+
+	require.Eventually(t, func() bool { return len(mgr.Runners()) == 0 }, time.Second, 10*time.Millisecond)
+	mockCli.AssertExpectations(t)
+}
+
+func TestGetOrStart_RecreatesDeadRunner(t *testing.T) {
+	tests := []struct {
+		name    string
+		inspect container.InspectResponse
+		err     error
+	}{
+		{"Exited", inspectState(container.StateExited), nil},
+		{"RemovedOutsideMonarch", container.InspectResponse{}, cerrdefs.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCli := new(MockDockerClient)
+			mgr := runner.NewManager(runner.NewDocker(mockCli))
+			ctx := context.Background()
+
+			mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+			mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+			mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockCli.On("ContainerInspect", mock.Anything, "runner-1").Return(tt.inspect, tt.err).Once()
+			mockCli.On("ContainerRemove", mock.Anything, "runner-1", mock.Anything).Return(tt.err).Once()
+
+			_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+			require.NoError(t, err)
+
+			id, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+			require.NoError(t, err)
+			assert.Equal(t, "runner-2", id)
+			mockCli.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrStart_ReplacesStoppedRunnerWithStaleImage(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerCreate", mock.Anything, mock.MatchedBy(func(cfg *container.Config) bool {
+		return cfg.Image == "golang@sha256:new"
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockCli.On("ContainerStop", mock.Anything, "runner-1", mock.Anything).Return(nil).Once()
+	mockCli.On("ContainerRemove", mock.Anything, "runner-1", mock.Anything).Return(nil).Once()
+
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:old")
+	require.NoError(t, err)
+	_, err = mgr.Stop(ctx, "runner-1")
+	require.NoError(t, err)
+
+	id, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:new")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-2", id)
+	mockCli.AssertExpectations(t)
+}
+
+func TestShutdown_KeepsRunningRunners(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
 	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-		Return(container.CreateResponse{ID: "new-container"}, nil).Once()
+		Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockCli.On("ContainerStop", mock.Anything, "runner-2", mock.Anything).Return(nil).Once()
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	_, err = mgr.GetOrStart(ctx, project("proj-2", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	_, err = mgr.Stop(ctx, "runner-2")
+	require.NoError(t, err)
+
+	// Only the stopped runner goes; the running one is left to adopt.
+	mockCli.On("ContainerRemove", ctx, "runner-2", container.RemoveOptions{Force: true, RemoveVolumes: true}).
+		Return(nil).Once()
+
+	kept, removed, err := mgr.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 1, removed)
+	assert.Empty(t, mgr.Runners())
+
+	mockCli.AssertNotCalled(t, "ContainerRemove", mock.Anything, "runner-1", mock.Anything)
+	mockCli.AssertExpectations(t)
+}
+
+func TestShutdown_RemovesRunnersWhenConfigured(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli)).WithRemoveOnShutdown(true)
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
 	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil).Once()
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	mockCli.On("ContainerRemove", ctx, "runner-1", container.RemoveOptions{Force: true, RemoveVolumes: true}).
+		Return(nil).Once()
+
+	kept, removed, err := mgr.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, kept)
+	assert.Equal(t, 1, removed)
+
+	// Nothing left to remove on a second call.
+	_, removed, err = mgr.Shutdown(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	mockCli.AssertExpectations(t)
+}
+
+func TestStopProject_RemovesOnlyThatProject(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "stack-1"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	_, err = mgr.GetOrStart(ctx, project("proj-2", "stack-1"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	mockCli.On("ContainerRemove", ctx, "runner-1", container.RemoveOptions{Force: true, RemoveVolumes: true}).
+		Return(nil).Once()
+
+	removed, err := mgr.StopProject(ctx, "proj-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
 
-	id, err := mgr.GetOrStart(ctx, "proj-1", "stack-1")
+	// proj-2's runner is still warm.
+	mockCli.On("ContainerInspect", mock.Anything, "runner-2").Return(inspectState(container.StateRunning), nil).Once()
+	id, err := mgr.GetOrStart(ctx, project("proj-2", "stack-1"), runner.Profile{}, "alpine")
 	assert.NoError(t, err)
-	assert.Equal(t, "new-container", id)
+	assert.Equal(t, "runner-2", id)
+
+	mockCli.AssertExpectations(t)
+}
+
+func TestGetOrStart_MountsWorkspaceReadOnly(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", ctx, mock.Anything, mock.MatchedBy(func(hc *container.HostConfig) bool {
+		return assert.ObjectsAreEqual([]mount.Mount{{
+			Type:     mount.TypeBind,
+			Source:   "/src/proj-1",
+			Target:   runner.WorkspaceDir,
+			ReadOnly: true,
+		}}, hc.Mounts)
+	}), mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	mockCli.On("ContainerStart", ctx, "runner-1", container.StartOptions{}).Return(nil)
+
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	mockCli.AssertExpectations(t)
+}
+
+func TestGetOrStart_WritableOverlay(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	var overlay string
+	mockCli.On("ContainerCreate", ctx, mock.Anything, mock.MatchedBy(func(hc *container.HostConfig) bool {
+		if len(hc.Mounts) != 1 {
+			return false
+		}
+		m := hc.Mounts[0]
+		if m.Type != mount.TypeVolume || m.Target != runner.WorkspaceDir || m.ReadOnly {
+			return false
+		}
+		opts := m.VolumeOptions.DriverConfig.Options
+		overlay = opts["o"]
+		return opts["type"] == "overlay" && strings.HasPrefix(overlay, "lowerdir=/src/proj-1,upperdir=")
+	}), mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	mockCli.On("ContainerStart", ctx, "runner-1", container.StartOptions{}).Return(nil)
+	mockCli.On("ContainerRemove", ctx, "runner-1", container.RemoveOptions{Force: true, RemoveVolumes: true}).Return(nil)
+
+	p := project("proj-1", "go")
+	p.Writable = true
+	_, err := mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	upper := strings.TrimPrefix(strings.Split(overlay, ",")[1], "upperdir=")
+	assert.DirExists(t, upper)
+
+	// The upper layer goes away with the runner.
+	_, err = mgr.StopProject(ctx, "proj-1")
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Dir(upper))
+}
+
+func TestGetOrStart_MountsSnapshots(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	// The snapshot dir is mounted read-only and replaces a runner that
+	// mounted the live project root.
+	mockCli.On("ContainerCreate", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerStart", ctx, "runner-1", container.StartOptions{}).Return(nil)
+	mockCli.On("ContainerRemove", ctx, "runner-1", container.RemoveOptions{Force: true, RemoveVolumes: true}).Return(nil)
+	mockCli.On("ContainerCreate", ctx, mock.MatchedBy(func(cfg *container.Config) bool {
+		return cfg.Labels["monarch.workspace"] == "/snapshots/proj-1"
+	}), mock.MatchedBy(func(hc *container.HostConfig) bool {
+		return assert.ObjectsAreEqual([]mount.Mount{{
+			Type:     mount.TypeBind,
+			Source:   "/snapshots/proj-1",
+			Target:   runner.WorkspaceDir,
+			ReadOnly: true,
+		}}, hc.Mounts)
+	}), mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+	mockCli.On("ContainerStart", ctx, "runner-2", container.StartOptions{}).Return(nil)
+	mockCli.On("ContainerInspect", ctx, "runner-2").Return(inspectState(container.StateRunning), nil)
+
+	p := project("proj-1", "go")
+	id, err := mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-1", id)
+
+	p.Snapshots = "/snapshots/proj-1"
+	p.Snapshot = "attempt-1"
+	id, err = mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-2", id)
+
+	// Later snapshots share the runner.
+	p.Snapshot = "attempt-2"
+	id, err = mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-2", id)
+
+	mockCli.AssertExpectations(t)
+}
+
+func TestGetOrStart_WritableMountsOnlyItsAttemptSnapshot(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	// Each attempt's snapshot is mounted writable, without an overlay, in
+	// a runner of its own, so no attempt can touch another's files.
+	for i, id := range []string{"runner-1", "runner-2"} {
+		src := fmt.Sprintf("/snapshots/proj-1/attempt-%d", i+1)
+		mockCli.On("ContainerCreate", ctx, mock.Anything, mock.MatchedBy(func(hc *container.HostConfig) bool {
+			return assert.ObjectsAreEqual([]mount.Mount{{
+				Type:   mount.TypeBind,
+				Source: src,
+				Target: runner.WorkspaceDir,
+			}}, hc.Mounts)
+		}), mock.Anything, mock.Anything, "").
+			Return(container.CreateResponse{ID: id}, nil).Once()
+		mockCli.On("ContainerStart", ctx, id, container.StartOptions{}).Return(nil)
+	}
+
+	p := project("proj-1", "go")
+	p.Writable = true
+	p.Snapshots = "/snapshots/proj-1"
+	p.Snapshot = "attempt-1/app"
+	id, err := mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-1", id)
+
+	p.Snapshot = "attempt-2/app"
+	id, err = mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	assert.Equal(t, "runner-2", id)
+	// The first attempt's runner is left to it.
+	assert.Len(t, mgr.Runners(), 2)
+	mockCli.AssertNotCalled(t, "ContainerRemove", mock.Anything, "runner-1", mock.Anything)
+
+	p.Snapshot = ""
+	_, err = mgr.GetOrStart(ctx, p, runner.Profile{}, "alpine")
+	assert.ErrorContains(t, err, "no snapshot to mount")
+
+	mockCli.AssertExpectations(t)
+}
+
+func TestGetOrStart_RequiresWorkspacePath(t *testing.T) {
+	mgr := runner.NewManager(runner.NewDocker(new(MockDockerClient)))
+
+	_, err := mgr.GetOrStart(context.Background(), runner.Project{ID: "proj-1", Stack: "go"}, runner.Profile{}, "alpine")
 
+	assert.ErrorContains(t, err, "no workspace path")
+}
+
+func TestGetOrStart_HardenedHostConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile runner.Profile
+		want    func(t *testing.T, cfg *container.Config, hc *container.HostConfig)
+	}{
+		{
+			name:    "Defaults",
+			profile: runner.Profile{Resources: runner.DefaultResources},
+			want: func(t *testing.T, cfg *container.Config, hc *container.HostConfig) {
+				assert.Equal(t, runner.RunnerUser, cfg.User)
+				assert.Equal(t, []string{"ALL"}, []string(hc.CapDrop))
+				assert.Equal(t, []string{"no-new-privileges:true"}, hc.SecurityOpt)
+				assert.True(t, hc.ReadonlyRootfs)
+				assert.Equal(t, container.NetworkMode("none"), hc.NetworkMode)
+				assert.Equal(t, int64(2<<30), hc.Memory)
+				assert.Equal(t, hc.Memory, hc.MemorySwap, "no swap")
+				assert.Equal(t, int64(2e9), hc.NanoCPUs)
+				require.NotNil(t, hc.PidsLimit)
+				assert.Equal(t, int64(512), *hc.PidsLimit)
+				assert.Equal(t, "rw,nosuid,nodev,size=536870912", hc.Tmpfs["/tmp"])
+			},
+		},
+		{
+			name:    "NetworkOptIn",
+			profile: runner.Profile{Network: true},
+			want: func(t *testing.T, cfg *container.Config, hc *container.HostConfig) {
+				assert.Equal(t, container.NetworkMode("bridge"), hc.NetworkMode)
+				assert.Zero(t, hc.Memory)
+				assert.Nil(t, hc.PidsLimit)
+				assert.True(t, hc.ReadonlyRootfs)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCli := new(MockDockerClient)
+			mgr := runner.NewManager(runner.NewDocker(mockCli))
+			ctx := context.Background()
+
+			var cfg *container.Config
+			var hc *container.HostConfig
+			mockCli.On("ContainerCreate", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "").
+				Run(func(args mock.Arguments) {
+					cfg = args.Get(1).(*container.Config)
+					hc = args.Get(2).(*container.HostConfig)
+				}).
+				Return(container.CreateResponse{ID: "runner-1"}, nil)
+			mockCli.On("ContainerStart", ctx, "runner-1", container.StartOptions{}).Return(nil)
+
+			_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), tt.profile, "alpine")
+			require.NoError(t, err)
+			tt.want(t, cfg, hc)
+		})
+	}
+}
+
+func TestGetOrStart_SeparateRunnerPerProfile(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "offline"}, nil).Once()
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "online"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockCli.On("ContainerInspect", mock.Anything, "offline").Return(inspectState(container.StateRunning), nil)
+
+	offline, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+	online, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{Network: true}, "alpine")
+	require.NoError(t, err)
+	again, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.NoError(t, err)
+
+	assert.Equal(t, "offline", offline)
+	assert.Equal(t, "online", online)
+	assert.Equal(t, "offline", again)
+}
+
+func TestGetOrStart_InvalidLimit(t *testing.T) {
+	mgr := runner.NewManager(runner.NewDocker(new(MockDockerClient)))
+
+	_, err := mgr.GetOrStart(context.Background(), project("proj-1", "go"),
+		runner.Profile{Resources: gates.Resources{Memory: "lots"}}, "alpine")
+
+	assert.ErrorContains(t, err, `invalid memory limit "lots"`)
+}
+
+func TestRunners_StopAndRestart(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, "runner-1", mock.Anything).Return(nil)
+	mockCli.On("ContainerStop", mock.Anything, "runner-1", mock.Anything).Return(nil)
+
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:abc")
+	require.NoError(t, err)
+
+	runners := mgr.Runners()
+	require.Len(t, runners, 1)
+	r := runners[0]
+	assert.Equal(t, "runner-1", r.ID)
+	assert.Equal(t, "proj-1", r.ProjectID)
+	assert.Equal(t, "golang@sha256:abc", r.Image)
+	assert.Equal(t, runner.StateIdle, r.State)
+	assert.False(t, r.StartedAt.IsZero())
+	assert.Nil(t, r.LastExec)
+
+	stopped, err := mgr.Stop(ctx, "runner-1")
+	require.NoError(t, err)
+	assert.Equal(t, runner.StateStopped, stopped.State)
+
+	restarted, err := mgr.Restart(ctx, "runner-1")
+	require.NoError(t, err)
+	assert.Equal(t, runner.StateIdle, restarted.State)
+	assert.True(t, restarted.StartedAt.After(r.StartedAt) || restarted.StartedAt.Equal(r.StartedAt))
+
+	_, err = mgr.Stop(ctx, "nope")
+	assert.ErrorIs(t, err, runner.ErrRunnerNotFound)
+	mockCli.AssertNumberOfCalls(t, "ContainerStart", 2)
+}
+
+func TestGetOrStart_ConcurrentCallersShareStart(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { <-release }).Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	mockCli.On("ContainerStart", mock.Anything, "runner-1", mock.Anything).Return(nil).Once()
+	mockCli.On("ContainerInspect", mock.Anything, "runner-1").Return(inspectState(container.StateRunning), nil).Once()
+
+	ids := make(chan string, 2)
+	for range 2 {
+		go func() {
+			id, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "alpine")
+			assert.NoError(t, err)
+			ids <- id
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		runners := mgr.Runners()
+		return len(runners) == 1 && runners[0].State == runner.StateStarting
+	}, time.Second, 5*time.Millisecond)
+	_, err := mgr.Restart(ctx, "")
+	assert.ErrorIs(t, err, runner.ErrRunnerNotFound, "a starting runner has no ID to control yet")
+
+	close(release)
+	assert.Equal(t, "runner-1", <-ids)
+	assert.Equal(t, "runner-1", <-ids)
+	assert.Equal(t, runner.StateIdle, mgr.Runners()[0].State)
 	mockCli.AssertExpectations(t)
 }
+
+func TestGetOrStart_FailedStartIsReported(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	mgr := runner.NewManager(runner.NewDocker(mockCli))
+
+	mockCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{}, assert.AnError).Once()
+
+	_, err := mgr.GetOrStart(context.Background(), project("proj-1", "go"), runner.Profile{}, "alpine")
+	require.Error(t, err)
+
+	runners := mgr.Runners()
+	require.Len(t, runners, 1)
+	assert.Equal(t, runner.StateStopped, runners[0].State)
+	assert.Contains(t, runners[0].LastError, assert.AnError.Error())
+}
@@ -0,0 +1,46 @@
+package parser
+
+import "strings"
+
+// ForCommand picks the parser for a gate command that emits machine-readable
+// output, or returns nil if the command's output is free-form text.
+func ForCommand(command string) Parser {
+	return ForArgs(strings.Fields(command))
+}
+
+// ForArgs is ForCommand for a command already split into arguments.
+func ForArgs(args []string) Parser {
+	switch {
+	case hasArgs(args, "go", "test") && hasArgs(args, "-json"):
+		return &GoTestParser{}
+	case hasTool(args, "eslint") && (hasArgs(args, "-f", "json") || hasArgs(args, "--format", "json") || hasArgs(args, "--format=json")):
+		return &ESLintParser{}
+	}
+	return nil
+}
+
+// hasArgs reports whether want appears as a contiguous run in args.
+func hasArgs(args []string, want ...string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		match := true
+		for j, w := range want {
+			if args[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTool(args []string, tool string) bool {
+	for _, arg := range args {
+		if arg == tool || strings.HasSuffix(arg, "/"+tool) {
+			return true
+		}
+	}
+	return false
+}
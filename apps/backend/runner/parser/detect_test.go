@@ -0,0 +1,28 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/monarch-dev/monarch/runner/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    parser.Parser
+	}{
+		{"go test -json ./...", &parser.GoTestParser{}},
+		{"go test ./...", nil},
+		{"npx eslint -f json .", &parser.ESLintParser{}},
+		{"node_modules/.bin/eslint --format=json src", &parser.ESLintParser{}},
+		{"npx eslint .", nil},
+		{"make lint", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.want, parser.ForCommand(tt.command))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+	"github.com/monarch-dev/monarch/gates"
+)
+
+// DefaultResources apply where no config layer sets a limit.
+var DefaultResources = gates.Resources{Memory: "2g", CPUs: 2, Pids: 512, Tmpfs: "512m"}
+
+// RunnerUser is the unprivileged uid:gid gates run as.
+const RunnerUser = "1000:1000"
+
+// runnerEnv points caches at the writable /tmp, since the root filesystem is
+// read-only and the runner user has no home directory.
+var runnerEnv = []string{"HOME=/tmp", "XDG_CACHE_HOME=/tmp/.cache"}
+
+// Profile is what a runner is started with beyond its image. Gates whose
+// profiles differ get separate runners.
+type Profile struct {
+	Resources gates.Resources
+	// Network gives the runner network access; runners are offline
+	// otherwise.
+	Network bool
+}
+
+// key identifies the project's runner for stack under this profile.
+func (p Profile) key(stack string) string {
+	return stack + "|" + p.String()
+}
+
+func (p Profile) String() string {
+	r := p.Resources
+	return fmt.Sprintf("mem=%s cpus=%g pids=%d tmpfs=%s net=%t", r.Memory, r.CPUs, r.Pids, r.Tmpfs, p.Network)
+}
+
+// limits are a profile's resource limits in bytes and nano-CPUs; zero
+// means unlimited.
+type limits struct {
+	memory   int64
+	nanoCPUs int64
+	pids     int64
+	tmpfs    int64
+}
+
+// limits parses the profile's resource limits.
+func (p Profile) limits() (limits, error) {
+	var l limits
+	r := p.Resources
+	if r.Memory != "" {
+		mem, err := units.RAMInBytes(r.Memory)
+		if err != nil {
+			return l, fmt.Errorf("invalid memory limit %q: %w", r.Memory, err)
+		}
+		l.memory = mem
+	}
+	if r.CPUs < 0 {
+		return l, fmt.Errorf("invalid cpu limit %g", r.CPUs)
+	}
+	l.nanoCPUs = int64(r.CPUs * 1e9)
+	if r.Pids < 0 {
+		return l, fmt.Errorf("invalid pids limit %d", r.Pids)
+	}
+	l.pids = r.Pids
+	if r.Tmpfs != "" {
+		size, err := units.RAMInBytes(r.Tmpfs)
+		if err != nil {
+			return l, fmt.Errorf("invalid tmpfs size %q: %w", r.Tmpfs, err)
+		}
+		l.tmpfs = size
+	}
+	return l, nil
+}
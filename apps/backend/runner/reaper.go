@@ -2,36 +2,215 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/filters"
+	cerrdefs "github.com/containerd/errdefs"
 )
 
-// DockerClient defines the subset of client.Client methods we use
-// This interface allows for mocking in tests
-type DockerClient interface {
-	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
-	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+// DefaultReapGrace is how old a resource must be before the Reaper removes
+// it. The Manager tracks a runner from the moment its container is created,
+// so this only has to cover that moment and volumes and networks created
+// ahead of their container.
+const DefaultReapGrace = 5 * time.Minute
+
+// managedLabels selects everything Monarch creates.
+var managedLabels = map[string]string{"monarch.managed": "true"}
+
+// Reaped is a resource a reaper pass removed, or would have removed in a
+// dry run.
+type Reaped struct {
+	// Kind is "container", "volume" or "network".
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// ReapReport is the outcome of one reaper pass.
+type ReapReport struct {
+	At     time.Time `json:"at"`
+	DryRun bool      `json:"dry_run"`
+	Reaped []Reaped  `json:"reaped"`
+	Error  string    `json:"error,omitempty"`
 }
 
-func ReapZombies(ctx context.Context, cli DockerClient) (int, error) {
-	args := filters.NewArgs()
-	args.Add("label", "monarch.managed=true")
+// ReaperStats are the Reaper's totals since it was created. Dry runs count
+// as passes but reap nothing.
+type ReaperStats struct {
+	Passes int `json:"passes"`
+	// Reaped counts removed resources by kind.
+	Reaped map[string]int `json:"reaped"`
+	Errors int            `json:"errors"`
+	Last   *ReapReport    `json:"last,omitempty"`
+}
 
-	containers, err := cli.ContainerList(ctx, container.ListOptions{
-		All:     true,
-		Filters: args,
-	})
-	if err != nil {
-		return 0, err
+// Reaper removes what this instance's runners leave behind while Monarch
+// runs: containers the Manager no longer tracks, e.g. after a crashed exec,
+// and managed volumes and networks nothing uses. Resources labelled with
+// another instance's ID are never touched, so instances can share a Docker
+// daemon or namespace.
+type Reaper struct {
+	rt     Runtime
+	res    Resources
+	mgr    *Manager
+	grace  time.Duration
+	dryRun bool
+
+	mu    sync.Mutex
+	stats ReaperStats
+}
+
+func NewReaper(rt Runtime, mgr *Manager) *Reaper {
+	return &Reaper{
+		rt:    rt,
+		mgr:   mgr,
+		grace: DefaultReapGrace,
+		stats: ReaperStats{Reaped: make(map[string]int)},
+	}
+}
+
+// WithResources also reaps volumes and networks; nil reaps containers only.
+func (rp *Reaper) WithResources(res Resources) *Reaper {
+	rp.res = res
+	return rp
+}
+
+// WithGrace sets how old a resource must be before it is reaped.
+func (rp *Reaper) WithGrace(d time.Duration) *Reaper {
+	rp.grace = d
+	return rp
+}
+
+// WithDryRun makes the background loop only log what it would reap.
+func (rp *Reaper) WithDryRun(dryRun bool) *Reaper {
+	rp.dryRun = dryRun
+	return rp
+}
+
+// Start runs a reaper pass every interval until ctx ends.
+func (rp *Reaper) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = rp.Reap(ctx, rp.dryRun)
+			}
+		}
+	}()
+}
+
+// Stats returns the Reaper's totals and its last report.
+func (rp *Reaper) Stats() ReaperStats {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	stats := rp.stats
+	stats.Reaped = make(map[string]int, len(rp.stats.Reaped))
+	for k, n := range rp.stats.Reaped {
+		stats.Reaped[k] = n
 	}
+	return stats
+}
+
+// Reap runs one pass, removing what it finds unless dryRun is set. Every
+// resource it reaps, or would reap, is logged and listed in the report.
+// Failures don't stop the pass; they are joined in the returned error.
+func (rp *Reaper) Reap(ctx context.Context, dryRun bool) (ReapReport, error) {
+	report := ReapReport{At: time.Now(), DryRun: dryRun, Reaped: []Reaped{}}
+	cutoff := report.At.Add(-rp.grace)
+	var errs []error
 
-	count := 0
+	containers, err := rp.rt.List(ctx, managedLabels)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list containers: %w", err))
+	}
 	for _, c := range containers {
-		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err == nil {
-			count++
+		if !rp.mgr.owns(c.Labels) || c.Created.After(cutoff) || rp.mgr.tracks(c.ID) {
+			continue
+		}
+		overlay := c.Labels["monarch.overlay"]
+		err := rp.reap(ctx, &report, Reaped{Kind: "container", ID: c.ID, Reason: "untracked"}, func(ctx context.Context, id string) error {
+			if err := rp.rt.Remove(ctx, id); err != nil {
+				return err
+			}
+			if overlay != "" {
+				_ = os.RemoveAll(overlay)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if rp.res != nil {
+		errs = append(errs, rp.reapUnused(ctx, &report, "volume", rp.res.Volumes, rp.res.RemoveVolume, cutoff)...)
+		errs = append(errs, rp.reapUnused(ctx, &report, "network", rp.res.Networks, rp.res.RemoveNetwork, cutoff)...)
+	}
+
+	err = errors.Join(errs...)
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	rp.mu.Lock()
+	rp.stats.Passes++
+	rp.stats.Errors += len(errs)
+	if !dryRun {
+		for _, item := range report.Reaped {
+			rp.stats.Reaped[item.Kind]++
+		}
+	}
+	last := report
+	rp.stats.Last = &last
+	rp.mu.Unlock()
+
+	if len(report.Reaped) > 0 || err != nil {
+		slog.Info("reaper pass finished", "reaped", len(report.Reaped), "errors", len(errs), "dry_run", dryRun)
+	}
+	return report, err
+}
+
+// reapUnused reaps this instance's volumes or networks that no container
+// uses.
+func (rp *Reaper) reapUnused(ctx context.Context, report *ReapReport, kind string,
+	list func(context.Context, map[string]string) ([]Resource, error),
+	remove func(context.Context, string) error, cutoff time.Time) []error {
+	resources, err := list(ctx, managedLabels)
+	if err != nil {
+		return []error{fmt.Errorf("failed to list %ss: %w", kind, err)}
+	}
+	var errs []error
+	for _, res := range resources {
+		if !rp.mgr.owns(res.Labels) || res.InUse || res.Created.After(cutoff) {
+			continue
+		}
+		if err := rp.reap(ctx, report, Reaped{Kind: kind, ID: res.ID, Reason: "unused"}, remove); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// reap removes item, unless the report is a dry run, and records it.
+// Anything already gone was removed by someone else and isn't reported.
+func (rp *Reaper) reap(ctx context.Context, report *ReapReport, item Reaped, remove func(context.Context, string) error) error {
+	if !report.DryRun {
+		if err := remove(ctx, item.ID); errors.Is(err, ErrContainerNotFound) || cerrdefs.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			slog.Warn("failed to reap runner resource", "kind", item.Kind, "id", item.ID, "reason", item.Reason, "error", err)
+			return fmt.Errorf("failed to remove %s %s %s: %w", item.Reason, item.Kind, item.ID, err)
 		}
 	}
-	return count, nil
+	report.Reaped = append(report.Reaped, item)
+	slog.Info("reaped runner resource", "kind", item.Kind, "id", item.ID, "reason", item.Reason, "dry_run", report.DryRun)
+	return nil
 }
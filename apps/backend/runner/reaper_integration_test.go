@@ -47,9 +47,11 @@ func TestReapZombies_Integration(t *testing.T) {
 	}
 
 	// 2. Reap
-	count, err := runner.ReapZombies(ctx, cli)
+	docker := runner.NewDocker(cli)
+	reaper := runner.NewReaper(docker, runner.NewManager(docker)).WithResources(docker).WithGrace(0)
+	report, err := reaper.Reap(ctx, false)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, count)
+	assert.Contains(t, report.Reaped, runner.Reaped{Kind: "container", ID: resp.ID, Reason: "untracked"})
 
 	// 3. Verify gone
 	_, err = cli.ContainerInspect(ctx, resp.ID)
@@ -0,0 +1,207 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// managedList matches the reaper's listing of Monarch's containers, as
+// opposed to the unfiltered listing that finds what volumes and networks
+// are in use.
+var managedList = mock.MatchedBy(func(o container.ListOptions) bool { return o.Filters.Len() > 0 })
+
+func TestReaper_ReapsUntrackedContainers(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	mgr := runner.NewManager(docker).WithInstance("me")
+	ctx := context.Background()
+
+	mockCli.On("ContainerCreate", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "tracked"}, nil)
+	mockCli.On("ContainerStart", ctx, "tracked", mock.Anything).Return(nil)
+	_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:new")
+	require.NoError(t, err)
+
+	young := runnerContainer("young", "me", "proj-3", "running", "Up 1 second", "golang@sha256:new")
+	young.Created = time.Now().Unix()
+	mockCli.On("ContainerList", ctx, managedList).Return([]types.Container{
+		runnerContainer("tracked", "me", "proj-1", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("orphan", "me", "proj-2", "running", "Up 5 minutes", "golang@sha256:new"),
+		runnerContainer("foreign", "other", "proj-2", "running", "Up 5 minutes", "golang@sha256:new"),
+		young,
+	}, nil)
+	mockCli.On("ContainerRemove", ctx, "orphan", container.RemoveOptions{Force: true, RemoveVolumes: true}).Return(nil).Once()
+
+	report, err := runner.NewReaper(docker, mgr).Reap(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []runner.Reaped{{Kind: "container", ID: "orphan", Reason: "untracked"}}, report.Reaped)
+	mockCli.AssertExpectations(t)
+	for _, id := range []string{"tracked", "foreign", "young"} {
+		mockCli.AssertNotCalled(t, "ContainerRemove", ctx, id, mock.Anything)
+	}
+}
+
+func TestReaper_SparesRunnersStillStarting(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	mgr := runner.NewManager(docker).WithInstance("me")
+	ctx := context.Background()
+
+	// The runner is created but its start hangs, as a slow image pull does.
+	starting, release := make(chan struct{}), make(chan struct{})
+	mockCli.On("ContainerCreate", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "").
+		Return(container.CreateResponse{ID: "starting"}, nil)
+	mockCli.On("ContainerStart", ctx, "starting", mock.Anything).Run(func(mock.Arguments) {
+		close(starting)
+		<-release
+	}).Return(nil)
+	done := make(chan error)
+	go func() {
+		_, err := mgr.GetOrStart(ctx, project("proj-1", "go"), runner.Profile{}, "golang@sha256:new")
+		done <- err
+	}()
+	<-starting
+
+	mockCli.On("ContainerList", ctx, managedList).Return([]types.Container{
+		runnerContainer("starting", "me", "proj-1", "created", "Created", "golang@sha256:new"),
+	}, nil)
+	report, err := runner.NewReaper(docker, mgr).WithGrace(0).Reap(ctx, false)
+
+	require.NoError(t, err)
+	assert.Empty(t, report.Reaped)
+	mockCli.AssertNotCalled(t, "ContainerRemove", ctx, "starting", mock.Anything)
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestReaper_ReapsUnusedVolumesAndNetworks(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	ctx := context.Background()
+	mine := map[string]string{"monarch.managed": "true", "monarch.instance": "me"}
+	theirs := map[string]string{"monarch.managed": "true", "monarch.instance": "other"}
+	old := time.Now().Add(-time.Hour)
+
+	mockCli.On("ContainerList", ctx, managedList).Return([]types.Container{}, nil)
+	mockCli.On("ContainerList", ctx, container.ListOptions{All: true}).Return([]types.Container{{
+		ID:     "app",
+		Mounts: []container.MountPoint{{Type: mount.TypeVolume, Name: "busy-vol"}},
+		NetworkSettings: &container.NetworkSettingsSummary{Networks: map[string]*network.EndpointSettings{
+			"busy": {NetworkID: "busy-net"},
+		}},
+	}}, nil)
+	mockCli.On("VolumeList", ctx, mock.Anything).Return(volume.ListResponse{Volumes: []*volume.Volume{
+		{Name: "orphan-vol", Labels: mine, CreatedAt: old.Format(time.RFC3339)},
+		{Name: "busy-vol", Labels: mine, CreatedAt: old.Format(time.RFC3339)},
+		{Name: "foreign-vol", Labels: theirs, CreatedAt: old.Format(time.RFC3339)},
+	}}, nil)
+	mockCli.On("NetworkList", ctx, mock.Anything).Return([]network.Summary{
+		{ID: "orphan-net", Labels: mine, Created: old},
+		{ID: "busy-net", Labels: mine, Created: old},
+		{ID: "young-net", Labels: mine, Created: time.Now()},
+	}, nil)
+	mockCli.On("VolumeRemove", ctx, "orphan-vol", false).Return(nil).Once()
+	mockCli.On("NetworkRemove", ctx, "orphan-net").Return(nil).Once()
+
+	reaper := runner.NewReaper(docker, runner.NewManager(docker).WithInstance("me")).WithResources(docker)
+	report, err := reaper.Reap(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []runner.Reaped{
+		{Kind: "volume", ID: "orphan-vol", Reason: "unused"},
+		{Kind: "network", ID: "orphan-net", Reason: "unused"},
+	}, report.Reaped)
+	assert.Equal(t, map[string]int{"volume": 1, "network": 1}, reaper.Stats().Reaped)
+	mockCli.AssertExpectations(t)
+}
+
+func TestReaper_DryRunOnlyReports(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	ctx := context.Background()
+	mockCli.On("ContainerList", ctx, managedList).Return([]types.Container{
+		runnerContainer("orphan", "me", "proj-1", "exited", "Exited (0)", "golang@sha256:new"),
+	}, nil)
+
+	reaper := runner.NewReaper(docker, runner.NewManager(docker).WithInstance("me"))
+	report, err := reaper.Reap(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []runner.Reaped{{Kind: "container", ID: "orphan", Reason: "untracked"}}, report.Reaped)
+	mockCli.AssertNotCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
+
+	stats := reaper.Stats()
+	assert.Equal(t, 1, stats.Passes)
+	assert.Empty(t, stats.Reaped, "dry runs reap nothing")
+	require.NotNil(t, stats.Last)
+	assert.Equal(t, report, *stats.Last)
+}
+
+func TestReaper_ReportsFailures(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	ctx := context.Background()
+	mockCli.On("ContainerList", ctx, managedList).Return([]types.Container{
+		runnerContainer("stuck", "me", "proj-1", "running", "Up", "golang@sha256:new"),
+		runnerContainer("gone", "me", "proj-2", "running", "Up", "golang@sha256:new"),
+	}, nil)
+	mockCli.On("ContainerRemove", ctx, "stuck", mock.Anything).Return(errors.New("device or resource busy"))
+	mockCli.On("ContainerRemove", ctx, "gone", mock.Anything).Return(cerrdefs.ErrNotFound)
+
+	reaper := runner.NewReaper(docker, runner.NewManager(docker).WithInstance("me"))
+	report, err := reaper.Reap(ctx, false)
+	assert.ErrorContains(t, err, "failed to remove untracked container stuck: device or resource busy")
+	assert.Equal(t, err.Error(), report.Error)
+	assert.Empty(t, report.Reaped, "a container removed meanwhile isn't reported")
+	assert.Equal(t, 1, reaper.Stats().Errors)
+}
+
+func TestReaper_Handlers(t *testing.T) {
+	mockCli := new(MockDockerClient)
+	docker := runner.NewDocker(mockCli)
+	mockCli.On("ContainerList", mock.Anything, managedList).Return([]types.Container{
+		runnerContainer("orphan", "me", "proj-1", "running", "Up", "golang@sha256:new"),
+	}, nil)
+	reaper := runner.NewReaper(docker, runner.NewManager(docker).WithInstance("me"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /reaper", reaper.StatsHandler)
+	mux.HandleFunc("POST /reaper/run", reaper.RunHandler)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/reaper/run?dry_run=true", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report runner.ReapReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []runner.Reaped{{Kind: "container", ID: "orphan", Reason: "untracked"}}, report.Reaped)
+	mockCli.AssertNotCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/reaper/run?dry_run=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/reaper", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats runner.ReaperStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 1, stats.Passes)
+	require.NotNil(t, stats.Last)
+	assert.True(t, stats.Last.DryRun)
+}
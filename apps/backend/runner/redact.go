@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces secret values in command output.
+const Redacted = "[REDACTED]"
+
+// redactor replaces secret values in output. A nil redactor has no secrets.
+type redactor struct {
+	r *strings.Replacer
+	// patterns are what r replaces, longest first.
+	patterns [][]byte
+}
+
+// newRedactor returns a redactor for secrets, or nil if there are none.
+// Output is streamed a line at a time, so each line of a multi-line secret,
+// such as a PEM key, is also redacted on its own.
+func newRedactor(secrets []string) *redactor {
+	var patterns []string
+	for _, s := range secrets {
+		patterns = append(patterns, s)
+		if strings.Contains(s, "\n") {
+			for _, line := range strings.Split(s, "\n") {
+				patterns = append(patterns, strings.TrimSuffix(line, "\r"))
+			}
+		}
+	}
+	patterns = slices.DeleteFunc(patterns, func(p string) bool { return strings.TrimSpace(p) == "" })
+	if len(patterns) == 0 {
+		return nil
+	}
+	// The replacer tries patterns in order, so a secret containing another
+	// is redacted whole.
+	slices.SortFunc(patterns, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	patterns = slices.Compact(patterns)
+
+	rd := &redactor{}
+	pairs := make([]string, 0, 2*len(patterns))
+	for _, p := range patterns {
+		pairs = append(pairs, p, Redacted)
+		rd.patterns = append(rd.patterns, []byte(p))
+	}
+	rd.r = strings.NewReplacer(pairs...)
+	return rd
+}
+
+// hold is how many bytes past a cut must be read before cut can tell
+// whether a secret spans it.
+func (r *redactor) hold() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.patterns[0]) - 1
+}
+
+// cut returns where to split text, at n or before it, so that no secret
+// spans the split and each piece can be redacted on its own. text must
+// extend hold bytes past n, or be all there is. A secret starting at 0 is
+// kept whole in the first piece instead.
+func (r *redactor) cut(text []byte, n int) int {
+	if r == nil {
+		return n
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, p := range r.patterns {
+			// Any match in this window spans n.
+			lo, hi := max(0, n-len(p)+1), min(len(text), n+len(p)-1)
+			if lo >= hi {
+				continue
+			}
+			i := bytes.Index(text[lo:hi], p)
+			if i < 0 {
+				continue
+			}
+			if lo+i == 0 {
+				return len(p)
+			}
+			n, moved = lo+i, true
+		}
+	}
+	return n
+}
+
+func (r *redactor) redact(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.r.Replace(s)
+}
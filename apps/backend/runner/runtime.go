@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrContainerNotFound is returned by a Runtime for a container that
+// doesn't exist, e.g. because it was removed outside Monarch.
+var ErrContainerNotFound = errors.New("container not found")
+
+// Runtime runs runner containers. The Manager, Executor and Reaper only
+// go through it, so they work the same on every backend: Docker, or
+// Kubernetes pods.
+type Runtime interface {
+	// Create creates a container from spec without starting it and
+	// returns its ID.
+	Create(ctx context.Context, spec ContainerSpec) (string, error)
+	Start(ctx context.Context, id string) error
+	// Stop stops a container, which can be started again later.
+	Stop(ctx context.Context, id string) error
+	// Remove force-removes a container, running or not, and any anonymous
+	// volumes created with it.
+	Remove(ctx context.Context, id string) error
+	Inspect(ctx context.Context, id string) (ContainerInfo, error)
+	// List returns every container, running or not, carrying all of labels.
+	List(ctx context.Context, labels map[string]string) ([]ContainerInfo, error)
+	// Exec runs spec in a running container, copying its output to stdout
+	// and stderr, and returns its exit code once it exits. If ctx ends
+	// first, Exec stops following the command, which may keep running, and
+	// returns an error wrapping ctx's. Nothing is written after it returns.
+	Exec(ctx context.Context, id string, spec ExecSpec, stdout, stderr io.Writer) (int, error)
+}
+
+// ContainerSpec is what a runner container is created from.
+type ContainerSpec struct {
+	Image      string
+	Cmd        []string
+	User       string // uid:gid
+	Env        []string
+	WorkingDir string
+	Labels     map[string]string
+	Workspace  Workspace
+	Profile    Profile
+}
+
+// Workspace is the host dir mounted at WorkspaceDir.
+type Workspace struct {
+	Source   string
+	ReadOnly bool
+	// Overlay, if set, is a host dir whose upper and work subdirs hold a
+	// throwaway writable overlay over Source.
+	Overlay string
+}
+
+// ContainerInfo describes a container as a Runtime reports it.
+type ContainerInfo struct {
+	ID      string
+	Labels  map[string]string
+	Created time.Time
+	Running bool
+	// Unhealthy is set if the container's health check is failing.
+	Unhealthy bool
+}
+
+// ExecSpec is a command to run in a container. WorkingDir defaults to the
+// container's.
+type ExecSpec struct {
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+}
+
+// Resources is implemented by runtimes that create volumes and networks for
+// runners, which the Reaper removes once nothing uses them.
+type Resources interface {
+	// Volumes returns every volume carrying all of labels.
+	Volumes(ctx context.Context, labels map[string]string) ([]Resource, error)
+	RemoveVolume(ctx context.Context, id string) error
+	// Networks returns every network carrying all of labels.
+	Networks(ctx context.Context, labels map[string]string) ([]Resource, error)
+	RemoveNetwork(ctx context.Context, id string) error
+}
+
+// Resource describes a volume or network as a Runtime reports it.
+type Resource struct {
+	ID      string
+	Labels  map[string]string
+	Created time.Time
+	// InUse is set while any container, Monarch's or not, uses it.
+	InUse bool
+}
@@ -0,0 +1,213 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrJobCancelled is returned for a queued gate whose owner was cancelled.
+var ErrJobCancelled = errors.New("queued gate cancelled")
+
+// QueueObserver is told a waiting gate's 1-based place in the queue whenever
+// it changes, and 0 once the gate starts.
+type QueueObserver func(position int)
+
+type queueObserverKey struct{}
+
+type jobOwnerKey struct{}
+
+// WithQueueObserver returns a context under which RunGate reports the
+// gate's queue position to fn.
+func WithQueueObserver(ctx context.Context, fn QueueObserver) context.Context {
+	return context.WithValue(ctx, queueObserverKey{}, fn)
+}
+
+// WithJobOwner returns a context whose gates belong to owner, typically a
+// task ID, so Scheduler.Cancel can drop them from the queue.
+func WithJobOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, jobOwnerKey{}, owner)
+}
+
+type job struct {
+	project  string
+	stack    string
+	owner    string
+	observe  QueueObserver
+	position int
+	// ready is closed when the job may run; err is set first if it was
+	// cancelled instead.
+	ready chan struct{}
+	err   error
+}
+
+// Scheduler bounds how many gates run at once. Gates of one project run in
+// submission order, one at a time unless their stack allows more; across
+// projects the longest-waiting runnable gate goes first.
+type Scheduler struct {
+	workers     int
+	parallelism map[string]int
+
+	mu      sync.Mutex
+	waiting []*job
+	running int
+	// active counts running gates per project.
+	active map[string]int
+}
+
+// NewScheduler returns a Scheduler running at most workers gates at once.
+func NewScheduler(workers int) *Scheduler {
+	return &Scheduler{
+		workers: max(workers, 1),
+		active:  make(map[string]int),
+	}
+}
+
+// WithParallelism lets gates of one project with the given stacks run
+// concurrently, up to the number given per stack.
+func (s *Scheduler) WithParallelism(parallelism map[string]int) *Scheduler {
+	s.parallelism = parallelism
+	return s
+}
+
+// Acquire waits until a gate for project may run and returns the func that
+// frees its slot. It fails if ctx ends or the job's owner is cancelled while
+// it waits.
+func (s *Scheduler) Acquire(ctx context.Context, project Project) (func(), error) {
+	j := &job{
+		project: project.ID,
+		stack:   project.Stack,
+		ready:   make(chan struct{}),
+	}
+	j.owner, _ = ctx.Value(jobOwnerKey{}).(string)
+	j.observe, _ = ctx.Value(queueObserverKey{}).(QueueObserver)
+
+	s.mu.Lock()
+	s.waiting = append(s.waiting, j)
+	notify := s.dispatch()
+	s.mu.Unlock()
+	notify()
+
+	select {
+	case <-j.ready:
+	case <-ctx.Done():
+		s.mu.Lock()
+		started := s.remove(j)
+		notify := s.dispatch()
+		s.mu.Unlock()
+		notify()
+		if started {
+			// Raced with dispatch; give the slot straight back.
+			s.release(j)
+		}
+		return nil, context.Cause(ctx)
+	}
+	if j.err != nil {
+		return nil, j.err
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { s.release(j) }) }, nil
+}
+
+// Cancel drops owner's queued gates, which fail with ErrJobCancelled, and
+// returns how many there were. Running gates are left to finish.
+func (s *Scheduler) Cancel(owner string) int {
+	s.mu.Lock()
+	var kept []*job
+	cancelled := 0
+	for _, j := range s.waiting {
+		if owner != "" && j.owner == owner {
+			j.err = fmt.Errorf("%w: %s was abandoned", ErrJobCancelled, owner)
+			close(j.ready)
+			cancelled++
+			continue
+		}
+		kept = append(kept, j)
+	}
+	s.waiting = kept
+	notify := s.dispatch()
+	s.mu.Unlock()
+	notify()
+	return cancelled
+}
+
+// Queued returns how many gates are waiting.
+func (s *Scheduler) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.waiting)
+}
+
+func (s *Scheduler) release(j *job) {
+	s.mu.Lock()
+	s.running--
+	s.active[j.project]--
+	if s.active[j.project] == 0 {
+		delete(s.active, j.project)
+	}
+	notify := s.dispatch()
+	s.mu.Unlock()
+	notify()
+}
+
+// remove takes j out of the queue and reports whether it had already been
+// started. Callers hold s.mu.
+func (s *Scheduler) remove(j *job) bool {
+	for i, w := range s.waiting {
+		if w == j {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return false
+		}
+	}
+	return j.err == nil
+}
+
+// dispatch starts every waiting job that may now run and returns a func
+// that tells observers their new positions; call it after unlocking, since
+// observers may be slow. Callers hold s.mu.
+func (s *Scheduler) dispatch() func() {
+	type update struct {
+		observe  QueueObserver
+		position int
+	}
+	var updates []update
+
+	var kept []*job
+	// A project's later jobs never overtake its earlier ones.
+	blocked := make(map[string]bool)
+	for _, j := range s.waiting {
+		if !blocked[j.project] && s.running < s.workers && s.active[j.project] < s.limit(j.stack) {
+			s.running++
+			s.active[j.project]++
+			close(j.ready)
+			if j.observe != nil {
+				updates = append(updates, update{j.observe, 0})
+			}
+			continue
+		}
+		blocked[j.project] = true
+		kept = append(kept, j)
+		if position := len(kept); position != j.position {
+			j.position = position
+			if j.observe != nil {
+				updates = append(updates, update{j.observe, position})
+			}
+		}
+	}
+	s.waiting = kept
+
+	return func() {
+		for _, u := range updates {
+			u.observe(u.position)
+		}
+	}
+}
+
+func (s *Scheduler) limit(stack string) int {
+	if n := s.parallelism[stack]; n > 0 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,146 @@
+package runner_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync acquires a slot in the background, returning a channel that
+// yields the release func once the job may run.
+func acquireAsync(t *testing.T, ctx context.Context, s *runner.Scheduler, p runner.Project) <-chan func() {
+	t.Helper()
+	ch := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(ctx, p)
+		if err == nil {
+			ch <- release
+		}
+	}()
+	return ch
+}
+
+func requireWaiting(t *testing.T, s *runner.Scheduler, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return s.Queued() == n }, time.Second, time.Millisecond)
+}
+
+func TestScheduler_GlobalWorkerLimit(t *testing.T) {
+	s := runner.NewScheduler(2)
+	ctx := context.Background()
+
+	r1, err := s.Acquire(ctx, project("proj-1", "go"))
+	require.NoError(t, err)
+	_, err = s.Acquire(ctx, project("proj-2", "go"))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var positions []int
+	observed := runner.WithQueueObserver(ctx, func(p int) {
+		mu.Lock()
+		defer mu.Unlock()
+		positions = append(positions, p)
+	})
+	third := acquireAsync(t, observed, s, project("proj-3", "go"))
+	requireWaiting(t, s, 1)
+
+	r1()
+	r1() // releasing twice is harmless
+	release := <-third
+	release()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{1, 0}, positions)
+}
+
+func TestScheduler_ProjectFIFO(t *testing.T) {
+	s := runner.NewScheduler(4)
+	ctx := context.Background()
+
+	first, err := s.Acquire(ctx, project("proj-1", "go"))
+	require.NoError(t, err)
+	second := acquireAsync(t, ctx, s, project("proj-1", "go"))
+	requireWaiting(t, s, 1)
+	third := acquireAsync(t, ctx, s, project("proj-1", "go"))
+	requireWaiting(t, s, 2)
+
+	// Other projects aren't held up by proj-1's queue.
+	other, err := s.Acquire(ctx, project("proj-2", "go"))
+	require.NoError(t, err)
+	other()
+
+	first()
+	release := <-second
+	select {
+	case <-third:
+		t.Fatal("third job overtook the second")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	(<-third)()
+	assert.Zero(t, s.Queued())
+}
+
+func TestScheduler_StackParallelism(t *testing.T) {
+	s := runner.NewScheduler(4).WithParallelism(map[string]int{"node": 2})
+	ctx := context.Background()
+
+	_, err := s.Acquire(ctx, project("proj-1", "node"))
+	require.NoError(t, err)
+	_, err = s.Acquire(ctx, project("proj-1", "node"))
+	require.NoError(t, err, "node projects may run two gates at once")
+
+	acquireAsync(t, ctx, s, project("proj-1", "node"))
+	requireWaiting(t, s, 1)
+}
+
+func TestScheduler_CancelOwner(t *testing.T) {
+	s := runner.NewScheduler(1)
+	ctx := context.Background()
+
+	release, err := s.Acquire(ctx, project("proj-1", "go"))
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(runner.WithJobOwner(ctx, "task-1"), project("proj-2", "go"))
+		errs <- err
+	}()
+	requireWaiting(t, s, 1)
+
+	assert.Zero(t, s.Cancel("task-2"))
+	assert.Equal(t, 1, s.Cancel("task-1"))
+	assert.ErrorIs(t, <-errs, runner.ErrJobCancelled)
+	assert.Zero(t, s.Queued())
+
+	release()
+	next, err := s.Acquire(ctx, project("proj-3", "go"))
+	require.NoError(t, err, "the cancelled job didn't take a slot")
+	next()
+}
+
+func TestScheduler_ContextCancelLeavesQueue(t *testing.T) {
+	s := runner.NewScheduler(1)
+
+	release, err := s.Acquire(context.Background(), project("proj-1", "go"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx, project("proj-2", "go"))
+		errs <- err
+	}()
+	requireWaiting(t, s, 1)
+	cancel()
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Zero(t, s.Queued())
+	release()
+}
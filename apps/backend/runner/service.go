@@ -2,40 +2,136 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/monarch-dev/monarch/gates"
 	"github.com/monarch-dev/monarch/runner/eval"
+	"github.com/monarch-dev/monarch/runner/parser"
 )
 
+// Project identifies the runner a command executes in.
+type Project struct {
+	ID    string
+	Stack string
+	// Image overrides the registry's image for Stack.
+	Image string
+	// Path is the project root on the host, mounted at WorkspaceDir.
+	Path string
+	// Snapshots, if set, is the host dir holding the project's workspace
+	// snapshots. It is mounted at WorkspaceDir instead of Path, and gates
+	// run in Snapshot, a path relative to it. A writable project mounts
+	// only Snapshot's attempt dir, its first element.
+	Snapshots string
+	Snapshot  string
+	// Revision, if set, identifies Snapshot's contents, such as its commit
+	// and diff hash, so a built image's tag is only hashed once per revision.
+	Revision  string
+	Writable  bool
+	Resources gates.Resources
+	// Build, if set, builds the runner image from the project instead of
+	// using Image or the registry's.
+	Build *gates.Build
+}
+
 type Service interface {
-	Execute(ctx context.Context, projectID string, cmd []string) (string, error)
-	RunGate(ctx context.Context, projectID string, gate gates.Gate) error
+	Execute(ctx context.Context, project Project, cmd []string) (string, error)
+	// RunGate never returns a bare error: infrastructure failures are reported
+	// as an OutcomeSystemError result so callers fail closed. A command gate's
+	// output is streamed to the ctx's LineSink, if any (see WithLineSink).
+	RunGate(ctx context.Context, project Project, gate gates.Gate) GateResult
+}
+
+// SecretStore resolves the secrets gates reference by name;
+// settings.Service implements it.
+type SecretStore interface {
+	Secret(ctx context.Context, name string) (string, error)
 }
 
 type RunnerService struct {
 	manager    *Manager
+	images     *ImageRegistry
+	builder    *ImageBuilder
 	executor   *Executor
 	evalEngine *eval.Engine
+	// limits holds global resource limits per stack.
+	limits map[string]gates.Resources
+	// timeout applies to gates that don't set their own; zero means none.
+	timeout time.Duration
+	// scheduler queues commands; without one they run immediately.
+	scheduler *Scheduler
+	// secrets resolves gate secrets; without it, gates can't use any.
+	secrets SecretStore
 }
 
-func NewService(manager *Manager, executor *Executor, evalEngine *eval.Engine) *RunnerService {
+func NewService(manager *Manager, images *ImageRegistry, executor *Executor, evalEngine *eval.Engine) *RunnerService {
 	return &RunnerService{
 		manager:    manager,
+		images:     images,
 		executor:   executor,
 		evalEngine: evalEngine,
 	}
 }
 
-func (s *RunnerService) Execute(ctx context.Context, projectID string, cmd []string) (string, error) {
-	// Retrieve container (assuming 'default' stack for raw execute, or pass stack in)
-	containerID, err := s.manager.GetOrStart(ctx, projectID, "default")
+// WithLimits sets global resource limits per stack, applied over
+// DefaultResources and under the project's and gate's own limits.
+func (s *RunnerService) WithLimits(limits map[string]gates.Resources) *RunnerService {
+	s.limits = limits
+	return s
+}
+
+// WithBuilder builds the runner images of projects that have their own
+// Dockerfile; without one, such projects can't run gates.
+func (s *RunnerService) WithBuilder(b *ImageBuilder) *RunnerService {
+	s.builder = b
+	return s
+}
+
+// WithGateTimeout sets the timeout for gates that don't set their own.
+func (s *RunnerService) WithGateTimeout(d time.Duration) *RunnerService {
+	s.timeout = d
+	return s
+}
+
+// WithScheduler queues every command through sched.
+func (s *RunnerService) WithScheduler(sched *Scheduler) *RunnerService {
+	s.scheduler = sched
+	return s
+}
+
+// WithSecrets resolves the secrets gates reference from store.
+func (s *RunnerService) WithSecrets(store SecretStore) *RunnerService {
+	s.secrets = store
+	return s
+}
+
+// acquire waits for the scheduler to let a command run for project.
+func (s *RunnerService) acquire(ctx context.Context, project Project) (func(), error) {
+	if s.scheduler == nil {
+		return func() {}, nil
+	}
+	return s.scheduler.Acquire(ctx, project)
+}
+
+func (s *RunnerService) Execute(ctx context.Context, project Project, cmd []string) (string, error) {
+	release, err := s.acquire(ctx, project)
 	if err != nil {
 		return "", err
 	}
+	defer release()
 
-	stdout, stderr, exitCode, err := s.executor.Run(ctx, containerID, cmd)
+	containerID, err := s.runnerFor(ctx, project, gates.Gate{})
+	if err != nil {
+		return "", err
+	}
+
+	done := s.manager.beginExec(containerID)
+	stdout, stderr, exitCode, err := s.executor.Run(ctx, containerID, cmd, "")
+	done(err)
 	if err != nil {
 		return "", err
 	}
@@ -47,42 +143,236 @@ func (s *RunnerService) Execute(ctx context.Context, projectID string, cmd []str
 	return stdout, nil
 }
 
-func (s *RunnerService) RunGate(ctx context.Context, projectID string, gate gates.Gate) error {
+func (s *RunnerService) RunGate(ctx context.Context, project Project, gate gates.Gate) GateResult {
+	start := time.Now()
+	var res GateResult
 	if gate.Type == "llm_eval" {
-		// Default to Snapshot mode for now as per plan focus
-		res, err := s.evalEngine.EvaluateSnapshot(ctx, gate.File, gate.Instruction)
+		res = s.runEvalGate(ctx, gate)
+	} else {
+		res = s.runCommandGate(ctx, project, gate)
+	}
+	res.Duration = time.Since(start)
+	return res
+}
+
+func (s *RunnerService) runEvalGate(ctx context.Context, gate gates.Gate) GateResult {
+	if s.evalEngine == nil {
+		return systemError(gate.Name, fmt.Errorf("gate %s requires an LLM API key", gate.Name))
+	}
+	// Default to Snapshot mode for now as per plan focus
+	verdict, err := s.evalEngine.EvaluateSnapshot(ctx, gate.File, gate.Instruction)
+	if err != nil {
+		return systemError(gate.Name, err)
+	}
+
+	res := GateResult{Gate: gate.Name, Outcome: OutcomePass, Stdout: verdict}
+	if strings.Contains(strings.ToUpper(verdict), "FAIL") {
+		res.Outcome = OutcomeValidationFailure
+		res.Findings = []parser.LogEntry{{
+			Severity: parser.SeverityError,
+			File:     gate.File,
+			Message:  verdict,
+			Tool:     "llm_eval",
+		}}
+	}
+	return res
+}
+
+// runnerFor returns the project's warm runner for gate, pulling or building
+// its image first if needed.
+func (s *RunnerService) runnerFor(ctx context.Context, project Project, gate gates.Gate) (string, error) {
+	image, err := s.image(ctx, project)
+	if err != nil {
+		return "", err
+	}
+	profile := Profile{
+		Resources: DefaultResources.
+			Merge(s.limits[project.Stack]).
+			Merge(project.Resources).
+			Merge(gate.Resources),
+		Network: gate.Network,
+	}
+	return s.manager.GetOrStart(ctx, project, profile, image)
+}
+
+// image returns the image the project's runners start from: the one built
+// from its Dockerfile if it has one, or else its stack's.
+func (s *RunnerService) image(ctx context.Context, project Project) (string, error) {
+	if project.Build == nil {
+		return s.images.Resolve(ctx, project.Stack, project.Image)
+	}
+	if s.builder == nil {
+		return "", errors.New("building runner images requires the docker runtime")
+	}
+	revision := ""
+	if project.Snapshot != "" && project.Revision != "" {
+		revision = project.ID + "@" + project.Revision
+	}
+	return s.builder.Build(ctx, projectRoot(project), revision, *project.Build)
+}
+
+func (s *RunnerService) runCommandGate(ctx context.Context, project Project, gate gates.Gate) GateResult {
+	argv, err := gate.Argv()
+	if err != nil {
+		return systemError(gate.Name, fmt.Errorf("gate %s: invalid command: %w", gate.Name, err))
+	}
+	if len(argv) == 0 {
+		return systemError(gate.Name, fmt.Errorf("gate %s has no command", gate.Name))
+	}
+
+	// Time spent queued doesn't count towards the gate's timeout.
+	release, err := s.acquire(ctx, project)
+	if err != nil {
+		return systemError(gate.Name, err)
+	}
+	defer release()
+
+	containerID, err := s.runnerFor(ctx, project, gate)
+	if err != nil {
+		return systemError(gate.Name, err)
+	}
+
+	workdir, err := gateWorkdir(project, gate)
+	if err != nil {
+		return systemError(gate.Name, err)
+	}
+
+	// Secrets are decrypted only now, and never kept.
+	env, secrets, err := s.gateEnv(ctx, gate)
+	if err != nil {
+		return systemError(gate.Name, err)
+	}
+
+	timeout := s.timeout
+	if gate.Timeout != "" {
+		if timeout, err = time.ParseDuration(gate.Timeout); err != nil || timeout <= 0 {
+			return systemError(gate.Name, fmt.Errorf("gate %s: invalid timeout %q", gate.Name, gate.Timeout))
+		}
+	}
+	// The timeout covers the command only, not starting the runner.
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+		defer cancel()
+	}
+
+	cmd := Command{Cmd: argv, Workdir: workdir, Env: env, Secrets: secrets}
+	done := s.manager.beginExec(containerID)
+	stdout, stderr, exitCode, err := s.executor.Stream(runCtx, containerID, cmd, lineSinkFrom(ctx))
+	done(err)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(context.Cause(runCtx), ErrTimeout) {
+			return GateResult{
+				Gate:    gate.Name,
+				Outcome: OutcomeTimeout,
+				Stdout:  stdout,
+				Stderr:  stderr,
+				Cause:   fmt.Errorf("%w after %s", ErrTimeout, timeout),
+			}
+		}
+		return systemError(gate.Name, err)
+	}
+
+	return classify(gate, argv, stdout, stderr, exitCode)
+}
+
+// gateEnv returns the gate's env and secrets as KEY=value pairs, in name
+// order, along with the secret values to redact from its output.
+func (s *RunnerService) gateEnv(ctx context.Context, gate gates.Gate) (env, secrets []string, err error) {
+	names := make([]string, 0, len(gate.Env)+len(gate.Secrets))
+	for name := range gate.Env {
+		names = append(names, name)
+	}
+	for name := range gate.Secrets {
+		if _, ok := gate.Env[name]; ok {
+			return nil, nil, fmt.Errorf("gate %s: %s is set in both env and secrets", gate.Name, name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if !gates.ValidEnvName(name) || name == execIDEnv {
+			return nil, nil, fmt.Errorf("gate %s: invalid environment variable name %q", gate.Name, name)
+		}
+		ref, ok := gate.Secrets[name]
+		if !ok {
+			env = append(env, name+"="+gate.Env[name])
+			continue
+		}
+		if s.secrets == nil {
+			return nil, nil, fmt.Errorf("gate %s: secrets are unavailable without an encryption key", gate.Name)
+		}
+		value, err := s.secrets.Secret(ctx, ref)
 		if err != nil {
-			return err
+			return nil, nil, fmt.Errorf("gate %s: failed to read secret %q: %w", gate.Name, ref, err)
 		}
-		
-		// Basic validation of result
-		if strings.Contains(strings.ToUpper(res), "FAIL") {
-			return fmt.Errorf("LLM evaluation failed: %s", res)
+		env = append(env, name+"="+value)
+		secrets = append(secrets, value)
+	}
+	return env, secrets, nil
+}
+
+// gateWorkdir resolves the gate's workdir inside the mounted workspace, or
+// the project's snapshot in it, refusing paths that would leave it.
+func gateWorkdir(project Project, gate gates.Gate) (string, error) {
+	root := WorkspaceDir
+	if project.Snapshots != "" {
+		_, rel, err := snapshotMount(project)
+		if err != nil {
+			return "", err
 		}
-		return nil
+		root = path.Join(WorkspaceDir, rel)
+	}
+	if gate.Workdir == "" {
+		return root, nil
 	}
+	rel, ok := gates.Within(gate.Workdir)
+	if !ok {
+		return "", fmt.Errorf("gate %s: workdir %q must be inside the project", gate.Name, gate.Workdir)
+	}
+	return path.Join(root, rel), nil
+}
 
-	// Standard Execution
-	// Determine stack from gate or default
-	// Assuming manager needs stack. Gate config has stack at top level, passed down?
-	// For now using "default" or "go" as placeholder if not in Gate struct.
-	// We added Tier, Command, Name. Not Stack in Gate. Stack is in Config.
-	stack := "default" 
-	
-	containerID, err := s.manager.GetOrStart(ctx, projectID, stack)
-	if err != nil {
-		return err
+// classify turns a finished command into a result. If the command has a
+// known output format that can't be parsed, the tool itself broke, so the
+// gate fails closed as a system error rather than passing or blaming the
+// agent.
+func classify(gate gates.Gate, argv []string, stdout, stderr string, exitCode int) GateResult {
+	res := GateResult{
+		Gate:     gate.Name,
+		Outcome:  OutcomePass,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: &exitCode,
 	}
 
-	cmd := strings.Fields(gate.Command)
-	_, stderr, exitCode, err := s.executor.Run(ctx, containerID, cmd)
-	if err != nil {
-		return err
+	p := parser.ForArgs(argv)
+	if gate.Shell {
+		p = parser.ForCommand(gate.Command)
+	}
+	if p != nil {
+		// Line-based reports still parse once truncated; whole-document
+		// ones don't, and fail as a system error.
+		out, truncated := untruncated(stdout)
+		entries, err := p.Parse([]byte(out))
+		if err != nil && truncated {
+			err = fmt.Errorf("output over %d bytes was truncated: %w", MaxOutputBytes, err)
+		}
+		if err != nil {
+			res.Outcome = OutcomeSystemError
+			res.Cause = err
+			return res
+		}
+		for i := range entries {
+			parser.Enrich(&entries[i])
+		}
+		res.Findings = entries
 	}
 
 	if exitCode != 0 {
-		return fmt.Errorf("gate %s failed: %s", gate.Name, stderr)
+		res.Outcome = OutcomeValidationFailure
 	}
-
-	return nil
-}
\ No newline at end of file
+	return res
+}
@@ -0,0 +1,576 @@
+package runner_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/monarch-dev/monarch/gates"
+	"github.com/monarch-dev/monarch/runner"
+	"github.com/monarch-dev/monarch/runner/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// execOutput fakes an attached exec stream carrying stdout and stderr.
+func execOutput(t *testing.T, stdout, stderr string) types.HijackedResponse {
+	var buf bytes.Buffer
+	_, err := stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(stdout))
+	require.NoError(t, err)
+	if stderr != "" {
+		_, err = stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(stderr))
+		require.NoError(t, err)
+	}
+	conn, peer := net.Pipe()
+	t.Cleanup(func() { peer.Close() })
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(&buf)}
+}
+
+// hangingExec fakes an exec stream that prints stdout and then hangs until
+// the stream is closed.
+func hangingExec(t *testing.T, stdout string) types.HijackedResponse {
+	conn, peer := net.Pipe()
+	t.Cleanup(func() { peer.Close() })
+	go func() {
+		_, _ = stdcopy.NewStdWriter(peer, stdcopy.Stdout).Write([]byte(stdout))
+	}()
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}
+}
+
+// expectKill expects the exec that kills a cancelled command's processes.
+func expectKill(t *testing.T, execCli *MockDockerClient, containerID string) {
+	execCli.On("ContainerExecCreate", mock.Anything, containerID, mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return len(cfg.Cmd) == 3 && cfg.Cmd[0] == "sh" && strings.Contains(cfg.Cmd[2], "kill -9")
+	})).Return(types.IDResponse{ID: "kill-1"}, nil).Once()
+	execCli.On("ContainerExecAttach", mock.Anything, "kill-1", mock.Anything).Return(execOutput(t, "", ""), nil).Once()
+	execCli.On("ContainerExecInspect", mock.Anything, "kill-1").Return(container.ExecInspect{}, nil).Once()
+}
+
+func newGateService(t *testing.T, stdout, stderr string, exitCode int) (*runner.RunnerService, *MockDockerClient) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	dockerCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	execCli := new(MockDockerClient)
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-1", mock.Anything).Return(types.IDResponse{ID: "exec-1"}, nil)
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-1", mock.Anything).Return(execOutput(t, stdout, stderr), nil)
+	execCli.On("ContainerExecInspect", mock.Anything, "exec-1").Return(container.ExecInspect{ExitCode: exitCode}, nil)
+
+	return runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(execCli)), nil), execCli
+}
+
+// localImages is a registry whose images are all already pulled.
+func localImages() *runner.ImageRegistry {
+	cli := new(MockImageClient)
+	cli.On("ImageInspect", mock.Anything, mock.Anything).
+		Return(image.InspectResponse{RepoDigests: []string{"golang@" + golangDigest}}, nil)
+	return runner.NewImageRegistry(cli, nil)
+}
+
+var goProject = runner.Project{ID: "proj-1", Stack: "go", Path: "/src/proj-1"}
+
+func TestRunGate_Outcomes(t *testing.T) {
+	const goFail = `{"Action":"fail","Package":"app","Test":"TestLogin","Output":"login broken"}`
+	goNoise := strings.Repeat(`{"Action":"output","Package":"app","Output":"noise"}`+"\n", runner.MaxOutputBytes/32)
+
+	tests := []struct {
+		name         string
+		command      string
+		stdout       string
+		exitCode     int
+		wantOutcome  runner.Outcome
+		wantFindings int
+	}{
+		{"Pass", "make test", "ok", 0, runner.OutcomePass, 0},
+		{"NonZeroExit", "make test", "", 1, runner.OutcomeValidationFailure, 0},
+		{"ParsedFindings", "go test -json ./...", goFail, 1, runner.OutcomeValidationFailure, 1},
+		{"UnparseableOutputFailsClosed", "go test -json ./...", "panic: not json", 2, runner.OutcomeSystemError, 0},
+		{"TruncatedOutputStillParses", "go test -json ./...", goNoise + goFail, 1, runner.OutcomeValidationFailure, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newGateService(t, tt.stdout, "stderr text", tt.exitCode)
+
+			res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: tt.command})
+
+			assert.Equal(t, tt.wantOutcome, res.Outcome)
+			assert.Len(t, res.Findings, tt.wantFindings)
+			require.NotNil(t, res.ExitCode)
+			assert.Equal(t, tt.exitCode, *res.ExitCode)
+			assert.Equal(t, "stderr text", res.Stderr)
+			if tt.wantOutcome == runner.OutcomeSystemError {
+				assert.ErrorIs(t, res.Cause, parser.ErrSystemFailure)
+			}
+		})
+	}
+}
+
+func TestRunGate_CommandForms(t *testing.T) {
+	tests := []struct {
+		name string
+		gate gates.Gate
+		want []string
+	}{
+		{"Quoted", gates.Gate{Command: "go test -run 'TestA|TestB' ./..."}, []string{"go", "test", "-run", "TestA|TestB", "./..."}},
+		{"Args", gates.Gate{Args: []string{"go", "test", "-run", "TestA|TestB", "./..."}}, []string{"go", "test", "-run", "TestA|TestB", "./..."}},
+		{"Shell", gates.Gate{Command: "make lint && make test > out.txt", Shell: true}, []string{"sh", "-c", "make lint && make test > out.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, execCli := newGateService(t, "", "", 0)
+			tt.gate.Name = "test"
+
+			res := svc.RunGate(context.Background(), goProject, tt.gate)
+
+			require.Equal(t, runner.OutcomePass, res.Outcome, res.Cause)
+			execCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "runner-1", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+				return assert.ObjectsAreEqual(tt.want, cfg.Cmd)
+			}))
+		})
+	}
+}
+
+func TestRunGate_ParsesOutputOfEveryCommandForm(t *testing.T) {
+	const goFail = `{"Action":"fail","Package":"app","Test":"TestLogin","Output":"login broken"}`
+	for _, gate := range []gates.Gate{
+		{Name: "test", Args: []string{"go", "test", "-json", "./..."}},
+		{Name: "test", Command: "go test -json ./... 2>/dev/null", Shell: true},
+	} {
+		svc, _ := newGateService(t, goFail, "", 1)
+
+		res := svc.RunGate(context.Background(), goProject, gate)
+
+		assert.Equal(t, runner.OutcomeValidationFailure, res.Outcome)
+		assert.Len(t, res.Findings, 1)
+	}
+}
+
+func TestRunGate_InvalidCommandIsSystemError(t *testing.T) {
+	svc, execCli := newGateService(t, "", "", 0)
+
+	res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "go test -run 'TestA"})
+
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+	assert.ErrorContains(t, res.Cause, "unterminated single quote")
+	execCli.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunGate_StreamsToContextSink(t *testing.T) {
+	svc, _ := newGateService(t, "building\ndone\n", "", 0)
+
+	var lines []string
+	ctx := runner.WithLineSink(context.Background(), func(l runner.Line) {
+		lines = append(lines, l.Text)
+	})
+	res := svc.RunGate(ctx, goProject, gates.Gate{Name: "build", Command: "make build"})
+
+	assert.Equal(t, runner.OutcomePass, res.Outcome)
+	assert.Equal(t, "building\ndone\n", res.Stdout)
+	assert.Equal(t, []string{"building", "done"}, lines)
+}
+
+func TestRunGate_DockerFailureIsSystemError(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{}, errors.New("daemon unreachable"))
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil)
+
+	res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "make test"})
+
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+	assert.Nil(t, res.ExitCode)
+	assert.ErrorContains(t, res.Err(), "daemon unreachable")
+}
+
+func TestRunGate_UsesStackImage(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.MatchedBy(func(cfg *container.Config) bool {
+		return cfg.Image == "docker.io/library/golang@"+golangDigest && cfg.Labels["monarch.stack"] == "go"
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{}, errors.New("stop here"))
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil)
+
+	svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "go test ./..."})
+
+	dockerCli.AssertExpectations(t)
+}
+
+func TestRunGate_UsesBuiltImage(t *testing.T) {
+	p := goProject
+	p.Path = buildProject(t)
+	p.Build = &gates.Build{}
+	builds := new(MockBuildClient)
+	builds.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{ID: "sha256:abc"}, nil)
+	builder := runner.NewImageBuilder(builds)
+	tag, err := builder.Tag(p.Path, *p.Build)
+	require.NoError(t, err)
+
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.MatchedBy(func(cfg *container.Config) bool {
+		return cfg.Image == tag && cfg.Labels["monarch.build"] == runner.BuildKey(*p.Build)
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{}, errors.New("stop here"))
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil).
+		WithBuilder(builder)
+
+	svc.RunGate(context.Background(), p, gates.Gate{Name: "test", Command: "go test ./..."})
+
+	dockerCli.AssertExpectations(t)
+}
+
+func TestRunGate_BuildFailureIsSystemError(t *testing.T) {
+	p := goProject
+	p.Path = buildProject(t)
+	p.Build = &gates.Build{}
+	builds := new(MockBuildClient)
+	builds.On("ImageInspect", mock.Anything, mock.Anything).Return(image.InspectResponse{}, cerrdefs.ErrNotFound)
+	builds.On("ImageBuild", mock.Anything, mock.Anything).Return(`{"error":"unknown instruction: RUNN"}`, nil)
+
+	for name, builder := range map[string]*runner.ImageBuilder{
+		"BuildFails": runner.NewImageBuilder(builds),
+		"NoBuilder":  nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := runner.NewService(runner.NewManager(runner.NewDocker(new(MockDockerClient))), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil).
+				WithBuilder(builder)
+
+			res := svc.RunGate(context.Background(), p, gates.Gate{Name: "test", Command: "go test ./..."})
+
+			assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+			assert.Nil(t, res.ExitCode)
+		})
+	}
+}
+
+func TestRunGate_LayersResourceLimits(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.MatchedBy(func(hc *container.HostConfig) bool {
+		return hc.Memory == 4<<30 && // project
+			hc.NanoCPUs == 1e9 && // global per stack
+			*hc.PidsLimit == 64 && // gate
+			hc.Tmpfs["/tmp"] == "rw,nosuid,nodev,size=536870912" && // default
+			hc.NetworkMode == "bridge"
+	}), mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{}, errors.New("stop here"))
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil).
+		WithLimits(map[string]gates.Resources{"go": {Memory: "1g", CPUs: 1}})
+
+	p := goProject
+	p.Resources = gates.Resources{Memory: "4g"}
+	svc.RunGate(context.Background(), p, gates.Gate{Name: "deps", Command: "go mod download", Network: true, Resources: gates.Resources{Pids: 64}})
+
+	dockerCli.AssertExpectations(t)
+}
+
+func TestRunGate_UnknownStackIsSystemError(t *testing.T) {
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(new(MockDockerClient))), localImages(), runner.NewExecutor(runner.NewDocker(new(MockDockerClient))), nil)
+
+	res := svc.RunGate(context.Background(), runner.Project{ID: "proj-1", Stack: "unknown", Path: "/src/proj-1"}, gates.Gate{Name: "test", Command: "make"})
+
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+	assert.ErrorIs(t, res.Cause, runner.ErrNoImage)
+}
+
+func TestRunGate_Workdir(t *testing.T) {
+	tests := []struct {
+		workdir string
+		want    string
+	}{
+		{"", "/workspace"},
+		{"services/api", "/workspace/services/api"},
+		{"./web/../web", "/workspace/web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.workdir, func(t *testing.T) {
+			svc, execCli := newGateService(t, "", "", 0)
+
+			svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "make", Workdir: tt.workdir})
+
+			execCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "runner-1", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+				return cfg.WorkingDir == tt.want
+			}))
+		})
+	}
+}
+
+func TestRunGate_WorkdirInSnapshot(t *testing.T) {
+	svc, execCli := newGateService(t, "", "", 0)
+	p := goProject
+	p.Snapshots = "/snapshots/proj-1"
+	p.Snapshot = "attempt-1/app"
+
+	svc.RunGate(context.Background(), p, gates.Gate{Name: "test", Command: "make", Workdir: "cmd"})
+
+	execCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "runner-1", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return cfg.WorkingDir == "/workspace/attempt-1/app/cmd"
+	}))
+}
+
+func TestRunGate_WorkdirInWritableSnapshot(t *testing.T) {
+	svc, execCli := newGateService(t, "", "", 0)
+	p := goProject
+	p.Writable = true
+	p.Snapshots = "/snapshots/proj-1"
+	p.Snapshot = "attempt-1/app"
+
+	svc.RunGate(context.Background(), p, gates.Gate{Name: "test", Command: "make", Workdir: "cmd"})
+
+	// Only attempt-1 is mounted, at the workspace root.
+	execCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "runner-1", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return cfg.WorkingDir == "/workspace/app/cmd"
+	}))
+}
+
+func TestRunGate_WorkdirOutsideProjectIsSystemError(t *testing.T) {
+	for _, workdir := range []string{"../other", "/etc", "a/../../b"} {
+		svc, _ := newGateService(t, "", "", 0)
+
+		res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "make", Workdir: workdir})
+
+		assert.Equal(t, runner.OutcomeSystemError, res.Outcome, workdir)
+		assert.ErrorContains(t, res.Cause, "must be inside the project", workdir)
+	}
+}
+
+// secretStore is an in-memory runner.SecretStore.
+type secretStore map[string]string
+
+func (s secretStore) Secret(_ context.Context, name string) (string, error) {
+	v, ok := s[name]
+	if !ok {
+		return "", errors.New("setting not found")
+	}
+	return v, nil
+}
+
+func TestRunGate_InjectsEnvAndSecrets(t *testing.T) {
+	svc, execCli := newGateService(t, "registry token: s3cr3t-npm\n", "auth s3cr3t-npm failed", 0)
+	svc.WithSecrets(secretStore{"npm_token": "s3cr3t-npm"})
+
+	var lines []string
+	ctx := runner.WithLineSink(context.Background(), func(l runner.Line) { lines = append(lines, l.Text) })
+	res := svc.RunGate(ctx, goProject, gates.Gate{
+		Name:    "install",
+		Command: "npm ci",
+		Env:     map[string]string{"NODE_ENV": "test"},
+		Secrets: map[string]string{"NPM_TOKEN": "npm_token"},
+	})
+
+	require.Equal(t, runner.OutcomePass, res.Outcome, res.Cause)
+	execCli.AssertCalled(t, "ContainerExecCreate", mock.Anything, "runner-1", mock.MatchedBy(func(cfg container.ExecOptions) bool {
+		return len(cfg.Env) == 3 && cfg.Env[0] == "NODE_ENV=test" && cfg.Env[1] == "NPM_TOKEN=s3cr3t-npm" &&
+			strings.HasPrefix(cfg.Env[2], "MONARCH_EXEC_ID=")
+	}))
+	assert.Equal(t, "registry token: [REDACTED]\n", res.Stdout)
+	assert.Equal(t, "auth [REDACTED] failed", res.Stderr)
+	assert.Equal(t, []string{"registry token: [REDACTED]", "auth [REDACTED] failed"}, lines)
+}
+
+func TestRunGate_BadEnvIsSystemError(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   runner.SecretStore
+		gate    gates.Gate
+		wantErr string
+	}{
+		{"NoStore", nil, gates.Gate{Secrets: map[string]string{"TOKEN": "token"}}, "secrets are unavailable"},
+		{"MissingSecret", secretStore{}, gates.Gate{Secrets: map[string]string{"TOKEN": "token"}}, `failed to read secret "token"`},
+		{"InvalidName", secretStore{}, gates.Gate{Env: map[string]string{"NODE-ENV": "test"}}, `invalid environment variable name "NODE-ENV"`},
+		{"ExecID", secretStore{}, gates.Gate{Env: map[string]string{"MONARCH_EXEC_ID": "x"}}, "invalid environment variable name"},
+		{"Both", secretStore{"token": "x"}, gates.Gate{
+			Env:     map[string]string{"TOKEN": "plain"},
+			Secrets: map[string]string{"TOKEN": "token"},
+		}, "TOKEN is set in both env and secrets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, execCli := newGateService(t, "", "", 0)
+			if tt.store != nil {
+				svc.WithSecrets(tt.store)
+			}
+			tt.gate.Name, tt.gate.Command = "test", "make"
+
+			res := svc.RunGate(context.Background(), goProject, tt.gate)
+
+			assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+			assert.ErrorContains(t, res.Cause, tt.wantErr)
+			execCli.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRunGate_Timeout(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	dockerCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	execCli := new(MockDockerClient)
+	expectKill(t, execCli, "runner-1")
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-1", mock.Anything).Return(types.IDResponse{ID: "exec-1"}, nil).Once()
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-1", mock.Anything).Return(hangingExec(t, "=== RUN TestHang\n"), nil)
+
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(execCli)), nil).
+		WithGateTimeout(time.Hour)
+
+	res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "test", Command: "make test", Timeout: "50ms"})
+
+	assert.Equal(t, runner.OutcomeTimeout, res.Outcome)
+	assert.Equal(t, "=== RUN TestHang\n", res.Stdout)
+	assert.Nil(t, res.ExitCode)
+	assert.ErrorIs(t, res.Err(), runner.ErrTimeout)
+	assert.EqualError(t, res.Err(), "gate test: gate timed out after 50ms")
+	execCli.AssertExpectations(t)
+	execCli.AssertNotCalled(t, "ContainerExecInspect", mock.Anything, "exec-1")
+}
+
+func TestRunGate_CancelledIsSystemError(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	dockerCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	execCli := new(MockDockerClient)
+	expectKill(t, execCli, "runner-1")
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-1", mock.Anything).Return(types.IDResponse{ID: "exec-1"}, nil).Once()
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-1", mock.Anything).Return(hangingExec(t, ""), nil)
+
+	svc := runner.NewService(runner.NewManager(runner.NewDocker(dockerCli)), localImages(), runner.NewExecutor(runner.NewDocker(execCli)), nil).
+		WithGateTimeout(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res := svc.RunGate(ctx, goProject, gates.Gate{Name: "test", Command: "make test"})
+
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome, "only the gate's own timeout is a TIMEOUT")
+	assert.ErrorIs(t, res.Cause, context.DeadlineExceeded)
+}
+
+func TestRunGate_WritableAttemptsDontShareRunners(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil).Once()
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-2"}, nil).Once()
+	dockerCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// The first attempt's gate hangs while the second's runs.
+	execCli := new(MockDockerClient)
+	expectKill(t, execCli, "runner-1")
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-1", mock.Anything).Return(types.IDResponse{ID: "exec-1"}, nil).Once()
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-1", mock.Anything).Return(hangingExec(t, ""), nil)
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-2", mock.Anything).Return(types.IDResponse{ID: "exec-2"}, nil)
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-2", mock.Anything).Return(execOutput(t, "ok", ""), nil)
+	execCli.On("ContainerExecInspect", mock.Anything, "exec-2").Return(container.ExecInspect{}, nil)
+
+	mgr := runner.NewManager(runner.NewDocker(dockerCli))
+	svc := runner.NewService(mgr, localImages(), runner.NewExecutor(runner.NewDocker(execCli)), nil).
+		WithScheduler(runner.NewScheduler(2).WithParallelism(map[string]int{"go": 2}))
+	p := goProject
+	p.Writable = true
+	p.Snapshots = "/snapshots/proj-1"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan runner.GateResult)
+	go func() {
+		p := p
+		p.Snapshot = "attempt-1"
+		first <- svc.RunGate(ctx, p, gates.Gate{Name: "test", Command: "make test"})
+	}()
+	require.Eventually(t, func() bool {
+		runners := mgr.Runners()
+		return len(runners) == 1 && runners[0].InFlight == 1
+	}, time.Second, 5*time.Millisecond)
+
+	p.Snapshot = "attempt-2"
+	res := svc.RunGate(context.Background(), p, gates.Gate{Name: "test", Command: "make test"})
+	assert.Equal(t, runner.OutcomePass, res.Outcome, res.Cause)
+
+	dockerCli.AssertNotCalled(t, "ContainerRemove", mock.Anything, "runner-1", mock.Anything)
+	cancel()
+	assert.ErrorIs(t, (<-first).Cause, context.Canceled, "the first gate ran until cancelled")
+}
+
+func TestRunGate_TracksExecsInRunnerState(t *testing.T) {
+	dockerCli := new(MockDockerClient)
+	dockerCli.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(container.CreateResponse{ID: "runner-1"}, nil)
+	dockerCli.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	execCli := new(MockDockerClient)
+	expectKill(t, execCli, "runner-1")
+	execCli.On("ContainerExecCreate", mock.Anything, "runner-1", mock.Anything).Return(types.IDResponse{ID: "exec-1"}, nil).Once()
+	execCli.On("ContainerExecAttach", mock.Anything, "exec-1", mock.Anything).Return(hangingExec(t, ""), nil)
+
+	mgr := runner.NewManager(runner.NewDocker(dockerCli))
+	svc := runner.NewService(mgr, localImages(), runner.NewExecutor(runner.NewDocker(execCli)), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.RunGate(ctx, goProject, gates.Gate{Name: "test", Command: "make test"})
+	}()
+
+	require.Eventually(t, func() bool {
+		runners := mgr.Runners()
+		return len(runners) == 1 && runners[0].State == runner.StateActive
+	}, time.Second, 5*time.Millisecond)
+	active := mgr.Runners()[0]
+	assert.Equal(t, 1, active.InFlight)
+	require.NotNil(t, active.LastExec)
+
+	cancel()
+	<-done
+
+	idle := mgr.Runners()[0]
+	assert.Equal(t, runner.StateIdle, idle.State)
+	assert.Zero(t, idle.InFlight)
+	assert.Contains(t, idle.LastError, "exec cancelled")
+}
+
+func TestRunGate_CancelledWhileQueuedIsSystemError(t *testing.T) {
+	svc, execCli := newGateService(t, "ok", "", 0)
+	sched := runner.NewScheduler(1)
+	svc.WithScheduler(sched)
+
+	release, err := sched.Acquire(context.Background(), goProject)
+	require.NoError(t, err)
+	defer release()
+
+	results := make(chan runner.GateResult, 1)
+	go func() {
+		ctx := runner.WithJobOwner(context.Background(), "task-1")
+		results <- svc.RunGate(ctx, goProject, gates.Gate{Name: "test", Command: "make test"})
+	}()
+	require.Eventually(t, func() bool { return sched.Queued() == 1 }, time.Second, time.Millisecond)
+	sched.Cancel("task-1")
+
+	res := <-results
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+	assert.ErrorIs(t, res.Cause, runner.ErrJobCancelled)
+	execCli.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunGate_LLMGateWithoutKeyIsSystemError(t *testing.T) {
+	svc := runner.NewService(nil, nil, nil, nil)
+
+	res := svc.RunGate(context.Background(), goProject, gates.Gate{Name: "review", Type: "llm_eval"})
+
+	assert.Equal(t, runner.OutcomeSystemError, res.Outcome)
+	assert.ErrorContains(t, res.Cause, "requires an LLM API key")
+}
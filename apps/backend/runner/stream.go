@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// maxLineBytes splits output that never ends a line, such as progress bars,
+// so a single line can't grow without bound.
+const maxLineBytes = 16 << 10
+
+// Line is one line of a command's output, stamped when it was read.
+type Line struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// LineSink receives output lines while a command runs. Lines from one run
+// arrive in order and never concurrently.
+type LineSink func(Line)
+
+type lineSinkKey struct{}
+
+// WithLineSink returns a context under which RunGate streams the gate's
+// output to sink as it is produced.
+func WithLineSink(ctx context.Context, sink LineSink) context.Context {
+	return context.WithValue(ctx, lineSinkKey{}, sink)
+}
+
+func lineSinkFrom(ctx context.Context) LineSink {
+	sink, _ := ctx.Value(lineSinkKey{}).(LineSink)
+	return sink
+}
+
+// lineWriter copies a stream into buf and, if it has a sink, splits it into
+// lines for it, redacted. The trailing partial line is held until flush.
+type lineWriter struct {
+	stream  string
+	buf     io.Writer
+	sink    LineSink
+	redact  *redactor
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if _, err := w.buf.Write(p); err != nil {
+		return 0, err
+	}
+	if w.sink == nil {
+		return len(p), nil
+	}
+
+	w.partial = append(w.partial, p...)
+	for w.emitNext() {
+	}
+	// Don't pin a large backing array once the lines are out.
+	if len(w.partial) == 0 {
+		w.partial = nil
+	}
+	return len(p), nil
+}
+
+// emitNext emits the next complete line, or the next maxLineBytes of an
+// overlong one, and reports whether there was one. An overlong line is cut
+// short of any secret that would span the cut, so that it is redacted.
+func (w *lineWriter) emitNext() bool {
+	i := bytes.IndexByte(w.partial, '\n')
+	switch {
+	case i >= 0 && i <= maxLineBytes:
+		w.emit(w.partial[:i])
+		w.partial = w.partial[i+1:]
+	case i >= 0 || len(w.partial) >= maxLineBytes+w.redact.hold():
+		line := w.partial
+		if i >= 0 {
+			line = line[:i]
+		}
+		n := w.redact.cut(line, maxLineBytes)
+		w.emit(w.partial[:n])
+		w.partial = w.partial[n:]
+	default:
+		return false
+	}
+	return true
+}
+
+// flush emits the unterminated last line, if any.
+func (w *lineWriter) flush() {
+	if w.sink != nil && len(w.partial) > 0 {
+		w.emit(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *lineWriter) emit(b []byte) {
+	w.sink(Line{
+		Time:   time.Now(),
+		Stream: w.stream,
+		Text:   w.redact.redact(string(bytes.TrimSuffix(b, []byte("\r")))),
+	})
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// MaxOutputBytes caps how much of each of a command's output streams is
+// kept in memory and returned. The tail is kept, since that's where test
+// runners and linters summarise failures; the whole output only goes to
+// the LineSink.
+const MaxOutputBytes = 64 << 10
+
+// truncatedFormat starts output whose beginning was dropped.
+const truncatedFormat = "[truncated %d bytes]\n"
+
+var truncatedMarker = regexp.MustCompile(`^\[truncated \d+ bytes\]\n`)
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	max     int
+	buf     []byte
+	dropped int
+	// prev is the last byte dropped.
+	prev byte
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= t.max {
+		t.dropped += len(t.buf) + len(p) - t.max
+		if len(p) > t.max {
+			t.prev = p[len(p)-t.max-1]
+		} else if len(t.buf) > 0 {
+			t.prev = t.buf[len(t.buf)-1]
+		}
+		t.buf = append(t.buf[:0], p[len(p)-t.max:]...)
+		return n, nil
+	}
+	if over := len(t.buf) + len(p) - t.max; over > 0 {
+		// Shift rather than grow, so the buffer never exceeds max.
+		t.dropped += over
+		t.prev = t.buf[over-1]
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	t.buf = append(t.buf, p...)
+	return n, nil
+}
+
+// String returns what was written, or, if some was dropped, a truncation
+// marker and the tail from its first whole line, so that line-based output
+// can still be parsed, all within max bytes. A tail without a line break
+// skips skip more bytes instead, so that it can't start partway through a
+// secret too long to redact.
+func (t *tailBuffer) String(skip int) string {
+	if t.dropped == 0 {
+		return string(t.buf)
+	}
+	// Room for the marker, sized for the most that can be dropped.
+	marker := fmt.Sprintf(truncatedFormat, t.dropped+len(t.buf))
+	cut := max(0, len(marker)-(t.max-len(t.buf)))
+	prev := t.prev
+	if cut > 0 {
+		prev = t.buf[cut-1]
+	}
+	if prev == '\n' {
+		// Already at the start of a line.
+	} else if i := bytes.IndexByte(t.buf[cut:], '\n'); i >= 0 {
+		cut += i + 1
+	} else {
+		cut += skip
+	}
+	cut = min(cut, len(t.buf))
+	for cut < len(t.buf) && !utf8.RuneStart(t.buf[cut]) {
+		cut++
+	}
+	return fmt.Sprintf(truncatedFormat, t.dropped+cut) + string(t.buf[cut:])
+}
+
+// untruncated strips the truncation marker from output, reporting whether
+// there was one.
+func untruncated(s string) (string, bool) {
+	if loc := truncatedMarker.FindStringIndex(s); loc != nil {
+		return s[loc[1]:], true
+	}
+	return s, false
+}
@@ -0,0 +1,38 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/monarch-dev/monarch/internal/httpjson"
+)
+
+// maxBodyBytes caps request bodies; secrets are tokens and keys, not files.
+const maxBodyBytes = 1 << 20
+
+type SecretRequest struct {
+	Value string `json:"value"`
+}
+
+// SetSecretHandler serves PUT /secrets/{name}. Secrets are write-only: no
+// endpoint returns their values.
+func (s *Service) SetSecretHandler(w http.ResponseWriter, r *http.Request) {
+	var req SecretRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := s.SetSecret(r.Context(), r.PathValue("name"), req.Value); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidSecretName) {
+			status = http.StatusBadRequest
+		}
+		httpjson.Error(w, status, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,20 +3,34 @@ package settings
 import (
 	"context"
 	"errors"
-	
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/monarch-dev/monarch/database"
 	"github.com/monarch-dev/monarch/internal/crypto"
-	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	ErrNotFound          = errors.New("setting not found")
+	ErrInvalidSecretName = errors.New("invalid secret name")
+)
+
+// SecretPrefix namespaces the settings gates can read as secrets, so a
+// project's gates.yaml can't reach Monarch's own settings.
+const SecretPrefix = "secrets."
+
+var secretName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 type Service struct {
-	q      *database.Queries
+	q      database.Querier
 	encKey []byte
 }
 
-func NewService(db database.DBTX, encKey []byte) *Service {
+func NewService(q database.Querier, encKey []byte) *Service {
 	return &Service{
-		q:      database.New(db),
+		q:      q,
 		encKey: encKey,
 	}
 }
@@ -35,7 +49,7 @@ func (s *Service) Set(ctx context.Context, key string, value string, encrypt boo
 	} else {
 		data = []byte(value)
 	}
-	
+
 	return s.q.UpsertSetting(ctx, database.UpsertSettingParams{
 		Key:         key,
 		Value:       data,
@@ -45,10 +59,13 @@ func (s *Service) Set(ctx context.Context, key string, value string, encrypt boo
 
 func (s *Service) Get(ctx context.Context, key string) (string, error) {
 	row, err := s.q.GetSetting(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
 	if err != nil {
 		return "", err
 	}
-	
+
 	if row.IsEncrypted.Bool {
 		decrypted, err := crypto.Decrypt(row.Value, s.encKey)
 		if err != nil {
@@ -56,6 +73,22 @@ func (s *Service) Get(ctx context.Context, key string) (string, error) {
 		}
 		return string(decrypted), nil
 	}
-	
+
 	return string(row.Value), nil
 }
+
+// SetSecret stores a secret for gates, always encrypted.
+func (s *Service) SetSecret(ctx context.Context, name, value string) error {
+	if !secretName.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSecretName, name)
+	}
+	return s.Set(ctx, SecretPrefix+name, value, true)
+}
+
+// Secret returns the decrypted value of a secret set with SetSecret.
+func (s *Service) Secret(ctx context.Context, name string) (string, error) {
+	if !secretName.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSecretName, name)
+	}
+	return s.Get(ctx, SecretPrefix+name)
+}
@@ -2,45 +2,91 @@ package settings_test
 
 import (
 	"context"
-	"os"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/stretchr/testify/require"
+	"github.com/monarch-dev/monarch/database/dbtest"
 	"github.com/monarch-dev/monarch/settings"
-	"github.com/monarch-dev/monarch/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func setupTestDB(t *testing.T) database.DBTX {
-	dbURL := os.Getenv("TEST_DB_URL")
-	if dbURL == "" {
-		t.Skip("TEST_DB_URL not set")
+// Key for testing (32 bytes)
+var encKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSettingsService_Integration(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			svc := settings.NewService(b.Querier, encKey)
+
+			ctx := context.Background()
+
+			// Test Save Encrypted
+			err := svc.Set(ctx, "GEMINI_API_KEY", "secret-value", true)
+			require.NoError(t, err)
+
+			// Test Get
+			val, err := svc.Get(ctx, "GEMINI_API_KEY")
+			require.NoError(t, err)
+			require.Equal(t, "secret-value", val)
+
+			_, err = svc.Get(ctx, "missing")
+			require.ErrorIs(t, err, settings.ErrNotFound)
+		})
 	}
-	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, dbURL)
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		pool.Close()
-	})
-	return pool
 }
 
-func TestSettingsService_Integration(t *testing.T) {
-	db := setupTestDB(t) 
-	
-	// Key for testing (32 bytes)
-	encKey := []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
-	
-	svc := settings.NewService(db, encKey)
-
-	ctx := context.Background()
-	
-	// Test Save Encrypted
-	err := svc.Set(ctx, "GEMINI_API_KEY", "secret-value", true)
-	require.NoError(t, err)
+func TestSettingsService_Secrets(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			svc := settings.NewService(b.Querier, encKey)
+			ctx := context.Background()
+
+			require.NoError(t, svc.SetSecret(ctx, "npm_token", "s3cr3t"))
+			val, err := svc.Secret(ctx, "npm_token")
+			require.NoError(t, err)
+			assert.Equal(t, "s3cr3t", val)
+
+			row, err := b.Querier.GetSetting(ctx, settings.SecretPrefix+"npm_token")
+			require.NoError(t, err)
+			assert.True(t, row.IsEncrypted.Bool)
+			assert.NotContains(t, string(row.Value), "s3cr3t")
+
+			require.NoError(t, svc.Set(ctx, "runner.instance_id", "abc", false))
+			_, err = svc.Secret(ctx, "runner.instance_id")
+			assert.ErrorIs(t, err, settings.ErrNotFound, "only secrets are readable as secrets")
+			assert.ErrorIs(t, svc.SetSecret(ctx, "../x", "v"), settings.ErrInvalidSecretName)
+		})
+	}
+}
+
+func TestSetSecretHandler(t *testing.T) {
+	svc := settings.NewService(dbtest.Backends(t)[0].Querier, encKey)
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /secrets/{name}", svc.SetSecretHandler)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"Stores", "/secrets/npm_token", `{"value":"s3cr3t"}`, http.StatusNoContent},
+		{"InvalidName", "/secrets/npm%20token", `{"value":"s3cr3t"}`, http.StatusBadRequest},
+		{"InvalidBody", "/secrets/npm_token", `{"secret":"s3cr3t"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("PUT", tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.NotContains(t, w.Body.String(), "s3cr3t")
+		})
+	}
 
-	// Test Get
-	val, err := svc.Get(ctx, "GEMINI_API_KEY")
+	val, err := svc.Secret(context.Background(), "npm_token")
 	require.NoError(t, err)
-	require.Equal(t, "secret-value", val)
+	assert.Equal(t, "s3cr3t", val)
 }
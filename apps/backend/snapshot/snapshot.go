@@ -0,0 +1,290 @@
+// Package snapshot freezes a project's workspace for an attempt, so its
+// gates validate exactly what was submitted even while the agent goes on
+// editing, and no attempt sees another's half-written files.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot is a frozen copy of a project's workspace.
+type Snapshot struct {
+	// Dir is the project root inside the snapshot, on the host.
+	Dir string
+	// Name is Dir relative to the project's snapshot root.
+	Name string
+	// Commit is the HEAD commit the snapshot was taken at; it is empty for
+	// projects outside git.
+	Commit string
+	// DiffHash is the SHA-256 of the uncommitted changes applied on top of
+	// Commit, empty if there were none. Outside git it hashes the whole
+	// copied tree instead.
+	DiffHash string
+
+	// root is the snapshot's top directory; repo is the repository it is a
+	// worktree of, if it is one.
+	root string
+	repo string
+}
+
+// Snapshotter takes snapshots under a root directory, one subdirectory per
+// project. The Docker daemon mounts them, so root must be on its host.
+type Snapshotter struct {
+	root string
+}
+
+func NewSnapshotter(root string) *Snapshotter {
+	return &Snapshotter{root: root}
+}
+
+// Root returns the directory holding the project's snapshots.
+func (s *Snapshotter) Root(projectID string) string {
+	return filepath.Join(s.root, projectID)
+}
+
+// Clean removes every snapshot, e.g. those left behind by a crash. The
+// project dirs are kept, since warm runners may still mount them. Worktree
+// records left in repositories are pruned by the next Take there.
+func (s *Snapshotter) Clean() error {
+	projects, err := os.ReadDir(s.root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range projects {
+		snaps, err := os.ReadDir(filepath.Join(s.root, p.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, snap := range snaps {
+			errs = append(errs, os.RemoveAll(filepath.Join(s.root, p.Name(), snap.Name())))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Take snapshots the workspace at src as name. A git workspace becomes a
+// worktree at its HEAD commit with the uncommitted changes, including
+// untracked files that aren't ignored, applied on top; anything else, or a
+// repository without commits, is copied.
+func (s *Snapshotter) Take(ctx context.Context, projectID, src, name string) (*Snapshot, error) {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+	// git reports the repository's real path, which Dir is worked out from.
+	if src, err = filepath.EvalSymlinks(src); err != nil {
+		return nil, err
+	}
+	base, err := filepath.Abs(s.root)
+	if err != nil {
+		return nil, err
+	}
+	parent := filepath.Join(base, projectID)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	root := filepath.Join(parent, name)
+
+	snap, err := s.worktree(ctx, src, root)
+	if errors.Is(err, errNotGit) {
+		snap, err = copyTree(src, root, base)
+	}
+	if err != nil {
+		_ = os.RemoveAll(root)
+		return nil, fmt.Errorf("failed to snapshot %s: %w", src, err)
+	}
+	if snap.Name, err = filepath.Rel(parent, snap.Dir); err != nil {
+		_ = s.Remove(ctx, snap)
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Remove deletes the snapshot and, for a worktree, its record in the
+// repository.
+func (s *Snapshotter) Remove(ctx context.Context, snap *Snapshot) error {
+	if err := os.RemoveAll(snap.root); err != nil {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+	if snap.repo != "" {
+		if _, err := git(ctx, snap.repo, nil, nil, "worktree", "prune"); err != nil {
+			return fmt.Errorf("failed to prune worktree: %w", err)
+		}
+	}
+	return nil
+}
+
+var errNotGit = errors.New("not a git workspace with commits")
+
+func (s *Snapshotter) worktree(ctx context.Context, src, root string) (*Snapshot, error) {
+	top, err := git(ctx, src, nil, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errNotGit
+	}
+	repo := strings.TrimSpace(string(top))
+	commit, err := git(ctx, repo, nil, nil, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	if err != nil {
+		return nil, errNotGit
+	}
+	snap := &Snapshot{Commit: strings.TrimSpace(string(commit)), root: root, repo: repo}
+
+	diff, err := workingDiff(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records of worktrees whose snapshot was deleted would block nothing,
+	// but they pile up.
+	_, _ = git(ctx, repo, nil, nil, "worktree", "prune")
+	if _, err := git(ctx, repo, nil, nil, "worktree", "add", "--detach", root, snap.Commit); err != nil {
+		return nil, err
+	}
+	if len(diff) > 0 {
+		if _, err := git(ctx, root, nil, bytes.NewReader(diff), "apply", "--binary", "--whitespace=nowarn"); err != nil {
+			_ = s.Remove(ctx, snap)
+			return nil, fmt.Errorf("failed to apply uncommitted changes: %w", err)
+		}
+		sum := sha256.Sum256(diff)
+		snap.DiffHash = hex.EncodeToString(sum[:])
+	}
+
+	rel, err := filepath.Rel(repo, src)
+	if err != nil {
+		_ = s.Remove(ctx, snap)
+		return nil, err
+	}
+	snap.Dir = filepath.Join(root, rel)
+	return snap, nil
+}
+
+// workingDiff returns the repository's changes against HEAD, staged or not,
+// including untracked files. They are staged in a copy of the index so the
+// real one is left alone.
+func workingDiff(ctx context.Context, repo string) ([]byte, error) {
+	out, err := git(ctx, repo, nil, nil, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "monarch-index-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	index := filepath.Join(tmp, "index")
+	// Starting from the real index keeps its stat cache, so only changed
+	// files are rehashed.
+	if err := copyFile(strings.TrimSpace(string(out)), index, 0o644); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if _, err := git(ctx, repo, env, nil, "add", "--all"); err != nil {
+		return nil, err
+	}
+	// Explicit prefixes override diff.noprefix and the like, which git apply
+	// wouldn't understand.
+	return git(ctx, repo, env, nil, "diff", "--cached", "--binary", "--no-color", "--no-ext-diff",
+		"--ignore-submodules=all", "--src-prefix=a/", "--dst-prefix=b/", "HEAD")
+}
+
+// copyTree copies src into root, hashing the tree as it goes. It skips
+// base, in case the snapshots are kept inside the project.
+func copyTree(src, root, base string) (*Snapshot, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == base {
+			return fs.SkipDir
+		}
+		dst := filepath.Join(root, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir():
+			fmt.Fprintf(h, "dir %s\x00", rel)
+			return os.MkdirAll(dst, info.Mode().Perm()|0o700)
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link %s %s\x00", rel, target)
+			return os.Symlink(target, dst)
+		case d.Type().IsRegular():
+			fmt.Fprintf(h, "file %s %o %d\x00", rel, info.Mode().Perm(), info.Size())
+			return copyHashed(path, dst, info.Mode().Perm(), h)
+		default:
+			// Sockets, pipes and devices can't be copied.
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Dir: root, DiffHash: hex.EncodeToString(h.Sum(nil)), root: root}, nil
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	return copyHashed(src, dst, perm, io.Discard)
+}
+
+// copyHashed copies src to dst, also writing its content to h.
+func copyHashed(src, dst string, perm fs.FileMode, h io.Writer) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// git runs git in dir with env added to the environment and returns its
+// stdout.
+func git(ctx context.Context, dir string, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,166 @@
+package snapshot_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/monarch-dev/monarch/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+		"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	run(t, repo, "init", "-q")
+	write(t, filepath.Join(repo, ".gitignore"), "build/\n")
+	write(t, filepath.Join(repo, "main.go"), "package main\n")
+	write(t, filepath.Join(repo, "app", "app.go"), "package app\n")
+	run(t, repo, "add", ".")
+	run(t, repo, "commit", "-q", "-m", "init")
+	return repo
+}
+
+func TestTake_GitWorktreeWithUncommittedChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	head := run(t, repo, "rev-parse", "HEAD")
+
+	write(t, filepath.Join(repo, "main.go"), "package main // edited\n")
+	write(t, filepath.Join(repo, "new.go"), "package main // untracked\n")
+	write(t, filepath.Join(repo, "build", "out"), "ignored")
+	run(t, repo, "add", "main.go")
+	staged := run(t, repo, "diff", "--cached", "--name-only")
+
+	s := snapshot.NewSnapshotter(t.TempDir())
+	snap, err := s.Take(ctx, "p1", repo, "a1")
+	require.NoError(t, err)
+
+	assert.Equal(t, head, snap.Commit)
+	assert.Len(t, snap.DiffHash, 64)
+	assert.Equal(t, "a1", snap.Name)
+	assert.Equal(t, filepath.Join(s.Root("p1"), "a1"), snap.Dir)
+	assert.Equal(t, "package main // edited\n", read(t, filepath.Join(snap.Dir, "main.go")))
+	assert.Equal(t, "package main // untracked\n", read(t, filepath.Join(snap.Dir, "new.go")))
+	assert.NoFileExists(t, filepath.Join(snap.Dir, "build", "out"), "ignored files aren't snapshotted")
+	assert.Equal(t, staged, run(t, repo, "diff", "--cached", "--name-only"), "the real index is untouched")
+
+	// Later edits don't reach the snapshot.
+	write(t, filepath.Join(repo, "main.go"), "package main // later\n")
+	assert.Equal(t, "package main // edited\n", read(t, filepath.Join(snap.Dir, "main.go")))
+
+	// The same changes hash the same.
+	write(t, filepath.Join(repo, "main.go"), "package main // edited\n")
+	again, err := s.Take(ctx, "p1", repo, "a2")
+	require.NoError(t, err)
+	assert.Equal(t, snap.DiffHash, again.DiffHash)
+
+	require.NoError(t, s.Remove(ctx, snap))
+	require.NoError(t, s.Remove(ctx, again))
+	assert.NoDirExists(t, snap.Dir)
+	assert.Equal(t, repo, onlyWorktree(t, run(t, repo, "worktree", "list", "--porcelain")), "worktree records are pruned")
+}
+
+// onlyWorktree returns the only worktree in git's porcelain list.
+func onlyWorktree(t *testing.T, list string) string {
+	t.Helper()
+	var paths []string
+	for _, l := range strings.Split(list, "\n") {
+		if p, ok := strings.CutPrefix(l, "worktree "); ok {
+			paths = append(paths, p)
+		}
+	}
+	require.Len(t, paths, 1)
+	path, err := filepath.EvalSymlinks(paths[0])
+	require.NoError(t, err)
+	return path
+}
+
+func TestTake_CleanGitWorkspaceHasNoDiff(t *testing.T) {
+	repo := newRepo(t)
+	s := snapshot.NewSnapshotter(t.TempDir())
+
+	snap, err := s.Take(context.Background(), "p1", repo, "a1")
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, snap.Commit)
+	assert.Empty(t, snap.DiffHash)
+}
+
+func TestTake_ProjectInRepoSubdir(t *testing.T) {
+	repo := newRepo(t)
+	s := snapshot.NewSnapshotter(t.TempDir())
+
+	snap, err := s.Take(context.Background(), "p1", filepath.Join(repo, "app"), "a1")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join("a1", "app"), snap.Name)
+	assert.Equal(t, "package app\n", read(t, filepath.Join(snap.Dir, "app.go")))
+}
+
+func TestTake_CopiesNonGitWorkspace(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	write(t, filepath.Join(src, "index.js"), "console.log(1)\n")
+	write(t, filepath.Join(src, "lib", "util.js"), "module.exports = {}\n")
+	require.NoError(t, os.Symlink("lib/util.js", filepath.Join(src, "util.js")))
+
+	s := snapshot.NewSnapshotter(t.TempDir())
+	snap, err := s.Take(ctx, "p1", src, "a1")
+	require.NoError(t, err)
+
+	assert.Empty(t, snap.Commit)
+	assert.Len(t, snap.DiffHash, 64)
+	assert.Equal(t, "module.exports = {}\n", read(t, filepath.Join(snap.Dir, "lib", "util.js")))
+	target, err := os.Readlink(filepath.Join(snap.Dir, "util.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "lib/util.js", target)
+
+	same, err := s.Take(ctx, "p1", src, "a2")
+	require.NoError(t, err)
+	assert.Equal(t, snap.DiffHash, same.DiffHash)
+
+	write(t, filepath.Join(src, "index.js"), "console.log(2)\n")
+	changed, err := s.Take(ctx, "p1", src, "a3")
+	require.NoError(t, err)
+	assert.NotEqual(t, snap.DiffHash, changed.DiffHash)
+	assert.Equal(t, "console.log(1)\n", read(t, filepath.Join(snap.Dir, "index.js")))
+
+	require.NoError(t, s.Clean())
+	entries, err := os.ReadDir(s.Root("p1"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the project dir stays for runners mounting it")
+}
@@ -0,0 +1,208 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/internal/httpjson"
+)
+
+// maxBodyBytes caps request bodies; the longest legal task is well under it.
+const maxBodyBytes = 1 << 20
+
+type CreateRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// UpdateRequest fields are optional; omitted fields are left unchanged.
+type UpdateRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+type TransitionRequest struct {
+	Status string `json:"status"`
+}
+
+// manualStatuses are the statuses a person may move a task to. The others
+// belong to the builder, which claims, submits and validates attempts.
+var manualStatuses = []Status{StatusBacklog, StatusBlocked}
+
+// CreateHandler serves POST /projects/{id}/tasks.
+func (s *Service) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := pathUUID(w, r, "Invalid project ID")
+	if !ok {
+		return
+	}
+
+	var req CreateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	t, err := s.Create(r.Context(), projectID, req.Title, req.Description)
+	if err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusCreated, t)
+}
+
+// ListHandler serves GET /projects/{id}/tasks. Repeat ?status= or pass a
+// comma-separated list to filter.
+func (s *Service) ListHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := pathUUID(w, r, "Invalid project ID")
+	if !ok {
+		return
+	}
+
+	var statuses []Status
+	for _, param := range r.URL.Query()["status"] {
+		for _, name := range strings.Split(param, ",") {
+			status, err := ParseStatus(strings.TrimSpace(name))
+			if err != nil {
+				httpjson.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	tasks, err := s.List(r.Context(), projectID, statuses)
+	if err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, tasks)
+}
+
+// GetHandler serves GET /tasks/{id}.
+func (s *Service) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	t, err := s.Get(r.Context(), id)
+	if err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, t)
+}
+
+// UpdateHandler serves PATCH /tasks/{id}.
+func (s *Service) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Title == nil && req.Description == nil {
+		httpjson.Error(w, http.StatusBadRequest, "Nothing to update")
+		return
+	}
+
+	t, err := s.Update(r.Context(), id, req.Title, req.Description)
+	if err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, t)
+}
+
+// DeleteHandler serves DELETE /tasks/{id}.
+func (s *Service) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	if err := s.Delete(r.Context(), id); err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TransitionHandler serves POST /tasks/{id}/status. Only the moves a person
+// makes are allowed; see manualStatuses.
+func (s *Service) TransitionHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathUUID(w, r, "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var req TransitionRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	to, err := ParseStatus(req.Status)
+	if err != nil {
+		httpjson.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !slices.Contains(manualStatuses, to) {
+		httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("status %s is only set by the builder", to))
+		return
+	}
+
+	t, err := s.Transition(r.Context(), id, to)
+	if err != nil {
+		httpjson.Error(w, StatusCode(err), err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, t)
+}
+
+// StatusCode maps task errors onto HTTP status codes.
+func StatusCode(err error) int {
+	var terr *TransitionError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidStatus), errors.Is(err, ErrInvalidTask):
+		return http.StatusBadRequest
+	case errors.As(err, &terr):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func pathUUID(w http.ResponseWriter, r *http.Request, msg string) (pgtype.UUID, bool) {
+	var id pgtype.UUID
+	if err := id.Scan(r.PathValue("id")); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, msg)
+		return id, false
+	}
+	return id, true
+}
+
+// decode strictly parses the JSON body into v, answering 400 on failure.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package task_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/monarch-dev/monarch/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMux(svc *task.Service) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /projects/{id}/tasks", svc.CreateHandler)
+	mux.HandleFunc("GET /projects/{id}/tasks", svc.ListHandler)
+	mux.HandleFunc("GET /tasks/{id}", svc.GetHandler)
+	mux.HandleFunc("PATCH /tasks/{id}", svc.UpdateHandler)
+	mux.HandleFunc("DELETE /tasks/{id}", svc.DeleteHandler)
+	mux.HandleFunc("POST /tasks/{id}/status", svc.TransitionHandler)
+	return mux
+}
+
+func serve(mux *http.ServeMux, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestTaskHandlers(t *testing.T) {
+	q := dbtest.Backends(t)[0].Querier
+	mux := newMux(task.NewService(q))
+
+	proj, err := q.CreateProject(context.Background(), t.TempDir())
+	require.NoError(t, err)
+	tasksPath := "/projects/" + proj.ID.String() + "/tasks"
+
+	w := serve(mux, "POST", tasksPath, `{"title":"Add login","description":"OAuth"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created database.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	taskPath := "/tasks/" + created.ID.String()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"CreateMissingTitle", "POST", tasksPath, `{"description":"x"}`, http.StatusBadRequest},
+		{"CreateUnknownField", "POST", tasksPath, `{"title":"x","priority":1}`, http.StatusBadRequest},
+		{"CreateUnknownProject", "POST", "/projects/00000000-0000-0000-0000-00000000dead/tasks", `{"title":"x"}`, http.StatusNotFound},
+		{"ListFiltered", "GET", tasksPath + "?status=BACKLOG,DONE", "", http.StatusOK},
+		{"ListBadFilter", "GET", tasksPath + "?status=OPEN", "", http.StatusBadRequest},
+		{"Get", "GET", taskPath, "", http.StatusOK},
+		{"GetBadID", "GET", "/tasks/nope", "", http.StatusBadRequest},
+		{"UpdateEmpty", "PATCH", taskPath, `{}`, http.StatusBadRequest},
+		{"Update", "PATCH", taskPath, `{"title":"Add SSO"}`, http.StatusOK},
+		{"Delete", "DELETE", taskPath, "", http.StatusNoContent},
+		{"GetDeleted", "GET", taskPath, "", http.StatusNotFound},
+		{"DeleteAgain", "DELETE", taskPath, "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(mux, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestTransitionHandler(t *testing.T) {
+	q := dbtest.Backends(t)[0].Querier
+	mux := newMux(task.NewService(q))
+
+	tk := newTask(t, q, task.StatusInProgress)
+
+	tests := []struct {
+		name     string
+		id       string
+		body     string
+		wantCode int
+	}{
+		{"Block", tk.ID.String(), `{"status":"BLOCKED"}`, http.StatusOK},
+		{"Unblock", tk.ID.String(), `{"status":"BACKLOG"}`, http.StatusOK},
+		{"Illegal", tk.ID.String(), `{"status":"BLOCKED"}`, http.StatusConflict},
+		// The builder's moves aren't allowed, legal or not.
+		{"Claim", tk.ID.String(), `{"status":"IN_PROGRESS"}`, http.StatusBadRequest},
+		{"Validate", tk.ID.String(), `{"status":"VALIDATING"}`, http.StatusBadRequest},
+		{"Done", tk.ID.String(), `{"status":"DONE"}`, http.StatusBadRequest},
+		{"UnknownStatus", tk.ID.String(), `{"status":"NOPE"}`, http.StatusBadRequest},
+		{"BadID", "not-a-uuid", `{"status":"BLOCKED"}`, http.StatusBadRequest},
+		{"NotFound", "00000000-0000-0000-0000-00000000dead", `{"status":"BLOCKED"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(mux, "POST", "/tasks/"+tt.id+"/status", tt.body)
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+		})
+	}
+
+	got, err := q.GetTask(context.Background(), tk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(task.StatusBacklog), got.Status)
+}
@@ -0,0 +1,209 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 10000
+)
+
+// maxTransitionAttempts bounds the compare-and-set retry loop when another
+// writer keeps changing the task underneath us.
+const maxTransitionAttempts = 3
+
+// Queue holds gates waiting to run; runner.Scheduler implements it. Jobs
+// are owned by the ID of the task they validate.
+type Queue interface {
+	Cancel(owner string) int
+}
+
+type Service struct {
+	q     database.Querier
+	queue Queue
+}
+
+func NewService(q database.Querier) *Service {
+	return &Service{q: q}
+}
+
+// WithQueue lets Delete and moving a task out of VALIDATING cancel its
+// queued gates.
+func (s *Service) WithQueue(q Queue) *Service {
+	s.queue = q
+	return s
+}
+
+// abandon cancels the task's queued gates.
+func (s *Service) abandon(id pgtype.UUID) {
+	if s.queue != nil {
+		s.queue.Cancel(id.String())
+	}
+}
+
+func (s *Service) Get(ctx context.Context, id pgtype.UUID) (database.Task, error) {
+	t, err := s.q.GetTask(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// Create adds a task to the project's backlog.
+func (s *Service) Create(ctx context.Context, projectID pgtype.UUID, title, description string) (database.Task, error) {
+	title = strings.TrimSpace(title)
+	if err := validate(title, description); err != nil {
+		return database.Task{}, err
+	}
+	if err := s.checkProject(ctx, projectID); err != nil {
+		return database.Task{}, err
+	}
+
+	return s.q.CreateTask(ctx, database.CreateTaskParams{
+		ProjectID:   projectID,
+		Title:       title,
+		Description: description,
+		Status:      string(StatusBacklog),
+	})
+}
+
+// List returns the project's tasks, optionally restricted to statuses.
+func (s *Service) List(ctx context.Context, projectID pgtype.UUID, statuses []Status) ([]database.Task, error) {
+	if err := s.checkProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	var (
+		tasks []database.Task
+		err   error
+	)
+	if len(statuses) == 0 {
+		tasks, err = s.q.ListTasks(ctx, projectID)
+	} else {
+		names := make([]string, len(statuses))
+		for i, status := range statuses {
+			names[i] = string(status)
+		}
+		tasks, err = s.q.ListTasksByStatus(ctx, database.ListTasksByStatusParams{
+			ProjectID: projectID,
+			Statuses:  names,
+		})
+	}
+	if tasks == nil {
+		tasks = []database.Task{}
+	}
+	return tasks, err
+}
+
+// Update changes the title and/or description; nil leaves a field as is.
+func (s *Service) Update(ctx context.Context, id pgtype.UUID, title, description *string) (database.Task, error) {
+	params := database.UpdateTaskParams{ID: id}
+	if title != nil {
+		trimmed := strings.TrimSpace(*title)
+		if err := validateTitle(trimmed); err != nil {
+			return database.Task{}, err
+		}
+		params.Title = pgtype.Text{String: trimmed, Valid: true}
+	}
+	if description != nil {
+		if err := validateDescription(*description); err != nil {
+			return database.Task{}, err
+		}
+		params.Description = pgtype.Text{String: *description, Valid: true}
+	}
+
+	t, err := s.q.UpdateTask(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *Service) Delete(ctx context.Context, id pgtype.UUID) error {
+	n, err := s.q.DeleteTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	s.abandon(id)
+	return nil
+}
+
+func (s *Service) checkProject(ctx context.Context, id pgtype.UUID) error {
+	_, err := s.q.GetProjectByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return err
+}
+
+func validate(title, description string) error {
+	if err := validateTitle(title); err != nil {
+		return err
+	}
+	return validateDescription(description)
+}
+
+func validateTitle(title string) error {
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidTask)
+	}
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		return fmt.Errorf("%w: title exceeds %d characters", ErrInvalidTask, maxTitleLength)
+	}
+	return nil
+}
+
+func validateDescription(description string) error {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return fmt.Errorf("%w: description exceeds %d characters", ErrInvalidTask, maxDescriptionLength)
+	}
+	return nil
+}
+
+// Transition moves the task to status to. The update only applies if the
+// task is still in the state it was validated against, so two concurrent
+// callers can't both claim the same task.
+func (s *Service) Transition(ctx context.Context, id pgtype.UUID, to Status) (database.Task, error) {
+	if _, err := ParseStatus(string(to)); err != nil {
+		return database.Task{}, err
+	}
+
+	for range maxTransitionAttempts {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return current, err
+		}
+
+		from := Status(current.Status)
+		if !CanTransition(from, to) {
+			return current, &TransitionError{From: from, To: to}
+		}
+
+		updated, err := s.q.TransitionTaskStatus(ctx, database.TransitionTaskStatusParams{
+			ID:         id,
+			FromStatus: string(from),
+			ToStatus:   string(to),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Lost the race; re-read and validate against the new state.
+			continue
+		}
+		if err == nil && from == StatusValidating {
+			s.abandon(id)
+		}
+		return updated, err
+	}
+	return database.Task{}, fmt.Errorf("task %s changed concurrently; retry", id)
+}
@@ -0,0 +1,120 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/monarch-dev/monarch/database"
+	"github.com/monarch-dev/monarch/database/dbtest"
+	"github.com/monarch-dev/monarch/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTask(t *testing.T, q database.Querier, status task.Status) database.Task {
+	ctx := context.Background()
+	proj, err := q.CreateProject(ctx, t.TempDir())
+	require.NoError(t, err)
+	tk, err := q.CreateTask(ctx, database.CreateTaskParams{ProjectID: proj.ID, Title: "t", Status: string(status)})
+	require.NoError(t, err)
+	return tk
+}
+
+func TestService_Transition(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := task.NewService(b.Querier)
+			tk := newTask(t, b.Querier, task.StatusBacklog)
+
+			got, err := svc.Transition(ctx, tk.ID, task.StatusInProgress)
+			require.NoError(t, err)
+			assert.Equal(t, "IN_PROGRESS", got.Status)
+
+			_, err = svc.Transition(ctx, tk.ID, task.StatusDone)
+			var terr *task.TransitionError
+			require.True(t, errors.As(err, &terr))
+			assert.Equal(t, task.StatusInProgress, terr.From)
+			assert.Equal(t, task.StatusDone, terr.To)
+
+			_, err = svc.Transition(ctx, pgtype.UUID{Bytes: [16]byte{0xde, 0xad}, Valid: true}, task.StatusInProgress)
+			assert.ErrorIs(t, err, task.ErrNotFound)
+		})
+	}
+}
+
+func TestService_CRUD(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := task.NewService(b.Querier)
+			proj, err := b.Querier.CreateProject(ctx, t.TempDir())
+			require.NoError(t, err)
+
+			_, err = svc.Create(ctx, proj.ID, "   ", "")
+			assert.ErrorIs(t, err, task.ErrInvalidTask)
+
+			_, err = svc.Create(ctx, pgtype.UUID{Bytes: [16]byte{0xde, 0xad}, Valid: true}, "orphan", "")
+			assert.ErrorIs(t, err, task.ErrProjectNotFound)
+
+			created, err := svc.Create(ctx, proj.ID, "  Add login ", "OAuth")
+			require.NoError(t, err)
+			assert.Equal(t, "Add login", created.Title)
+			assert.Equal(t, "BACKLOG", created.Status)
+
+			title := "Add SSO login"
+			updated, err := svc.Update(ctx, created.ID, &title, nil)
+			require.NoError(t, err)
+			assert.Equal(t, title, updated.Title)
+			assert.Equal(t, "OAuth", updated.Description)
+
+			backlog, err := svc.List(ctx, proj.ID, []task.Status{task.StatusBacklog})
+			require.NoError(t, err)
+			assert.Len(t, backlog, 1)
+
+			done, err := svc.List(ctx, proj.ID, []task.Status{task.StatusDone})
+			require.NoError(t, err)
+			assert.Empty(t, done)
+
+			require.NoError(t, svc.Delete(ctx, created.ID))
+			assert.ErrorIs(t, svc.Delete(ctx, created.ID), task.ErrNotFound)
+			_, err = svc.Get(ctx, created.ID)
+			assert.ErrorIs(t, err, task.ErrNotFound)
+		})
+	}
+}
+
+// fakeQueue records the owners whose queued gates were cancelled.
+type fakeQueue struct {
+	cancelled []string
+}
+
+func (q *fakeQueue) Cancel(owner string) int {
+	q.cancelled = append(q.cancelled, owner)
+	return 1
+}
+
+func TestService_AbandonCancelsQueuedGates(t *testing.T) {
+	for _, b := range dbtest.Backends(t) {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &fakeQueue{}
+			svc := task.NewService(b.Querier).WithQueue(queue)
+			tk := newTask(t, b.Querier, task.StatusValidating)
+
+			_, err := svc.Transition(ctx, tk.ID, task.StatusBlocked)
+			require.NoError(t, err)
+			require.Len(t, queue.cancelled, 1, "leaving VALIDATING abandons its queued gates")
+
+			_, err = svc.Transition(ctx, tk.ID, task.StatusBacklog)
+			require.NoError(t, err)
+			assert.Len(t, queue.cancelled, 1, "other transitions leave the queue alone")
+
+			require.NoError(t, svc.Delete(ctx, tk.ID))
+			assert.Len(t, queue.cancelled, 2)
+			assert.Equal(t, queue.cancelled[0], queue.cancelled[1])
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package task owns the task lifecycle. Every status change goes through
+// Service.Transition so illegal moves are rejected in one place.
+package task
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Status string
+
+const (
+	StatusBacklog    Status = "BACKLOG"
+	StatusInProgress Status = "IN_PROGRESS"
+	StatusValidating Status = "VALIDATING"
+	StatusDone       Status = "DONE"
+	StatusBlocked    Status = "BLOCKED"
+)
+
+// transitions lists the legal moves out of each state. DONE is terminal.
+var transitions = map[Status][]Status{
+	StatusBacklog:    {StatusInProgress},
+	StatusInProgress: {StatusValidating, StatusBlocked, StatusBacklog},
+	StatusValidating: {StatusDone, StatusInProgress, StatusBlocked},
+	StatusBlocked:    {StatusBacklog},
+	StatusDone:       nil,
+}
+
+var (
+	ErrNotFound        = errors.New("task not found")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrInvalidStatus   = errors.New("invalid task status")
+	ErrInvalidTask     = errors.New("invalid task")
+)
+
+// TransitionError reports a move the state machine does not allow.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("illegal task transition %s -> %s", e.From, e.To)
+}
+
+// ParseStatus validates s against the known states.
+func ParseStatus(s string) (Status, error) {
+	status := Status(s)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidStatus, s)
+	}
+	return status, nil
+}
+
+// CanTransition reports whether the state machine allows from -> to.
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package task_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/monarch-dev/monarch/task"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to task.Status
+		want     bool
+	}{
+		{task.StatusBacklog, task.StatusInProgress, true},
+		{task.StatusInProgress, task.StatusValidating, true},
+		{task.StatusInProgress, task.StatusBlocked, true},
+		{task.StatusValidating, task.StatusDone, true},
+		{task.StatusValidating, task.StatusInProgress, true},
+		{task.StatusBlocked, task.StatusBacklog, true},
+		{task.StatusBacklog, task.StatusDone, false},
+		{task.StatusDone, task.StatusInProgress, false},
+		{task.StatusBlocked, task.StatusInProgress, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, task.CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	s, err := task.ParseStatus("VALIDATING")
+	assert.NoError(t, err)
+	assert.Equal(t, task.StatusValidating, s)
+
+	_, err = task.ParseStatus("in_progress")
+	assert.True(t, errors.Is(err, task.ErrInvalidStatus))
+}